@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+
+	nanomarkup "github.com/nanomarkup/nanomarkup.go"
+	"github.com/nanomarkup/nanomarkup.go/nanoast"
+	"github.com/nanomarkup/nanomarkup.go/nanopath"
+)
+
+// newFlags returns the flag set of the command which reports errors to the standard error.
+func (c *cli) newFlags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: nano %s\n", c.cmdUsage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func runFmt(c *cli, args []string) int {
+	fs := c.newFlags("fmt")
+	list := fs.Bool("l", false, "list the files whose formatting differs and exit with 1 without changing them")
+	tabs := fs.Bool("tabs", true, "indent by tabs instead of spaces")
+	width := fs.Int("width", 4, "the number of spaces of one indentation level if tabs are not used")
+	align := fs.Bool("align", false, "align the values of consecutive items of entities")
+	if fs.Parse(args) != nil {
+		return exitError
+	}
+	opts := []nanomarkup.FormatOption{
+		nanomarkup.WithTabs(*tabs),
+		nanomarkup.WithIndentWidth(*width),
+		nanomarkup.WithKeyAlignment(*align),
+	}
+	code := exitOK
+	for _, name := range files(fs.Args()) {
+		data, err := c.read(name)
+		if err != nil {
+			return c.errorf("%v", err)
+		}
+		out, err := nanomarkup.Format(data, opts...)
+		if err != nil {
+			fmt.Fprintf(c.stderr, "%s: %v\n", name, err)
+			code = exitError
+			continue
+		}
+		changed := !bytes.Equal(data, out)
+		if *list && changed {
+			fmt.Fprintln(c.stdout, name)
+			if code == exitOK {
+				code = exitFail
+			}
+		}
+		// the files are formatted in place, the standard input is written to the standard output
+		if !*list && (changed || name == "-") {
+			if err = c.write(name, out); err != nil {
+				return c.errorf("%v", err)
+			}
+		}
+	}
+	return code
+}
+
+func runValidate(c *cli, args []string) int {
+	fs := c.newFlags("validate")
+	if fs.Parse(args) != nil {
+		return exitError
+	}
+	code := exitOK
+	for _, name := range files(fs.Args()) {
+		data, err := c.read(name)
+		if err != nil {
+			return c.errorf("%v", err)
+		}
+		for _, err := range nanomarkup.Validate(data) {
+			fmt.Fprintf(c.stderr, "%s: %v\n", name, err)
+			code = exitFail
+		}
+	}
+	return code
+}
+
+func runCompact(c *cli, args []string) int {
+	fs := c.newFlags("compact")
+	write := fs.Bool("w", false, "write the result to the file instead of the standard output")
+	minify := fs.Bool("minify", false, "remove comments and empty lines too")
+	if fs.Parse(args) != nil {
+		return exitError
+	}
+	for _, name := range files(fs.Args()) {
+		data, err := c.read(name)
+		if err != nil {
+			return c.errorf("%v", err)
+		}
+		out := bytes.Buffer{}
+		if *minify {
+			err = nanomarkup.Minify(&out, data)
+		} else {
+			err = nanomarkup.Compact(&out, data)
+		}
+		if err != nil {
+			return c.errorf("%s: %v", name, err)
+		}
+		if !*write {
+			name = "-"
+		}
+		if err = c.write(name, out.Bytes()); err != nil {
+			return c.errorf("%v", err)
+		}
+	}
+	return exitOK
+}
+
+func runGet(c *cli, args []string) int {
+	fs := c.newFlags("get")
+	indent := fs.String("indent", "\t", "the indentation of nested items")
+	if fs.Parse(args) != nil {
+		return exitError
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return exitError
+	}
+	path, err := nanopath.Compile(fs.Arg(0))
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+	data, err := c.read(fs.Arg(1))
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+	root, err := nanopath.Parse(data)
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+	matches := path.Select(root)
+	if len(matches) == 0 {
+		fmt.Fprintf(c.stderr, "nano: %s: not found\n", fs.Arg(0))
+		return exitFail
+	}
+	out := bytes.Buffer{}
+	enc := nanomarkup.NewEncoder(&out)
+	enc.SetIndent("", *indent)
+	for _, m := range matches {
+		if m.Node.Kind == nanopath.Scalar {
+			if err = enc.Flush(); err != nil {
+				return c.errorf("%v", err)
+			}
+			out.WriteString(m.Node.Value)
+			out.WriteByte('\n')
+		} else if err = enc.Encode(m.Node, nil); err != nil {
+			return c.errorf("%v", err)
+		}
+	}
+	if err = enc.Flush(); err != nil {
+		return c.errorf("%v", err)
+	}
+	if err = c.write("-", out.Bytes()); err != nil {
+		return c.errorf("%v", err)
+	}
+	return exitOK
+}
+
+func runSet(c *cli, args []string) int {
+	fs := c.newFlags("set")
+	write := fs.Bool("w", false, "write the result to the file instead of the standard output")
+	if fs.Parse(args) != nil {
+		return exitError
+	}
+	if fs.NArg() < 2 || fs.NArg() > 3 {
+		fs.Usage()
+		return exitError
+	}
+	path, err := nanopath.Compile(fs.Arg(0))
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+	keys, ok := path.Keys()
+	if !ok {
+		return c.errorf("invalid path %q: only keys and indexes can be set", fs.Arg(0))
+	}
+	name := fs.Arg(2)
+	data, err := c.read(name)
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+	doc, err := nanoast.Parse(data)
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+	if err = assign(doc, keys, nanoast.NewValue(fs.Arg(1))); err != nil {
+		return c.errorf("%v", err)
+	}
+	if !*write {
+		name = "-"
+	}
+	if err = c.write(name, nanoast.Print(doc)); err != nil {
+		return c.errorf("%v", err)
+	}
+	return exitOK
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nanomarkup/nanomarkup.go/nanoconfig"
+	"github.com/nanomarkup/nanomarkup.go/nanocsv"
+	"github.com/nanomarkup/nanomarkup.go/nanoenv"
+	"github.com/nanomarkup/nanomarkup.go/nanojson"
+	"github.com/nanomarkup/nanomarkup.go/nanoxml"
+	"github.com/nanomarkup/nanomarkup.go/nanoyaml"
+)
+
+// format converts a data format from and to nano.
+type format struct {
+	exts []string
+	from func(data []byte, indent string) ([]byte, error)
+	to   func(data []byte, indent string) ([]byte, error)
+}
+
+var formats = map[string]format{
+	"nano": {
+		exts: []string{".nano"},
+		from: func(data []byte, indent string) ([]byte, error) { return data, nil },
+		to:   func(data []byte, indent string) ([]byte, error) { return data, nil },
+	},
+	"json": {
+		exts: []string{".json"},
+		from: func(data []byte, indent string) ([]byte, error) { return nanojson.FromJSON(data) },
+		to: func(data []byte, indent string) ([]byte, error) {
+			return nanojson.ToJSON(data, nanojson.WithComments(), nanojson.WithIndent("", indent))
+		},
+	},
+	"yaml": {
+		exts: []string{".yaml", ".yml"},
+		from: func(data []byte, indent string) ([]byte, error) { return nanoyaml.FromYAML(data) },
+		to:   func(data []byte, indent string) ([]byte, error) { return nanoyaml.ToYAML(data) },
+	},
+	"xml": {
+		exts: []string{".xml"},
+		from: func(data []byte, indent string) ([]byte, error) { return nanoxml.FromXML(data) },
+		to: func(data []byte, indent string) ([]byte, error) {
+			return nanoxml.ToXML(data, nanoxml.WithIndent("", indent))
+		},
+	},
+	"csv": {
+		exts: []string{".csv"},
+		from: func(data []byte, indent string) ([]byte, error) { return nanocsv.FromCSV(data) },
+		to:   func(data []byte, indent string) ([]byte, error) { return nanocsv.ToCSV(data) },
+	},
+	"env": {
+		exts: []string{".env"},
+		from: func(data []byte, indent string) ([]byte, error) { return nanoenv.FromDotenv(data) },
+		to:   func(data []byte, indent string) ([]byte, error) { return nanoenv.ToDotenv(data) },
+	},
+	"ini": {
+		exts: []string{".ini"},
+		from: func(data []byte, indent string) ([]byte, error) { return nanoconfig.FromINI(data) },
+		to:   func(data []byte, indent string) ([]byte, error) { return nanoconfig.ToINI(data) },
+	},
+	"toml": {
+		exts: []string{".toml"},
+		from: func(data []byte, indent string) ([]byte, error) { return nanoconfig.FromTOML(data) },
+		to:   func(data []byte, indent string) ([]byte, error) { return nanoconfig.ToTOML(data) },
+	},
+	"properties": {
+		exts: []string{".properties"},
+		from: func(data []byte, indent string) ([]byte, error) { return nanoconfig.FromProperties(data) },
+		to:   func(data []byte, indent string) ([]byte, error) { return nanoconfig.ToProperties(data) },
+	},
+}
+
+// aliases are the alternative names of the formats.
+var aliases = map[string]string{
+	"dotenv": "env",
+	"yml":    "yaml",
+}
+
+// lookupFormat returns the format by its name or alias.
+func lookupFormat(name string) (format, bool) {
+	name = strings.ToLower(name)
+	if a, ok := aliases[name]; ok {
+		name = a
+	}
+	f, ok := formats[name]
+	return f, ok
+}
+
+// formatNames returns the sorted names of the supported formats.
+func formatNames() string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// detectFormat returns the format of the file by its extension, nano by default.
+func detectFormat(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	for fname, f := range formats {
+		for _, e := range f.exts {
+			if e == ext {
+				return fname
+			}
+		}
+	}
+	return "nano"
+}
+
+func runConvert(c *cli, args []string) int {
+	fs := c.newFlags("convert")
+	from := fs.String("from", "", "the format of the input, it is detected by the file extension if it is omitted ("+formatNames()+")")
+	to := fs.String("to", "nano", "the format of the output ("+formatNames()+")")
+	indent := fs.String("indent", "\t", "the indentation of nested items")
+	if fs.Parse(args) != nil {
+		return exitError
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return exitError
+	}
+	if *from == "" {
+		*from = detectFormat(fs.Arg(0))
+	}
+	src, ok := lookupFormat(*from)
+	if !ok {
+		return c.errorf("unknown format %q, the supported formats are %s", *from, formatNames())
+	}
+	dst, ok := lookupFormat(*to)
+	if !ok {
+		return c.errorf("unknown format %q, the supported formats are %s", *to, formatNames())
+	}
+	data, err := c.read(fs.Arg(0))
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+	if data, err = src.from(data, *indent); err != nil {
+		return c.errorf("%s: %v", *from, err)
+	}
+	if strings.EqualFold(*to, "nano") {
+		// write the result in the same layout as the other commands
+		if data, err = relayout(data, *indent); err != nil {
+			return c.errorf("%v", err)
+		}
+	} else if data, err = dst.to(data, *indent); err != nil {
+		return c.errorf("%s: %v", *to, err)
+	}
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		data = append(data, '\n')
+	}
+	if err = c.write("-", data); err != nil {
+		return c.errorf("%v", err)
+	}
+	return exitOK
+}
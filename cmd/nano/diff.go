@@ -0,0 +1,42 @@
+package main
+
+import (
+	"github.com/nanomarkup/nanomarkup.go/nanodiff"
+)
+
+func runDiff(c *cli, args []string) int {
+	fs := c.newFlags("diff")
+	patch := fs.Bool("patch", false, "print a patch document which turns the first file into the second")
+	if fs.Parse(args) != nil {
+		return exitError
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitError
+	}
+	docs := [2][]byte{}
+	for i, name := range fs.Args() {
+		data, err := c.read(name)
+		if err != nil {
+			return c.errorf("%v", err)
+		}
+		docs[i] = data
+	}
+	d, err := nanodiff.Compare(docs[0], docs[1])
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+	out := d.Report()
+	if *patch {
+		if out, err = d.Patch(); err != nil {
+			return c.errorf("%v", err)
+		}
+	}
+	if err = c.write("-", out); err != nil {
+		return c.errorf("%v", err)
+	}
+	if d.Equal() {
+		return exitOK
+	}
+	return exitFail
+}
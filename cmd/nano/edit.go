@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nanomarkup/nanomarkup.go/nanoast"
+)
+
+// assign sets the value of the path creating the missing entities.
+// The layout and the comments of the document are kept.
+func assign(doc *nanoast.Document, keys []string, value nanoast.Node) error {
+	if len(keys) == 0 {
+		return doc.SetValue(value)
+	}
+	n := doc.Value()
+	if n == nil {
+		n = nanoast.NewEntity()
+		if err := doc.SetValue(n); err != nil {
+			return err
+		}
+	}
+	for i, key := range keys[:len(keys)-1] {
+		next := child(n, key)
+		if next == nil {
+			next = nanoast.NewEntity()
+			if err := setChild(n, key, next); err != nil {
+				return fmt.Errorf("%s: %w", strings.Join(keys[:i+1], "."), err)
+			}
+		}
+		n = next
+	}
+	if err := setChild(n, keys[len(keys)-1], value); err != nil {
+		return fmt.Errorf("%s: %w", strings.Join(keys, "."), err)
+	}
+	return nil
+}
+
+// child returns the item of the entity or the array, nil if it is not found.
+func child(n nanoast.Node, key string) nanoast.Node {
+	switch n := n.(type) {
+	case *nanoast.Entity:
+		if p := n.Get(key); p != nil {
+			return p.Value
+		}
+	case *nanoast.Array:
+		values := n.Values()
+		if i, ok := arrayIndex(key, len(values)); ok && i < len(values) {
+			return values[i]
+		}
+	}
+	return nil
+}
+
+// setChild replaces the item of the entity or the array, a new item is added to the end.
+func setChild(n nanoast.Node, key string, value nanoast.Node) error {
+	switch n := n.(type) {
+	case *nanoast.Entity:
+		_, err := n.Set(key, value)
+		return err
+	case *nanoast.Array:
+		values := n.Values()
+		i, ok := arrayIndex(key, len(values))
+		if !ok {
+			return fmt.Errorf("index %s is out of range", key)
+		} else if i == len(values) {
+			return n.Append(value)
+		}
+		for j, it := range n.Items {
+			if it == values[i] {
+				return n.SetAt(j, value)
+			}
+		}
+		return nil
+	case *nanoast.Scalar:
+		return fmt.Errorf("%q is not an entity or an array", n.Value)
+	}
+	return fmt.Errorf("the value is not an entity or an array")
+}
+
+// arrayIndex returns the index of the array item, a negative index counts from the end.
+// The index can be equal to the length to add a new item.
+func arrayIndex(key string, length int) (int, bool) {
+	i, err := strconv.Atoi(key)
+	if err != nil {
+		return 0, false
+	}
+	if i < 0 {
+		i += length
+	}
+	return i, i >= 0 && i <= length
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+
+	nanomarkup "github.com/nanomarkup/nanomarkup.go"
+	"github.com/nanomarkup/nanomarkup.go/internal/nanotree"
+)
+
+// relayout writes the nano-encoded data indented by the indent keeping its comments.
+func relayout(data []byte, indent string) ([]byte, error) {
+	if errs := nanomarkup.Validate(data); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	n, footer, err := nanotree.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	out := bytes.Buffer{}
+	enc := nanomarkup.NewEncoder(&out)
+	enc.SetIndent("", indent)
+	if err = nanotree.WriteDocument(enc, n, footer); err != nil {
+		return nil, err
+	}
+	if err = enc.Flush(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
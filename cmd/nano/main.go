@@ -0,0 +1,127 @@
+// Nano is a tool for nano-encoded documents.
+//
+// Usage:
+//
+//	nano <command> [flags] [arguments]
+//
+// The commands are:
+//
+//	fmt       format documents in place
+//	validate  check the syntax of documents
+//	compact   remove the indentation of a document, and comments with -minify
+//	get       print the values of a document which match a path
+//	set       change a value of a document keeping its layout and comments
+//	convert   convert a document between nano and other formats
+//	diff      compare two documents
+//
+// The commands read the files which are passed as arguments or the standard input
+// if there are no files or the file is "-". The paths of get and set use the syntax
+// of the nanopath package, set accepts the paths of keys and indexes only.
+//
+// The exit code is 0 on success, 1 if a check fails (invalid data, unformatted documents,
+// a missing value or different documents) and 2 on a usage or an I/O error.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	exitOK    int = 0
+	exitFail  int = 1
+	exitError int = 2
+)
+
+// command is a subcommand of the tool.
+type command struct {
+	name  string
+	usage string
+	run   func(c *cli, args []string) int
+}
+
+// cli holds the standard streams of the tool.
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	// the usage of the running command
+	cmdUsage string
+}
+
+var commands = []command{
+	{"fmt", "fmt [-l] [-tabs=false] [-width n] [-align] [files]", runFmt},
+	{"validate", "validate [files]", runValidate},
+	{"compact", "compact [-w] [-minify] [files]", runCompact},
+	{"get", "get <path> [file]", runGet},
+	{"set", "set [-w] <path> <value> [file]", runSet},
+	{"convert", "convert [-from format] [-to format] [-indent string] [file]", runConvert},
+	{"diff", "diff [-patch] <file1> <file2>", runDiff},
+}
+
+func main() {
+	c := cli{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	os.Exit(c.run(os.Args[1:]))
+}
+
+func (c *cli) run(args []string) int {
+	if len(args) == 0 {
+		c.usage()
+		return exitError
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			c.cmdUsage = cmd.usage
+			return cmd.run(c, args[1:])
+		}
+	}
+	if args[0] != "help" && args[0] != "-h" && args[0] != "--help" {
+		fmt.Fprintf(c.stderr, "nano: unknown command %q\n", args[0])
+	}
+	c.usage()
+	return exitError
+}
+
+func (c *cli) usage() {
+	fmt.Fprintln(c.stderr, "Usage: nano <command> [flags] [arguments]")
+	fmt.Fprintln(c.stderr, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(c.stderr, "  nano %s\n", cmd.usage)
+	}
+}
+
+// errorf prints the error and returns the exit code of errors.
+func (c *cli) errorf(format string, a ...any) int {
+	fmt.Fprintf(c.stderr, "nano: "+format+"\n", a...)
+	return exitError
+}
+
+// read returns the content of the file or the standard input if the name is empty or "-".
+func (c *cli) read(name string) ([]byte, error) {
+	if name == "" || name == "-" {
+		return io.ReadAll(c.stdin)
+	}
+	return os.ReadFile(name)
+}
+
+// write writes the data to the file or the standard output if the name is empty or "-".
+func (c *cli) write(name string, data []byte) error {
+	if name == "" || name == "-" {
+		_, err := c.stdout.Write(data)
+		return err
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, data, info.Mode().Perm())
+}
+
+// files returns the names of files or "-" for the standard input.
+func files(args []string) []string {
+	if len(args) == 0 {
+		return []string{"-"}
+	}
+	return args
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testDoc = "// top\n{\n// the server\nserver {\nhost localhost\nport 8080\n}\nhosts [\na\nb\n]\n}\n// end\n"
+
+// exec runs the tool with the input and returns the exit code and the output.
+func exec(input string, args ...string) (int, string, string) {
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+	c := cli{stdin: strings.NewReader(input), stdout: &stdout, stderr: &stderr}
+	code := c.run(args)
+	return code, stdout.String(), stderr.String()
+}
+
+func writeFile(t *testing.T, name, data string) string {
+	name = filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(name, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestFmt(t *testing.T) {
+	want := "// top\n{\n\t// the server\n\tserver {\n\t\thost localhost\n\t\tport 8080\n\t}\n\thosts [\n\t\ta\n\t\tb\n\t]\n}\n// end\n"
+	if code, out, _ := exec(testDoc, "fmt"); code != exitOK || out != want {
+		t.Errorf("[fmt] code: %d; out: %q; want: %q", code, out, want)
+	}
+	name := writeFile(t, "a.nano", testDoc)
+	if code, out, _ := exec("", "fmt", "-l", name); code != exitFail || out != name+"\n" {
+		t.Errorf("[fmt -l] code: %d; out: %q", code, out)
+	}
+	if data, _ := os.ReadFile(name); string(data) != testDoc {
+		t.Errorf("[fmt -l] file: %q; want: %q", data, testDoc)
+	}
+	// the files are formatted in place
+	if code, out, _ := exec("", "fmt", name); code != exitOK || out != "" {
+		t.Errorf("[fmt] code: %d; out: %q", code, out)
+	}
+	if data, _ := os.ReadFile(name); string(data) != want {
+		t.Errorf("[fmt] file: %q; want: %q", data, want)
+	}
+	if code, out, _ := exec("", "fmt", "-l", name); code != exitOK || out != "" {
+		t.Errorf("[fmt -l] code: %d; out: %q", code, out)
+	}
+	multi := "{\n  text `\n  line\n`\n  port 8080\n}\n"
+	want = "{\n  text `\n  line\n`\n  port 8080\n}\n"
+	if code, out, _ := exec(multi, "fmt", "-tabs=false", "-width", "2"); code != exitOK || out != want {
+		t.Errorf("[fmt] code: %d; out: %q; want: %q", code, out, want)
+	}
+	if code, _, errs := exec("{\na\n", "fmt"); code != exitError || errs == "" {
+		t.Errorf("[fmt] code: %d; want: %d", code, exitError)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if code, _, errs := exec(testDoc, "validate"); code != exitOK || errs != "" {
+		t.Errorf("[validate] code: %d; errors: %q", code, errs)
+	}
+	if code, _, errs := exec("{\nkey value\n", "validate"); code != exitFail || !strings.HasPrefix(errs, "-: ") {
+		t.Errorf("[validate] code: %d; errors: %q", code, errs)
+	}
+	if code, _, _ := exec("", "validate", filepath.Join(t.TempDir(), "missing.nano")); code != exitError {
+		t.Errorf("[validate] code: %d; want: %d", code, exitError)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	want := "{\n// the server\nserver {\nhost localhost\nport 8080\n}\n}\n"
+	if code, out, _ := exec("{\n\t// the server\n\tserver {\n\t\thost localhost\n\t\tport 8080\n\t}\n}\n", "compact"); code != exitOK || out != want {
+		t.Errorf("[compact] code: %d; out: %q; want: %q", code, out, want)
+	}
+	want = "{\nserver {\nhost localhost\nport 8080\n}\n}\n"
+	if code, out, _ := exec("{\n\t// the server\n\tserver {\n\t\thost localhost\n\n\t\tport 8080 // the port\n\t}\n}\n", "compact", "-minify"); code != exitOK || out != want {
+		t.Errorf("[compact -minify] code: %d; out: %q; want: %q", code, out, want)
+	}
+}
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		path string
+		code int
+		out  string
+	}{
+		{"server.port", exitOK, "8080\n"},
+		{"hosts[1]", exitOK, "b\n"},
+		{"hosts.0", exitOK, "a\n"},
+		{"server", exitOK, "{\n\thost localhost\n\tport 8080\n}\n"},
+		{"server.name", exitFail, ""},
+		{"hosts[2]", exitFail, ""},
+		{"hosts[*]", exitOK, "a\nb\n"},
+		{"hosts[-1]", exitOK, "b\n"},
+		{"..port", exitOK, "8080\n"},
+		{"hosts[x]", exitError, ""},
+		{"server.", exitError, ""},
+	}
+	for _, test := range tests {
+		if code, out, _ := exec(testDoc, "get", test.path); code != test.code || out != test.out {
+			t.Errorf("[get %s] code: %d; out: %q; want: %d, %q", test.path, code, out, test.code, test.out)
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		path  string
+		value string
+		code  int
+		out   string
+	}{
+		{"server.port", "9090", exitOK, "// top\n{\n  // the server\n  server {\n    host localhost\n\n    port 9090 // the port\n  }\n  hosts [\n    a\n    b\n  ]\n}\n// end\n"},
+		{"['hosts'][2]", "c", exitOK, "// top\n{\n  // the server\n  server {\n    host localhost\n\n    port 8080 // the port\n  }\n  hosts [\n    a\n    b\n    c\n  ]\n}\n// end\n"},
+		{"hosts.-1", "c", exitOK, "// top\n{\n  // the server\n  server {\n    host localhost\n\n    port 8080 // the port\n  }\n  hosts [\n    a\n    c\n  ]\n}\n// end\n"},
+		{"server.tls.enabled", "true", exitOK, "// top\n{\n  // the server\n  server {\n    host localhost\n\n    port 8080 // the port\n    tls {\n      enabled true\n    }\n  }\n  hosts [\n    a\n    b\n  ]\n}\n// end\n"},
+		{"hosts[5]", "c", exitError, ""},
+		{"hosts[*]", "c", exitError, ""},
+		{"server.port.x", "1", exitError, ""},
+	}
+	// the layout and the comments of the document are kept
+	doc := "// top\n{\n  // the server\n  server {\n    host localhost\n\n    port 8080 // the port\n  }\n  hosts [\n    a\n    b\n  ]\n}\n// end\n"
+	for _, test := range tests {
+		if code, out, _ := exec(doc, "set", test.path, test.value); code != test.code || out != test.out {
+			t.Errorf("[set %s] code: %d; out: %q; want: %d, %q", test.path, code, out, test.code, test.out)
+		}
+	}
+	name := writeFile(t, "a.nano", testDoc)
+	if code, _, _ := exec("", "set", "-w", "server.port", "9090", name); code != exitOK {
+		t.Errorf("[set -w] code: %d", code)
+	}
+	if code, out, _ := exec("", "get", "server.port", name); code != exitOK || out != "9090\n" {
+		t.Errorf("[set -w] code: %d; out: %q", code, out)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	want := "{\n\t\"server\": {\n\t\t\"host\": \"localhost\",\n\t\t\"port\": \"8080\"\n\t}\n}\n"
+	if code, out, errs := exec("{\nserver {\nhost localhost\nport 8080\n}\n}\n", "convert", "-to", "json"); code != exitOK || out != want {
+		t.Errorf("[convert] code: %d; out: %q; want: %q; errors: %q", code, out, want, errs)
+	}
+	name := writeFile(t, "a.json", want)
+	nano := "{\n\tserver {\n\t\thost localhost\n\t\tport 8080\n\t}\n}\n"
+	if code, out, errs := exec("", "convert", name); code != exitOK || out != nano {
+		t.Errorf("[convert] code: %d; out: %q; want: %q; errors: %q", code, out, nano, errs)
+	}
+	if code, out, errs := exec("[server]\nhost = localhost\nport = 8080\n", "convert", "-from", "ini", "-to", "yml"); code != exitOK || out != "server:\n  host: localhost\n  port: 8080\n" {
+		t.Errorf("[convert] code: %d; out: %q; errors: %q", code, out, errs)
+	}
+	if code, _, _ := exec("{\n}\n", "convert", "-to", "png"); code != exitError {
+		t.Errorf("[convert] code: %d; want: %d", code, exitError)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := writeFile(t, "a.nano", testDoc)
+	b := writeFile(t, "b.nano", "{\nserver {\nhost localhost\nport 9090\ntls {\n}\n}\nhosts [\na\n]\n}\n")
+	if code, out, _ := exec("", "diff", a, a); code != exitOK || out != "" {
+		t.Errorf("[diff] code: %d; out: %q", code, out)
+	}
+	want := "~ server.port: 8080 -> 9090\n+ server.tls: {}\n- hosts[1]: b\n"
+	if code, out, _ := exec("", "diff", a, b); code != exitFail || out != want {
+		t.Errorf("[diff] code: %d; out: %q; want: %q", code, out, want)
+	}
+	want = "{\nserver {\nport 9090\ntls {\n}\n}\nhosts [\na\n]\n}\n"
+	if code, out, _ := exec("", "diff", "-patch", a, b); code != exitFail || out != want {
+		t.Errorf("[diff -patch] code: %d; out: %q; want: %q", code, out, want)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	if code, _, errs := exec("", "unknown"); code != exitError || !strings.Contains(errs, "Usage:") {
+		t.Errorf("[usage] code: %d; errors: %q", code, errs)
+	}
+	if code, _, _ := exec("", "get"); code != exitError {
+		t.Errorf("[get] code: %d; want: %d", code, exitError)
+	}
+}
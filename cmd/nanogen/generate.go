@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/doc"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/nanomarkup/nanomarkup.go/internal/nanotag"
+)
+
+// typeDecl is a type declared in the package.
+type typeDecl struct {
+	doc  string
+	expr ast.Expr
+}
+
+// goPackage holds the declared types of a Go package.
+type goPackage struct {
+	name  string
+	types map[string]typeDecl
+}
+
+// generator writes the statements which fill the metadata of a type.
+type generator struct {
+	pkg  *goPackage
+	buf  *bytes.Buffer
+	vars int
+	// the types which are being described to stop at recursive types
+	visiting map[string]bool
+}
+
+// parsePackage parses the Go files of the directory except the tests.
+func parsePackage(dir string) (*goPackage, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	files := []*ast.File{}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		// skip the files excluded by the build constraints as the go command does
+		if ok, err := build.Default.MatchFile(dir, name); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) > 0 && f.Name.Name != files[0].Name.Name {
+			return nil, fmt.Errorf("%s: want one package, found %s and %s", dir, files[0].Name.Name, f.Name.Name)
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%s: no Go files", dir)
+	}
+	d, err := doc.NewFromFiles(fset, files, files[0].Name.Name, doc.AllDecls|doc.PreserveAST)
+	if err != nil {
+		return nil, err
+	}
+	out := &goPackage{name: d.Name, types: map[string]typeDecl{}}
+	for _, t := range d.Types {
+		for _, spec := range t.Decl.Specs {
+			if s, ok := spec.(*ast.TypeSpec); ok && s.Name.Name == t.Name {
+				out.types[t.Name] = typeDecl{t.Doc, s.Type}
+			}
+		}
+	}
+	return out, nil
+}
+
+// generate returns the formatted source of the functions which return the metadata of the types.
+func (p *goPackage) generate(names []string) ([]byte, error) {
+	buf := bytes.Buffer{}
+	buf.WriteString("// Code generated by nanogen; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", p.name)
+	buf.WriteString("import \"github.com/nanomarkup/nanomarkup.go/nanometadata\"\n")
+	for _, name := range names {
+		t, ok := p.types[name]
+		if !ok {
+			return nil, fmt.Errorf("type %s is not found in package %s", name, p.name)
+		}
+		fmt.Fprintf(&buf, "\n// %sMetadata returns the metadata of %s with the comments of the Go source.\n", name, name)
+		fmt.Fprintf(&buf, "func %sMetadata() *nanometadata.Metadata {\n", name)
+		buf.WriteString("m := &nanometadata.Metadata{}\n")
+		g := generator{pkg: p, buf: &buf, visiting: map[string]bool{name: true}}
+		g.comments("m", t.doc)
+		g.fields("m", t.expr)
+		buf.WriteString("return m\n}\n")
+	}
+	return format.Source(buf.Bytes())
+}
+
+// comments writes the statements which add the lines of the text to the comments of the variable.
+// Empty lines are skipped, so the comments are not separated by blank lines.
+func (g *generator) comments(v, text string) {
+	for _, line := range strings.Split(text, "\n") {
+		if line != "" {
+			fmt.Fprintf(g.buf, "%s.Comments.Add(%s, false)\n", v, strconv.Quote(" "+line))
+		}
+	}
+}
+
+// fields writes the statements which fill the metadata of the variable
+// by the comments of the fields if the type is a struct.
+func (g *generator) fields(v string, expr ast.Expr) {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		g.fields(v, t.X)
+	case *ast.ParenExpr:
+		g.fields(v, t.X)
+	case *ast.Ident:
+		decl, ok := g.pkg.types[t.Name]
+		if !ok || g.visiting[t.Name] {
+			return
+		}
+		g.visiting[t.Name] = true
+		g.fields(v, decl.expr)
+		delete(g.visiting, t.Name)
+	case *ast.ArrayType:
+		g.elem(v, t.Elt)
+	case *ast.MapType:
+		g.elem(v, t.Value)
+	case *ast.StructType:
+		for _, f := range t.Fields.List {
+			if len(f.Names) == 0 {
+				// the fields of an embedded struct are promoted
+				g.fields(v, f.Type)
+				continue
+			}
+			for _, name := range f.Names {
+				if name.IsExported() {
+					g.field(v, name.Name, f)
+				}
+			}
+		}
+	}
+}
+
+// elem writes the statements which set the metadata of the items of an array, a slice or a map
+// if the element type has comments.
+func (g *generator) elem(v string, expr ast.Expr) {
+	g.vars++
+	child := fmt.Sprintf("m%d", g.vars)
+	parent := g.buf
+	body := bytes.Buffer{}
+	g.buf = &body
+	g.fields(child, expr)
+	g.buf = parent
+	if body.Len() == 0 {
+		return
+	}
+	fmt.Fprintf(g.buf, "%s := &nanometadata.Metadata{}\n", child)
+	g.buf.Write(body.Bytes())
+	fmt.Fprintf(g.buf, "%s.SetElem(%s)\n", v, child)
+}
+
+// field writes the statements which add the metadata of the field if it has comments.
+func (g *generator) field(v, name string, f *ast.Field) {
+	g.vars++
+	child := fmt.Sprintf("m%d", g.vars)
+	parent := g.buf
+	body := bytes.Buffer{}
+	g.buf = &body
+	g.comments(child, f.Doc.Text())
+	if text := strings.TrimSpace(f.Comment.Text()); text != "" {
+		fmt.Fprintf(g.buf, "%s.Trailing = %s\n", child, strconv.Quote(" "+strings.ReplaceAll(text, "\n", " ")))
+	}
+	g.fields(child, f.Type)
+	g.buf = parent
+	if body.Len() == 0 {
+		return
+	}
+	fmt.Fprintf(g.buf, "%s := &nanometadata.Metadata{}\n", child)
+	g.buf.Write(body.Bytes())
+	fmt.Fprintf(g.buf, "%s.AddField(%s, %s)\n", v, strconv.Quote(name), child)
+	if key := fieldKey(name, f); key != name {
+		fmt.Fprintf(g.buf, "%s.SetKey(%s, %s)\n", v, strconv.Quote(name), strconv.Quote(key))
+	}
+}
+
+// fieldKey returns the key of the field in nano data, which is the name of the nano tag if it is set.
+func fieldKey(name string, f *ast.Field) string {
+	if f.Tag == nil {
+		return name
+	}
+	text, err := strconv.Unquote(f.Tag.Value)
+	if err != nil {
+		return name
+	}
+	return nanotag.Parse(reflect.StructField{Name: name, Tag: reflect.StructTag(text)}).Name
+}
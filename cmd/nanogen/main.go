@@ -0,0 +1,90 @@
+// Nanogen generates functions which return the metadata of Go types with the comments
+// of the Go source, so the marshaled data is documented in the same way as the types.
+//
+// Usage:
+//
+//	nanogen -type T[,T...] [-output file] [dir]
+//
+// It is intended to be run by go generate in the directory of a package:
+//
+//	//go:generate nanogen -type Config
+//
+// The doc comment of a type becomes the comment of the value, the doc comments of struct
+// fields become the comments of the fields and the line comments of fields become the
+// trailing comments. Struct types of fields which are declared in the same package are
+// described recursively, the element types of arrays, slices and maps are described
+// by the metadata shared by the items. The generated function of the type T is named TMetadata
+// and the output file is t_nanometa.go in the directory of the package by default.
+//
+// The exit code is 0 on success and 2 on a usage, a parsing or an I/O error.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	exitOK    int = 0
+	exitError int = 2
+)
+
+// cli holds the standard streams of the tool.
+type cli struct {
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	c := cli{stdout: os.Stdout, stderr: os.Stderr}
+	os.Exit(c.run(os.Args[1:]))
+}
+
+func (c *cli) run(args []string) int {
+	fs := flag.NewFlagSet("nanogen", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(c.stderr, "Usage: nanogen -type T[,T...] [-output file] [dir]")
+		fs.PrintDefaults()
+	}
+	types := fs.String("type", "", "comma-separated list of type names")
+	output := fs.String("output", "", "the output file, the default is <type>_nanometa.go")
+	if fs.Parse(args) != nil {
+		return exitError
+	}
+	if *types == "" || fs.NArg() > 1 {
+		fs.Usage()
+		return exitError
+	}
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+	names := strings.Split(*types, ",")
+	pkg, err := parsePackage(dir)
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+	src, err := pkg.generate(names)
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+	name := *output
+	if name == "" {
+		name = filepath.Join(dir, strings.ToLower(names[0])+"_nanometa.go")
+	}
+	if err = os.WriteFile(name, src, 0644); err != nil {
+		return c.errorf("%v", err)
+	}
+	return exitOK
+}
+
+// errorf prints the error and returns the exit code of errors.
+func (c *cli) errorf(format string, a ...any) int {
+	fmt.Fprintf(c.stderr, "nanogen: "+format+"\n", a...)
+	return exitError
+}
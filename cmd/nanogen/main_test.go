@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSource = `package config
+
+//go:generate nanogen -type Config
+
+// Config is the configuration of the service.
+//
+// It is loaded at startup.
+type Config struct {
+	// Name of the service.
+	Name string
+	// Server is the main server.
+	Server Server
+	Backup *Server // the backup server
+	Limits struct {
+		// Rate is the number of requests per second.
+		Rate int
+	}
+	Options
+	Hosts  []string
+	secret string // not written
+}
+
+// Server is a listener.
+type Server struct {
+	// Host name.
+	Host string
+	Port int ` + "`nano:\"port\"`" + ` // listening port
+	Next *Server
+}
+
+type Options struct {
+	// Debug enables the debug mode.
+	Debug bool
+}
+`
+
+const testOutput = `// Code generated by nanogen; DO NOT EDIT.
+
+package config
+
+import "github.com/nanomarkup/nanomarkup.go/nanometadata"
+
+// ConfigMetadata returns the metadata of Config with the comments of the Go source.
+func ConfigMetadata() *nanometadata.Metadata {
+	m := &nanometadata.Metadata{}
+	m.Comments.Add(" Config is the configuration of the service.", false)
+	m.Comments.Add(" It is loaded at startup.", false)
+	m1 := &nanometadata.Metadata{}
+	m1.Comments.Add(" Name of the service.", false)
+	m.AddField("Name", m1)
+	m2 := &nanometadata.Metadata{}
+	m2.Comments.Add(" Server is the main server.", false)
+	m3 := &nanometadata.Metadata{}
+	m3.Comments.Add(" Host name.", false)
+	m2.AddField("Host", m3)
+	m4 := &nanometadata.Metadata{}
+	m4.Trailing = " listening port"
+	m2.AddField("Port", m4)
+	m2.SetKey("Port", "port")
+	m.AddField("Server", m2)
+	m6 := &nanometadata.Metadata{}
+	m6.Trailing = " the backup server"
+	m7 := &nanometadata.Metadata{}
+	m7.Comments.Add(" Host name.", false)
+	m6.AddField("Host", m7)
+	m8 := &nanometadata.Metadata{}
+	m8.Trailing = " listening port"
+	m6.AddField("Port", m8)
+	m6.SetKey("Port", "port")
+	m.AddField("Backup", m6)
+	m10 := &nanometadata.Metadata{}
+	m11 := &nanometadata.Metadata{}
+	m11.Comments.Add(" Rate is the number of requests per second.", false)
+	m10.AddField("Rate", m11)
+	m.AddField("Limits", m10)
+	m12 := &nanometadata.Metadata{}
+	m12.Comments.Add(" Debug enables the debug mode.", false)
+	m.AddField("Debug", m12)
+	return m
+}
+`
+
+func exec(args ...string) (int, string) {
+	stderr := bytes.Buffer{}
+	c := cli{stdout: &bytes.Buffer{}, stderr: &stderr}
+	code := c.run(args)
+	return code, stderr.String()
+}
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "config.go"), []byte(testSource), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code, errs := exec("-type", "Config", dir); code != exitOK {
+		t.Fatalf("[nanogen] code: %d; errors: %s", code, errs)
+	}
+	out, err := os.ReadFile(filepath.Join(dir, "config_nanometa.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != testOutput {
+		t.Errorf("[nanogen] out: %s\nwant: %s", out, testOutput)
+	}
+	// the generated file does not break the parsing of the package
+	name := filepath.Join(dir, "meta.go")
+	if code, errs := exec("-type", "Config,Server", "-output", name, dir); code != exitOK {
+		t.Fatalf("[nanogen] code: %d; errors: %s", code, errs)
+	}
+	if out, err = os.ReadFile(name); err != nil || !strings.Contains(string(out), "func ServerMetadata() *nanometadata.Metadata {") {
+		t.Errorf("[nanogen] out: %s; error: %v", out, err)
+	}
+}
+
+func TestGenerateElements(t *testing.T) {
+	src := `package config
+
+type Config struct {
+	Servers []Server
+	Zones   map[string]*Server
+	Backups Backups
+	Names   []string
+}
+
+type Backups [2]Server
+
+type Server struct {
+	// Host name.
+	Host string
+}
+`
+	want := `// Code generated by nanogen; DO NOT EDIT.
+
+package config
+
+import "github.com/nanomarkup/nanomarkup.go/nanometadata"
+
+// ConfigMetadata returns the metadata of Config with the comments of the Go source.
+func ConfigMetadata() *nanometadata.Metadata {
+	m := &nanometadata.Metadata{}
+	m1 := &nanometadata.Metadata{}
+	m2 := &nanometadata.Metadata{}
+	m3 := &nanometadata.Metadata{}
+	m3.Comments.Add(" Host name.", false)
+	m2.AddField("Host", m3)
+	m1.SetElem(m2)
+	m.AddField("Servers", m1)
+	m4 := &nanometadata.Metadata{}
+	m5 := &nanometadata.Metadata{}
+	m6 := &nanometadata.Metadata{}
+	m6.Comments.Add(" Host name.", false)
+	m5.AddField("Host", m6)
+	m4.SetElem(m5)
+	m.AddField("Zones", m4)
+	m7 := &nanometadata.Metadata{}
+	m8 := &nanometadata.Metadata{}
+	m9 := &nanometadata.Metadata{}
+	m9.Comments.Add(" Host name.", false)
+	m8.AddField("Host", m9)
+	m7.SetElem(m8)
+	m.AddField("Backups", m7)
+	return m
+}
+`
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "config.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// the tests and other files are skipped
+	if err := os.WriteFile(filepath.Join(dir, "config_test.go"), []byte("package config_test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "readme.txt"), []byte("text"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// the files excluded by the build constraints are skipped
+	if err := os.WriteFile(filepath.Join(dir, "gen.go"), []byte("//go:build ignore\n\npackage main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code, errs := exec("-type", "Config", dir); code != exitOK {
+		t.Fatalf("[nanogen] code: %d; errors: %s", code, errs)
+	}
+	out, err := os.ReadFile(filepath.Join(dir, "config_nanometa.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != want {
+		t.Errorf("[nanogen] out: %s\nwant: %s", out, want)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "config.go"), []byte(testSource), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mixed := t.TempDir()
+	if err := os.WriteFile(filepath.Join(mixed, "config.go"), []byte(testSource), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(mixed, "other.go"), []byte("package other\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := [][]string{
+		{},
+		{"-type", "Missing", dir},
+		{"-type", "Config", mixed},
+		{"-type", "Config", t.TempDir()},
+		{"-type", "Config", filepath.Join(dir, "missing")},
+		{"-type", "Config", dir, dir},
+		{"-unknown"},
+	}
+	for _, args := range tests {
+		if code, errs := exec(args...); code != exitError || errs == "" {
+			t.Errorf("[nanogen %s] code: %d; errors: %q", strings.Join(args, " "), code, errs)
+		}
+	}
+}
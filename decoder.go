@@ -0,0 +1,213 @@
+package nanomarkup
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/nanomarkup/nanomarkup.go/nanocomment"
+	"github.com/nanomarkup/nanomarkup.go/nanoerror"
+	"github.com/nanomarkup/nanomarkup.go/nanometadata"
+	"github.com/nanomarkup/nanomarkup.go/nanostr"
+)
+
+// String returns a string representation of the delimiter.
+func (d Delim) String() string {
+	return string(d)
+}
+
+// Decode reads the next nano-encoded value from its input and stores it in the value pointed to by v.
+// The comments before the value are stored in the metadata.
+//
+// See the documentation for Unmarshal for details about the conversion of nano data into a Go value.
+func (d *Decoder) Decode(v any, meta *nanometadata.Metadata) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer {
+		return &nanoerror.InvalidArgumentError{Context: "Decode", Err: fmt.Errorf("the first argument is not a Pointer")}
+	}
+	if rv.IsNil() {
+		return &nanoerror.InvalidArgumentError{Context: "Decode", Err: fmt.Errorf("the first argument is Nil")}
+	}
+	item, err := d.nextValue()
+	if err != nil {
+		return err
+	}
+	if meta != nil && len(d.comments) > 0 {
+		meta.Comments.Adds(d.comments)
+	}
+	if err = unmarshalItem(d.d, item, rv.Elem(), meta); err != nil {
+		return err
+	}
+	return d.d.Err()
+}
+
+// Token returns the next nano token in the input stream.
+// At the end of the input stream, Token returns nil, io.EOF.
+//
+// Comments are not returned as tokens, use the Comments method to get
+// the comments which precede the last token and the Trailing method to get
+// the trailing comment of the last value.
+func (d *Decoder) Token() (Token, error) {
+	d.trailing = ""
+	if d.pending {
+		item := d.value
+		d.pending = false
+		d.value = nil
+		d.comments = nanocomment.Comments{}
+		return d.valueToken(item)
+	}
+	if d.scoped && len(d.stack) == 0 {
+		return nil, io.EOF
+	}
+	item, ok, err := d.next()
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		if len(d.stack) > 0 {
+			return nil, d.missingError()
+		}
+		return nil, io.EOF
+	}
+	val := bytes.TrimRight(item, " \t")
+	if len(val) == 1 && (val[0] == 93 || val[0] == 125) { // ], }
+		typ := entity
+		if val[0] == 93 {
+			typ = array
+		}
+		if len(d.stack) == 0 || d.stack[len(d.stack)-1] != typ {
+			return nil, &nanoerror.InvalidEntityError{Context: "Decode", Entity: string(val), Err: fmt.Errorf("there is nothing to close")}
+		}
+		d.stack = d.stack[:len(d.stack)-1]
+		return Delim(val[0]), nil
+	}
+	if len(d.stack) > 0 && d.stack[len(d.stack)-1] == entity {
+		ks, vs := splitItem(item)
+		d.value = vs
+		d.pending = true
+		return Key(ks), nil
+	}
+	return d.valueToken(item)
+}
+
+// More reports whether there is another element in the current array or entity being parsed.
+func (d *Decoder) More() bool {
+	if d.pending {
+		return true
+	}
+	if d.scoped && len(d.stack) == 0 {
+		return false
+	}
+	if !d.peeked {
+		d.peek, d.peekComments, d.peekOk, _ = nextItem(d.d)
+		d.peeked = true
+	}
+	if !d.peekOk {
+		return false
+	}
+	val := bytes.TrimRight(d.peek, " \t")
+	return !(len(val) == 1 && (val[0] == 93 || val[0] == 125)) // ], }
+}
+
+// Comments returns the comments which precede the last token or value.
+func (d *Decoder) Comments() nanocomment.Comments {
+	return d.comments
+}
+
+// Trailing returns the text of the trailing comment after "//" of the last token
+// if it is a single-line value, otherwise it returns an empty string.
+func (d *Decoder) Trailing() string {
+	return d.trailing
+}
+
+// decodeFrom reads the value using the UnmarshalNanoFrom method
+// and checks that the value is read completely.
+func (d *Decoder) decodeFrom(m UnmarshalerFrom) error {
+	if err := m.UnmarshalNanoFrom(d); err != nil {
+		return err
+	}
+	if d.pending || len(d.stack) > 0 {
+		return &nanoerror.InvalidEntityError{Context: "Decode", Entity: fmt.Sprintf("%T", m), Err: fmt.Errorf("the value is not read completely")}
+	}
+	return d.d.Err()
+}
+
+// next returns the next item including the item which is read in advance.
+func (d *Decoder) next() ([]byte, bool, error) {
+	if d.peeked {
+		d.peeked = false
+		d.comments = d.peekComments
+		return d.peek, d.peekOk, d.d.Err()
+	}
+	item, comments, ok, err := nextItem(d.d)
+	d.comments = comments
+	if err == nil {
+		err = d.d.Err()
+	}
+	return item, ok, err
+}
+
+// nextValue returns the first item of the next value.
+func (d *Decoder) nextValue() ([]byte, error) {
+	if d.pending {
+		item := d.value
+		d.pending = false
+		d.value = nil
+		d.comments = nanocomment.Comments{}
+		return item, nil
+	}
+	if len(d.stack) > 0 && d.stack[len(d.stack)-1] == entity {
+		return nil, &nanoerror.InvalidEntityError{Context: "Decode", Entity: "", Err: fmt.Errorf("a key of the value is missing")}
+	}
+	if d.scoped && len(d.stack) == 0 {
+		return nil, io.EOF
+	}
+	item, ok, err := d.next()
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		if len(d.stack) > 0 {
+			return nil, d.missingError()
+		}
+		return nil, io.EOF
+	}
+	val := bytes.TrimRight(item, " \t")
+	if len(val) == 1 && (val[0] == 93 || val[0] == 125) { // ], }
+		return nil, &nanoerror.InvalidEntityError{Context: "Decode", Entity: string(val), Err: fmt.Errorf("a value is missing")}
+	}
+	return item, nil
+}
+
+func (d *Decoder) valueToken(item []byte) (Token, error) {
+	val := bytes.TrimRight(item, " \t")
+	if len(val) > 0 {
+		switch val[0] {
+		case 91: // [
+			if len(val) > 1 {
+				return nil, &nanoerror.InvalidEntityError{Context: "Decode", Entity: string(item), Err: fmt.Errorf("the data of an array must be started from a new line")}
+			}
+			d.stack = append(d.stack, array)
+			return Delim(val[0]), nil
+		case 123: // {
+			if len(val) > 1 {
+				return nil, &nanoerror.InvalidEntityError{Context: "Decode", Entity: string(item), Err: fmt.Errorf("the data of an entity must be started from a new line")}
+			}
+			d.stack = append(d.stack, entity)
+			return Delim(val[0]), nil
+		}
+	}
+	_, d.trailing, _ = nanostr.CutComment(item)
+	s, err := nanostr.Unmarshal(d.d, item)
+	if err != nil {
+		return nil, err
+	}
+	return string(s), d.d.Err()
+}
+
+func (d *Decoder) missingError() error {
+	if d.stack[len(d.stack)-1] == array {
+		return &nanoerror.InvalidEntityError{Context: "Decode", Entity: "", Err: fmt.Errorf("']' is missing")}
+	} else {
+		return &nanoerror.InvalidEntityError{Context: "Decode", Entity: "", Err: fmt.Errorf("'}' is missing")}
+	}
+}
@@ -0,0 +1,121 @@
+package nanomarkup
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/nanomarkup/nanomarkup.go/nanometadata"
+)
+
+func TestDecoderTokens(t *testing.T) {
+	in := `// servers
+{
+Name main
+Servers [
+{
+Port 80
+}
+]
+Text ` + "`" + `
+multi
+line
+` + "`" + `
+Empty 
+}
+`
+	want := []Token{Delim('{'), Key("Name"), "main", Key("Servers"), Delim('['), Delim('{'), Key("Port"), "80",
+		Delim('}'), Delim(']'), Key("Text"), "multi\nline", Key("Empty"), "", Delim('}')}
+	dec := NewDecoder(strings.NewReader(in))
+	for i := 0; ; i++ {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			if i != len(want) {
+				t.Errorf("[Token] the number of tokens is %d; want: %d", i, len(want))
+			}
+			break
+		} else if err != nil {
+			t.Error(err)
+			return
+		}
+		if i >= len(want) || tok != want[i] {
+			t.Errorf("[Token] index: %d; out: %#v", i, tok)
+			return
+		}
+		if i == 0 && dec.Comments().String() != " servers" {
+			t.Errorf("[Token] out comments: %s; want: %s", dec.Comments().String(), " servers")
+		}
+	}
+}
+
+func TestDecoderTrailing(t *testing.T) {
+	dec := NewDecoder(strings.NewReader("{\nPort 8080 // the port\nName main\nEmpty // no value\n}\n"))
+	want := []string{"", "", " the port", "", "", "", " no value", ""}
+	for i := range want {
+		if _, err := dec.Token(); err != nil {
+			t.Error(err)
+			return
+		}
+		if dec.Trailing() != want[i] {
+			t.Errorf("[Trailing] index: %d; out: %q; want: %q", i, dec.Trailing(), want[i])
+		}
+	}
+}
+
+func TestDecoderUnmarshalFrom(t *testing.T) {
+	type test struct {
+		Ring *customRing
+		Name string
+	}
+	in := "{\nRing {\n// the oldest item goes first\nItems [\n1\n2\n]\nName buffer\n}\nName main\n}\n"
+	out := test{}
+	if err := Unmarshal([]byte(in), &out, nil); err != nil {
+		t.Error(err)
+		return
+	}
+	if out.Name != "main" || out.Ring == nil || out.Ring.Name != "buffer" || len(out.Ring.items) != 2 || out.Ring.items[1] != 2 {
+		t.Errorf("[Unmarshal] in: %s; out: %v", in, out)
+	}
+
+	// check the decoder
+	ring := customRing{}
+	meta := nanometadata.Metadata{}
+	dec := NewDecoder(strings.NewReader("// a ring\n{\nItems [\n3\n]\n}\n"))
+	if err := dec.Decode(&ring, &meta); err != nil {
+		t.Error(err)
+		return
+	}
+	if len(ring.items) != 1 || ring.items[0] != 3 || meta.Comments.String() != " a ring" {
+		t.Errorf("[Decode] out: %v; meta: %v", ring, meta)
+	}
+	if err := dec.Decode(&ring, nil); err != io.EOF {
+		t.Errorf("[Decode] out error: %v; want: %v", err, io.EOF)
+	}
+}
+
+func TestDecoderValues(t *testing.T) {
+	in := "[\n{\nPort 80\n}\n{\nPort 443\n}\n]\n"
+	type server struct {
+		Port int
+	}
+	dec := NewDecoder(strings.NewReader(in))
+	if tok, err := dec.Token(); err != nil || tok != Delim('[') {
+		t.Errorf("[Token] out: %v; error: %v", tok, err)
+		return
+	}
+	ports := []int{}
+	for dec.More() {
+		s := server{}
+		if err := dec.Decode(&s, nil); err != nil {
+			t.Error(err)
+			return
+		}
+		ports = append(ports, s.Port)
+	}
+	if tok, err := dec.Token(); err != nil || tok != Delim(']') {
+		t.Errorf("[Token] out: %v; error: %v", tok, err)
+	}
+	if len(ports) != 2 || ports[0] != 80 || ports[1] != 443 {
+		t.Errorf("[Decode] out: %v", ports)
+	}
+}
@@ -0,0 +1,243 @@
+package nanomarkup
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/nanomarkup/nanomarkup.go/nanocomment"
+	"github.com/nanomarkup/nanomarkup.go/nanoerror"
+	"github.com/nanomarkup/nanomarkup.go/nanometadata"
+)
+
+// the size of buffered data which is written to the output stream before the end of a value
+const encoderFlushSize int = 4096
+
+// SetIndent instructs the encoder to format each subsequent encoded value as if indented
+// by the package-level function Indent(dst, src, prefix, indent).
+// The content of multi-line values is not indented.
+func (e *Encoder) SetIndent(prefix, indent string) {
+	e.prefix = prefix
+	e.indent = indent
+}
+
+// Encode writes the encoding of v to the stream.
+// The comments of the metadata are written before the value unless the value follows a key.
+//
+// See the documentation for Marshal for details about the conversion of Go values to nano data.
+func (e *Encoder) Encode(v any, meta *nanometadata.Metadata) error {
+	if err := e.checkValue(); err != nil {
+		return err
+	}
+	if meta != nil && len(meta.Comments) > 0 && !e.key {
+		e.appendData(nanocomment.Marshal(meta.Comments))
+	}
+	val := reflect.ValueOf(v)
+	if m, ok := v.(MarshalerTo); ok && !isValueNil(val) {
+		// write the value directly to the stream
+		if err := e.encodeTo(m); err != nil {
+			return err
+		}
+		return e.flush(false)
+	}
+	out, err := marshalData([]byte{}, val, meta, e.opts)
+	if err != nil {
+		return err
+	}
+	if len(out) == 0 {
+		out = append(out, 10) // new line
+	}
+	e.appendData(out)
+	return e.flush(false)
+}
+
+// Key writes a key of an entity item. The value of the item must be written next.
+func (e *Encoder) Key(name string) error {
+	if len(e.stack) == 0 || e.stack[len(e.stack)-1] != entity {
+		return &nanoerror.InvalidEntityError{Context: "Encode", Entity: name, Err: fmt.Errorf("a key must be written inside an entity")}
+	} else if e.key {
+		return &nanoerror.InvalidEntityError{Context: "Encode", Entity: name, Err: fmt.Errorf("a value of the previous key is missing")}
+	} else if name == "" || strings.ContainsAny(name, " \t\n") {
+		return &nanoerror.InvalidArgumentError{Context: "Encode", Err: fmt.Errorf("invalid key: %q", name)}
+	}
+	e.appendIndent(len(e.stack))
+	e.buf = append(e.buf, name...)
+	e.buf = append(e.buf, 32) // space
+	e.key = true
+	return nil
+}
+
+// Comments writes the comments at the current position.
+// The comments cannot be written between a key and its value.
+func (e *Encoder) Comments(comments nanocomment.Comments) error {
+	if e.key {
+		return &nanoerror.InvalidEntityError{Context: "Encode", Entity: "", Err: fmt.Errorf("comments cannot be written after a key")}
+	}
+	e.appendData(nanocomment.Marshal(comments))
+	return nil
+}
+
+// BeginEntity writes the beginning of an entity.
+func (e *Encoder) BeginEntity() error {
+	return e.begin(entity, "{")
+}
+
+// EndEntity writes the end of an entity.
+func (e *Encoder) EndEntity() error {
+	return e.end(entity, "}")
+}
+
+// BeginArray writes the beginning of an array.
+func (e *Encoder) BeginArray() error {
+	return e.begin(array, "[")
+}
+
+// EndArray writes the end of an array.
+func (e *Encoder) EndArray() error {
+	return e.end(array, "]")
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (e *Encoder) Flush() error {
+	return e.flush(true)
+}
+
+func (e *Encoder) begin(typ unmarshalType, delim string) error {
+	if err := e.checkValue(); err != nil {
+		return err
+	}
+	e.appendLine([]byte(delim), len(e.stack))
+	e.stack = append(e.stack, typ)
+	return nil
+}
+
+func (e *Encoder) end(typ unmarshalType, delim string) error {
+	if len(e.stack) == 0 || e.stack[len(e.stack)-1] != typ {
+		return &nanoerror.InvalidEntityError{Context: "Encode", Entity: delim, Err: fmt.Errorf("there is nothing to close")}
+	} else if e.key {
+		return &nanoerror.InvalidEntityError{Context: "Encode", Entity: delim, Err: fmt.Errorf("a value of the previous key is missing")}
+	}
+	e.stack = e.stack[:len(e.stack)-1]
+	e.appendLine([]byte(delim), len(e.stack))
+	return e.flush(false)
+}
+
+// checkValue checks a value can be written at the current position.
+func (e *Encoder) checkValue() error {
+	if len(e.stack) > 0 && e.stack[len(e.stack)-1] == entity && !e.key {
+		return &nanoerror.InvalidEntityError{Context: "Encode", Entity: "", Err: fmt.Errorf("a key of the value is missing")}
+	}
+	return nil
+}
+
+// encodeTo writes the value using the MarshalNanoTo method
+// and checks that all opened entities and arrays are closed.
+func (e *Encoder) encodeTo(m MarshalerTo) error {
+	depth := len(e.stack)
+	key := e.key
+	size := len(e.buf)
+	if err := m.MarshalNanoTo(e); err != nil {
+		return err
+	}
+	if len(e.stack) != depth || e.key || (key && len(e.buf) == size) {
+		return &nanoerror.InvalidEntityError{Context: "Encode", Entity: fmt.Sprintf("%T", m), Err: fmt.Errorf("the value is not written completely")}
+	}
+	return nil
+}
+
+// flush writes the buffered data if the value is completed or the buffer is large enough.
+func (e *Encoder) flush(force bool) error {
+	if e.w == nil {
+		return nil
+	}
+	completed := len(e.stack) == 0 && !e.key
+	if !force && !completed && len(e.buf) < encoderFlushSize {
+		return nil
+	}
+	if completed {
+		e.first = true
+	}
+	if len(e.buf) == 0 {
+		return nil
+	}
+	_, err := e.w.Write(e.buf)
+	e.buf = e.buf[:0]
+	return err
+}
+
+func (e *Encoder) appendIndent(level int) {
+	if e.first {
+		e.first = false
+		return
+	}
+	e.buf = append(e.buf, e.prefix...)
+	for i := 0; i < level; i++ {
+		e.buf = append(e.buf, e.indent...)
+	}
+}
+
+// appendLine appends the line at the level or after a key.
+func (e *Encoder) appendLine(line []byte, level int) {
+	if e.key {
+		e.key = false
+	} else if len(line) > 0 {
+		e.appendIndent(level)
+	}
+	e.buf = append(e.buf, line...)
+	e.buf = append(e.buf, 10) // new line
+}
+
+// appendData appends the nano-encoded data indenting it by the current level.
+// The content of multi-line values and multi-line comments is appended as is.
+func (e *Encoder) appendData(src []byte) {
+	level := len(e.stack)
+	stack := []unmarshalType{}
+	multi := false
+	comment := false
+	for _, line := range bytes.Split(bytes.TrimSuffix(src, []byte("\n")), []byte("\n")) {
+		if multi || comment {
+			e.buf = append(e.buf, line...)
+			e.buf = append(e.buf, "\n"...)
+			if multi && len(line) == 1 && line[0] == 96 { // `
+				multi = false
+			} else if comment && bytes.HasSuffix(bytes.TrimRight(line, " "), []byte("*/")) {
+				comment = false
+			}
+			continue
+		}
+		item := bytes.Trim(line, " \t")
+		if len(item) == 1 && (item[0] == 93 || item[0] == 125) { // ], }
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			if level > 0 {
+				level--
+			}
+			e.appendLine(item, level)
+			continue
+		}
+		if len(item) > 1 && item[0] == 47 && (item[1] == 47 || item[1] == 42) { // //, /*
+			e.appendLine(bytes.TrimLeft(line, " \t"), level)
+			comment = item[1] == 42 && !bytes.HasSuffix(item[2:], []byte("*/"))
+			continue
+		}
+		e.appendLine(bytes.TrimLeft(line, " \t"), level)
+		// get a value of the item
+		if len(stack) > 0 && stack[len(stack)-1] == entity {
+			_, item = splitItem(item)
+		}
+		if len(item) == 1 {
+			switch item[0] {
+			case 91: // [
+				stack = append(stack, array)
+				level++
+			case 123: // {
+				stack = append(stack, entity)
+				level++
+			case 96: // `
+				multi = true
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package nanomarkup
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nanomarkup/nanomarkup.go/nanometadata"
+)
+
+func TestEncoderMarshalTo(t *testing.T) {
+	type test struct {
+		Ring customRing
+		Text string
+	}
+	in := test{customRing{"buffer", []int{1, 2}}, "multi\nline"}
+	want := "{\nRing {\nName buffer\n// the oldest item goes first\nItems [\n1\n2\n]\n}\nText `\nmulti\nline\n`\n}\n"
+	out, err := Marshal(&in, nil)
+	if s := checkMarshal(in, out, want, err); s != "" {
+		t.Error(s)
+	}
+
+	// check the encoder with indentation
+	want = "{\n" +
+		"##  Ring {\n" +
+		"##    Name buffer\n" +
+		"##    // the oldest item goes first\n" +
+		"##    Items [\n" +
+		"##      1\n" +
+		"##      2\n" +
+		"##    ]\n" +
+		"##  }\n" +
+		"##  Text `\n" +
+		"multi\n" +
+		"line\n" +
+		"`\n" +
+		"##}\n"
+	dst := bytes.Buffer{}
+	enc := NewEncoder(&dst)
+	enc.SetIndent("##", "  ")
+	if err = enc.Encode(&in, nil); err != nil {
+		t.Error(err)
+		return
+	}
+	if dst.String() != want {
+		t.Errorf("[Encode] in: %v; out: %s; want: %s", in, dst.String(), want)
+	}
+
+	// check the round trip
+	dst.Reset()
+	enc.SetIndent("", "\t")
+	if err = enc.Encode(&in, nil); err != nil {
+		t.Error(err)
+		return
+	}
+	res := test{}
+	if err = Unmarshal(dst.Bytes(), &res, nil); err != nil {
+		t.Error(err)
+		return
+	}
+	if res.Ring.Name != in.Ring.Name || len(res.Ring.items) != 2 || res.Text != in.Text {
+		t.Errorf("[Unmarshal] in: %s; out: %v; want: %v", dst.String(), res, in)
+	}
+}
+
+func TestEncoderCommentStyle(t *testing.T) {
+	in := struct {
+		Name string `comment:"Service name\nThe name is unique"`
+		Port int    `comment:"Listening port"`
+	}{"api", 8080}
+	want := "{\n/* Service name\nThe name is unique */\nName api\n/* Listening port */\nPort 8080\n}\n"
+	dst := bytes.Buffer{}
+	enc := NewEncoder(&dst, WithCommentStyle(BlockComments))
+	if err := enc.Encode(in, nil); err != nil {
+		t.Fatal(err)
+	}
+	if dst.String() != want {
+		t.Errorf("[Encode] out: %q; want: %q", dst.String(), want)
+	}
+}
+
+func TestEncoderTokens(t *testing.T) {
+	dst := bytes.Buffer{}
+	enc := NewEncoder(&dst)
+	enc.SetIndent("", "\t")
+	meta := nanometadata.CreateMetadata(" servers", false)
+	steps := []func() error{
+		enc.BeginEntity,
+		func() error { return enc.Key("Servers") },
+		enc.BeginArray,
+		func() error { return enc.Encode(map[string]int{"Port": 80}, nil) },
+		func() error { return enc.Encode(&customRing{"ring", []int{7}}, nil) },
+		enc.EndArray,
+		func() error { return enc.Comments(meta.Comments) },
+		func() error { return enc.Key("Name") },
+		func() error { return enc.Encode("main", meta) },
+		enc.EndEntity,
+	}
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Errorf("[Encode] step %d: %s", i, err.Error())
+			return
+		}
+	}
+	want := "{\n" +
+		"\tServers [\n" +
+		"\t\t{\n" +
+		"\t\t\tPort 80\n" +
+		"\t\t}\n" +
+		"\t\t{\n" +
+		"\t\t\tName ring\n" +
+		"\t\t\t// the oldest item goes first\n" +
+		"\t\t\tItems [\n" +
+		"\t\t\t\t7\n" +
+		"\t\t\t]\n" +
+		"\t\t}\n" +
+		"\t]\n" +
+		"\t// servers\n" +
+		"\tName main\n" +
+		"}\n"
+	if dst.String() != want {
+		t.Errorf("[Encode] out: %s; want: %s", dst.String(), want)
+	}
+}
+
+func TestEncoderErrors(t *testing.T) {
+	enc := NewEncoder(&bytes.Buffer{})
+	if err := enc.Key("Name"); err == nil {
+		t.Error("[Encode] a key outside an entity must fail")
+	}
+	if err := enc.EndArray(); err == nil {
+		t.Error("[Encode] closing of an unopened array must fail")
+	}
+	if err := enc.BeginEntity(); err != nil {
+		t.Error(err)
+	}
+	if err := enc.Encode(1, nil); err == nil {
+		t.Error("[Encode] a value without a key must fail")
+	}
+	if err := enc.Key("bad key"); err == nil {
+		t.Error("[Encode] a key with a space must fail")
+	}
+	if err := enc.Key("Name"); err != nil {
+		t.Error(err)
+	}
+	if err := enc.EndEntity(); err == nil {
+		t.Error("[Encode] closing of an entity after a key must fail")
+	}
+}
@@ -0,0 +1,191 @@
+package nanomarkup
+
+import (
+	"bytes"
+)
+
+// formatOptions is the layout of Format.
+type formatOptions struct {
+	tabs  bool
+	width int
+	align bool
+}
+
+// formatLine is a line of the formatted data.
+type formatLine struct {
+	level int
+	// the key of an item which value can be aligned
+	key  []byte
+	text []byte
+	// the line is written as is without the indentation
+	verbatim bool
+}
+
+// formatter converts nano data to the canonical layout line by line.
+type formatter struct {
+	lines  [][]byte
+	index  int
+	stack  []unmarshalType
+	out    []formatLine
+	blank  bool
+	opened bool
+}
+
+func appendFormat(dst, src []byte, o formatOptions) []byte {
+	f := formatter{lines: bytes.Split(src, []byte("\n"))}
+	// skip the new line at the end of the data
+	if n := len(f.lines); n > 1 && len(f.lines[n-1]) == 0 {
+		f.lines = f.lines[:n-1]
+	}
+	f.opened = true
+	for f.index = 0; f.index < len(f.lines); f.index++ {
+		item := bytes.TrimLeft(f.lines[f.index], " \t")
+		if len(bytes.TrimRight(item, " \t\r")) == 0 {
+			// collapse blank lines and skip them at the beginning of a value
+			f.blank = !f.opened
+			continue
+		}
+		if f.comment(item) {
+			continue
+		}
+		val := bytes.TrimRight(item, " \t")
+		if len(val) == 1 && (val[0] == 93 || val[0] == 125) { // ], }
+			// skip blank lines at the end of a value
+			f.blank = false
+			if len(f.stack) > 0 {
+				f.stack = f.stack[:len(f.stack)-1]
+			}
+			f.add(formatLine{level: len(f.stack), text: val})
+			continue
+		}
+		var key []byte
+		if len(f.stack) > 0 && f.stack[len(f.stack)-1] == entity {
+			key, item = splitItem(item)
+			val = bytes.TrimRight(item, " \t")
+		}
+		f.value(key, item, val)
+	}
+	return f.write(dst, o)
+}
+
+// value adds the first line of a value and the content of a multi-line value.
+func (f *formatter) value(key, item, val []byte) {
+	line := formatLine{level: len(f.stack), key: key, text: item}
+	if len(val) == 1 {
+		switch val[0] {
+		case 91: // [
+			f.stack = append(f.stack, array)
+		case 123: // {
+			f.stack = append(f.stack, entity)
+		case 96: // `
+			f.add(formatLine{level: line.level, text: f.item(key, val)})
+			for f.index++; f.index < len(f.lines); f.index++ {
+				l := f.lines[f.index]
+				f.out = append(f.out, formatLine{text: l, verbatim: true})
+				if len(l) == 1 && l[0] == 96 { // `
+					break
+				}
+			}
+			return
+		}
+		if len(f.stack) > line.level {
+			f.add(formatLine{level: line.level, text: f.item(key, val)})
+			f.opened = true
+			return
+		}
+	}
+	f.add(line)
+}
+
+// comment adds a single or multi-line comment.
+// It returns false if the item is not a comment.
+func (f *formatter) comment(item []byte) bool {
+	if len(item) < 2 || item[0] != 47 || (item[1] != 47 && item[1] != 42) { // /, *
+		return false
+	}
+	if item[1] == 47 {
+		f.add(formatLine{level: len(f.stack), text: bytes.TrimRight(item, " \t")})
+		return true
+	}
+	f.add(formatLine{level: len(f.stack), text: item})
+	// check MultilineCommentEndOpCode
+	b := bytes.TrimRight(item[2:], " ")
+	if len(b) > 1 && bytes.HasSuffix(b, []byte("*/")) {
+		return true
+	}
+	// the content of a multi-line comment is written as is
+	for f.index++; f.index < len(f.lines); f.index++ {
+		f.out = append(f.out, formatLine{text: f.lines[f.index], verbatim: true})
+		if bytes.HasSuffix(bytes.TrimRight(f.lines[f.index], " "), []byte("*/")) {
+			break
+		}
+	}
+	return true
+}
+
+// item returns the line of an item which has the key or not.
+func (f *formatter) item(key, val []byte) []byte {
+	if key == nil {
+		return val
+	}
+	line := make([]byte, 0, len(key)+len(val)+1)
+	line = append(line, key...)
+	line = append(line, 32) // space
+	return append(line, val...)
+}
+
+// add adds the line and the pending blank line before it.
+func (f *formatter) add(line formatLine) {
+	if f.blank {
+		f.out = append(f.out, formatLine{verbatim: true})
+		f.blank = false
+	}
+	f.out = append(f.out, line)
+	f.opened = false
+}
+
+// write appends the lines indenting them and aligning the values of keys.
+func (f *formatter) write(dst []byte, o formatOptions) []byte {
+	indent := []byte{9} // tab
+	if !o.tabs {
+		indent = bytes.Repeat([]byte{32}, o.width) // space
+	}
+	for i := 0; i < len(f.out); i++ {
+		line := f.out[i]
+		if line.verbatim {
+			dst = append(dst, line.text...)
+			dst = append(dst, 10) // new line
+			continue
+		}
+		if line.key == nil {
+			dst = append(dst, bytes.Repeat(indent, line.level)...)
+			dst = append(dst, line.text...)
+			dst = append(dst, 10) // new line
+			continue
+		}
+		// get the width of keys of the following items of the same entity
+		width := 0
+		end := i + 1
+		for o.align && end < len(f.out) && f.out[end].key != nil && f.out[end].level == line.level {
+			end++
+		}
+		for _, l := range f.out[i:end] {
+			if len(l.text) > 0 {
+				width = max(width, len(l.key))
+			}
+		}
+		for ; i < end; i++ {
+			line = f.out[i]
+			dst = append(dst, bytes.Repeat(indent, line.level)...)
+			dst = append(dst, line.key...)
+			if len(line.text) > 0 {
+				// items without a value are not aligned
+				dst = append(dst, bytes.Repeat([]byte{32}, width-len(line.key)+1)...) // space
+				dst = append(dst, line.text...)
+			}
+			dst = append(dst, 10) // new line
+		}
+		i--
+	}
+	return dst
+}
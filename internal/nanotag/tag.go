@@ -0,0 +1,73 @@
+// Package nanotag parses the nano tags of struct fields.
+//
+// A tag consists of the key of the field and the options separated by commas,
+// like `nano:"name,omitempty"`. The "-" tag skips the field.
+package nanotag
+
+import (
+	"reflect"
+	"strings"
+)
+
+const (
+	TagName   string = "nano"
+	Ignore    string = "-"
+	OmitEmpty string = "omitempty"
+	Remain    string = "remain"
+	delim     string = ","
+)
+
+// Tag is the parsed nano tag of a struct field.
+type Tag struct {
+	// Name is the key of the field: the name from the tag or the name of the field.
+	Name string
+	// Renamed reports whether the tag sets the name.
+	Renamed bool
+	// Found reports whether the field has a nano tag.
+	Found     bool
+	Ignore    bool
+	OmitEmpty bool
+	// Remain reports whether the field collects the unknown keys of an entity.
+	Remain bool
+}
+
+// Parse parses the nano tag of the field.
+func Parse(f reflect.StructField) Tag {
+	t := Tag{Name: f.Name}
+	tag, ok := f.Tag.Lookup(TagName)
+	if !ok {
+		return t
+	}
+	t.Found = true
+	if tag == Ignore {
+		t.Ignore = true
+		return t
+	} else if tag == OmitEmpty {
+		t.OmitEmpty = true
+		return t
+	}
+	items := strings.Split(tag, delim)
+	name := items[0]
+	if len(items) > 1 {
+		if items[0] == OmitEmpty {
+			name = items[1]
+			t.OmitEmpty = true
+		} else if items[1] == OmitEmpty {
+			t.OmitEmpty = true
+		}
+		for _, item := range items[1:] {
+			if item == Remain {
+				t.Remain = true
+			}
+		}
+	}
+	switch name {
+	case Ignore:
+		t.Ignore = true
+	case OmitEmpty, "":
+	default:
+		t.Name = name
+		t.Renamed = true
+	}
+	return t
+}
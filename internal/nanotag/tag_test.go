@@ -0,0 +1,38 @@
+package nanotag
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	type test struct {
+		Plain     string
+		Renamed   string `nano:"name"`
+		Ignored   string `nano:"-"`
+		Omit      string `nano:"omitempty"`
+		OmitFirst string `nano:"omitempty,first"`
+		OmitLast  string `nano:"last,omitempty"`
+		Dash      string `nano:"-,omitempty"`
+		Extra     string `nano:",remain"`
+		Named     string `nano:"named,omitempty,remain"`
+		Other     string `json:"other"`
+	}
+	want := []Tag{
+		{Name: "Plain"},
+		{Name: "name", Renamed: true, Found: true},
+		{Name: "Ignored", Found: true, Ignore: true},
+		{Name: "Omit", Found: true, OmitEmpty: true},
+		{Name: "first", Renamed: true, Found: true, OmitEmpty: true},
+		{Name: "last", Renamed: true, Found: true, OmitEmpty: true},
+		{Name: "Dash", Found: true, Ignore: true, OmitEmpty: true},
+		{Name: "Extra", Found: true, Remain: true},
+		{Name: "named", Renamed: true, Found: true, OmitEmpty: true, Remain: true},
+		{Name: "Other"},
+	}
+	for i, f := range reflect.VisibleFields(reflect.TypeOf(test{})) {
+		if out := Parse(f); out != want[i] {
+			t.Errorf("[Parse] field: %s; out: %+v; want: %+v", f.Name, out, want[i])
+		}
+	}
+}
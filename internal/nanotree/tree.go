@@ -0,0 +1,256 @@
+// Package nanotree reads nano data into trees of values which keep their comments
+// and writes the trees back. It is shared by the converters and Merge.
+package nanotree
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/nanomarkup/nanomarkup.go/nanocomment"
+	"github.com/nanomarkup/nanomarkup.go/nanodecoder"
+	"github.com/nanomarkup/nanomarkup.go/nanoerror"
+	"github.com/nanomarkup/nanomarkup.go/nanometadata"
+	"github.com/nanomarkup/nanomarkup.go/nanostr"
+)
+
+// Node is a nano value with its comments.
+type Node struct {
+	// Kind is '{' for an entity, '[' for an array and 0 for a scalar or a multi-line value.
+	Kind byte
+	// Keys are the keys of the entity items in the order of Items.
+	Keys  []string
+	Items []*Node
+	Value string
+	// Trailing is the text of the comment after a single-line value.
+	Trailing string
+	// Comments precede the value, End contains the comments before the closing bracket.
+	Comments nanocomment.Comments
+	End      nanocomment.Comments
+}
+
+// Encoder writes nano values, it is implemented by *nanomarkup.Encoder.
+type Encoder interface {
+	Encode(v any, meta *nanometadata.Metadata) error
+	Key(name string) error
+	Comments(comments nanocomment.Comments) error
+	BeginEntity() error
+	EndEntity() error
+	BeginArray() error
+	EndArray() error
+}
+
+// parser reads the values of nano data with their comments.
+type parser struct {
+	d nanodecoder.Decoder
+}
+
+// NewEntity returns an empty entity.
+func NewEntity() *Node {
+	return &Node{Kind: '{'}
+}
+
+// NewArray returns an empty array.
+func NewArray() *Node {
+	return &Node{Kind: '['}
+}
+
+// NewValue returns a scalar.
+func NewValue(value string) *Node {
+	return &Node{Value: value}
+}
+
+// Parse reads the value of the nano data and the comments after it.
+// The value is nil if the data does not contain a value.
+// The data should be validated before, the errors of the syntax are not reported in detail.
+func Parse(data []byte) (*Node, nanocomment.Comments, error) {
+	p := parser{}
+	p.d.InitReader(bytes.NewReader(data))
+	item, comments, ok, err := p.next()
+	if err != nil || !ok {
+		return nil, comments, err
+	}
+	n, err := p.value(item, comments)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, comments, ok, err = p.next(); err != nil {
+		return nil, nil, err
+	} else if ok {
+		return nil, nil, &nanoerror.InvalidEntityError{Context: "Decode", Entity: "", Err: fmt.Errorf("the data contains more than one value")}
+	}
+	return n, comments, nil
+}
+
+// Write writes the comments and the value using the encoder.
+func (n *Node) Write(enc Encoder) error {
+	if len(n.Comments) > 0 {
+		if err := enc.Comments(n.Comments); err != nil {
+			return err
+		}
+	}
+	return n.write(enc)
+}
+
+// write writes the value without its comments.
+func (n *Node) write(enc Encoder) error {
+	if n.Kind == 0 {
+		if n.Trailing != "" {
+			return enc.Encode(n.Value, &nanometadata.Metadata{Trailing: n.Trailing})
+		}
+		return enc.Encode(n.Value, nil)
+	}
+	var err error
+	if n.Kind == '{' {
+		err = enc.BeginEntity()
+	} else {
+		err = enc.BeginArray()
+	}
+	if err != nil {
+		return err
+	}
+	for i, it := range n.Items {
+		if len(it.Comments) > 0 {
+			if err = enc.Comments(it.Comments); err != nil {
+				return err
+			}
+		}
+		if n.Kind == '{' {
+			if err = enc.Key(n.Keys[i]); err != nil {
+				return err
+			}
+		}
+		if err = it.write(enc); err != nil {
+			return err
+		}
+	}
+	if len(n.End) > 0 {
+		if err = enc.Comments(n.End); err != nil {
+			return err
+		}
+	}
+	if n.Kind == '{' {
+		return enc.EndEntity()
+	}
+	return enc.EndArray()
+}
+
+// WriteDocument writes the value and the comments after it, the value can be nil.
+func WriteDocument(enc Encoder, n *Node, footer nanocomment.Comments) error {
+	if n != nil {
+		if err := n.Write(enc); err != nil {
+			return err
+		}
+	}
+	if len(footer) > 0 {
+		return enc.Comments(footer)
+	}
+	return nil
+}
+
+// Get returns the item of the entity by the key, nil if it is not found.
+func (n *Node) Get(key string) *Node {
+	if i := n.Index(key); i >= 0 {
+		return n.Items[i]
+	}
+	return nil
+}
+
+// Index returns the index of the item of the entity by the key, -1 if it is not found.
+func (n *Node) Index(key string) int {
+	for i, k := range n.Keys {
+		if k == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// Add adds the item to the end of the entity or the array, the key of an array item is ignored.
+func (n *Node) Add(key string, item *Node) {
+	if n.Kind == '{' {
+		n.Keys = append(n.Keys, key)
+	}
+	n.Items = append(n.Items, item)
+}
+
+// Remove removes the item of the entity or the array at the index.
+func (n *Node) Remove(i int) {
+	if n.Kind == '{' {
+		n.Keys = append(n.Keys[:i], n.Keys[i+1:]...)
+	}
+	n.Items = append(n.Items[:i], n.Items[i+1:]...)
+}
+
+// next returns the next item and the comments before it skipping empty lines.
+func (p *parser) next() ([]byte, nanocomment.Comments, bool, error) {
+	comments := nanocomment.Comments{}
+	item, ok := p.d.Next()
+	for ; ok; item, ok = p.d.Next() {
+		item = bytes.TrimLeft(item, " \t")
+		if len(item) == 0 {
+			continue
+		}
+		comms, err := nanocomment.Unmarshal(&p.d, item)
+		if err != nil {
+			return nil, comments, false, err
+		} else if len(comms) > 0 {
+			comments.Adds(comms)
+			continue
+		}
+		return item, comments, true, nil
+	}
+	return nil, comments, false, p.d.Err()
+}
+
+// value reads the value which begins with the item.
+func (p *parser) value(item []byte, comments nanocomment.Comments) (*Node, error) {
+	n := &Node{Comments: comments}
+	if val := bytes.TrimRight(item, " \t"); len(val) == 1 && (val[0] == 91 || val[0] == 123) { // [, {
+		n.Kind = val[0]
+		return n, p.items(n)
+	}
+	_, n.Trailing, _ = nanostr.CutComment(item)
+	s, err := nanostr.Unmarshal(&p.d, item)
+	if err != nil {
+		return nil, err
+	}
+	n.Value = string(s)
+	return n, p.d.Err()
+}
+
+// items reads the items of the entity or the array up to the closing bracket.
+func (p *parser) items(n *Node) error {
+	for {
+		item, comments, ok, err := p.next()
+		if err != nil {
+			return err
+		} else if !ok {
+			return &nanoerror.InvalidEntityError{Context: "Decode", Entity: "", Err: fmt.Errorf("'%c' is missing", n.Kind+2)}
+		}
+		if val := bytes.TrimRight(item, " \t"); len(val) == 1 && (val[0] == 93 || val[0] == 125) { // ], }
+			// the closing bracket follows the opening one through one character in ASCII
+			if val[0] != n.Kind+2 {
+				return &nanoerror.InvalidEntityError{Context: "Decode", Entity: string(val), Err: fmt.Errorf("there is nothing to close")}
+			}
+			n.End = comments
+			return nil
+		}
+		key := ""
+		if n.Kind == 123 { // {
+			key, item = splitItem(item)
+		}
+		it, err := p.value(item, comments)
+		if err != nil {
+			return err
+		}
+		n.Add(key, it)
+	}
+}
+
+// splitItem splits the item of an entity to a key and a value.
+func splitItem(item []byte) (string, []byte) {
+	if space := bytes.IndexByte(item, 32); space > 0 { // space
+		return string(item[:space]), bytes.TrimLeft(item[space+1:], " \t")
+	}
+	return string(item), []byte{}
+}
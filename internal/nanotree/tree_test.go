@@ -0,0 +1,64 @@
+package nanotree_test
+
+import (
+	"bytes"
+	"testing"
+
+	nanomarkup "github.com/nanomarkup/nanomarkup.go"
+	"github.com/nanomarkup/nanomarkup.go/internal/nanotree"
+)
+
+const testDoc = "// top\n{\n// the server\nserver {\nhost localhost\nport 8080 // the port\n// end of server\n}\nhosts [\na\n/* b */\nb\n]\ntext `\nline 1\nline 2\n`\n}\n// end\n"
+
+func TestParse(t *testing.T) {
+	n, footer, err := nanotree.Parse([]byte(testDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Kind != '{' || len(n.Items) != 3 || n.Comments.String() != " top" || footer.String() != " end" {
+		t.Fatalf("[Parse] out: %+v; footer: %q", n, footer.String())
+	}
+	server := n.Get("server")
+	if server == nil || server.Comments.String() != " the server" || server.End.String() != " end of server" || server.Get("port").Value != "8080" || server.Get("port").Trailing != " the port" {
+		t.Errorf("[Parse] server: %+v", server)
+	}
+	if hosts := n.Get("hosts"); hosts.Kind != '[' || hosts.Items[1].Value != "b" || hosts.Items[1].Comments.String() != " b " {
+		t.Errorf("[Parse] hosts: %+v", hosts)
+	}
+	if text := n.Get("text"); text.Value != "line 1\nline 2" {
+		t.Errorf("[Parse] text: %q", text.Value)
+	}
+
+	out := bytes.Buffer{}
+	enc := nanomarkup.NewEncoder(&out)
+	if err = nanotree.WriteDocument(enc, n, footer); err != nil {
+		t.Fatal(err)
+	}
+	if err = enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != testDoc {
+		t.Errorf("[WriteDocument] out: %q; want: %q", out.String(), testDoc)
+	}
+
+	if n, footer, err = nanotree.Parse([]byte("\n// only\n")); err != nil || n != nil || footer.String() != " only" {
+		t.Errorf("[Parse] out: %+v; footer: %q; error: %v", n, footer.String(), err)
+	}
+	for _, item := range []string{"a\nb\n", "{\na 1\n", "[\n}\n"} {
+		if _, _, err = nanotree.Parse([]byte(item)); err == nil {
+			t.Errorf("[Parse] in: %q; want: error", item)
+		}
+	}
+}
+
+func TestEdit(t *testing.T) {
+	n := nanotree.NewEntity()
+	n.Add("a", nanotree.NewValue("1"))
+	n.Add("b", nanotree.NewArray())
+	n.Get("b").Add("ignored", nanotree.NewValue("2"))
+	n.Add("c", nanotree.NewValue("3"))
+	n.Remove(n.Index("a"))
+	if n.Index("a") != -1 || n.Index("c") != 1 || len(n.Get("b").Keys) != 0 || n.Get("b").Items[0].Value != "2" {
+		t.Errorf("[Edit] out: %+v", n)
+	}
+}
@@ -3,6 +3,7 @@ package nanomarkup
 import (
 	"encoding"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -83,6 +84,12 @@ func TestStringMarshal(t *testing.T) {
 		{v: "go ", want: "go "},
 		{v: " go ", want: "go "},
 		{v: "hello world", want: "hello world"},
+		{v: "[a-z]+", want: "`\n[a-z]+\n`\n"},
+		{v: "{}", want: "`\n{}\n`\n"},
+		{v: "// text", want: "`\n// text\n`\n"},
+		{v: "`", want: "`\n\\`\n`\n"},
+		{v: "`x", want: "`\n`x\n`\n"},
+		{v: "a\n`\n\\`", want: "`\na\n\\`\n\\\\`\n`\n"},
 	}
 
 	for _, item := range testCases {
@@ -424,7 +431,7 @@ func TestCustomMarshal(t *testing.T) {
 	in := struct {
 		Today time.Time
 	}{time.Date(1983, 12, 20, 19, 30, 0, 0, time.Local)}
-	want := "{\nToday {\n1983-12-20T19:30:00-05:00\n}\n}\n"
+	want := fmt.Sprintf("{\nToday %s\n}\n", in.Today.Format(time.RFC3339Nano))
 	out, err := Marshal(in, nil)
 	if s := checkMarshal(in, out, want, err); s != "" {
 		t.Error(s)
@@ -433,9 +440,242 @@ func TestCustomMarshal(t *testing.T) {
 	in2 := struct {
 		Today customTime
 	}{customTime{time.Date(1983, 12, 20, 19, 30, 0, 0, time.Local)}}
-	want = fmt.Sprintf("{\nToday {\n%s\n}\n}\n", in2.Today.Format(in2.Today.getTimeFormat()))
+	want = fmt.Sprintf("{\nToday %s\n}\n", in2.Today.Format(in2.Today.getTimeFormat()))
 	out, err = Marshal(in2, nil)
 	if s := checkMarshal(in, out, want, err); s != "" {
 		t.Error(s)
 	}
 }
+
+func TestCustomKindMarshal(t *testing.T) {
+	testCases := []struct {
+		v    any
+		want string
+	}{
+		{v: levelWarning, want: "warning"},
+		{v: []customLevel{levelInfo, levelWarning}, want: "[\ninfo\nwarning\n]\n"},
+		{v: map[customLevel]int{levelInfo: 1}, want: "{\ninfo 1\n}\n"},
+		{v: customTags{"b": true, "a": true}, want: "[\na\nb\n]\n"},
+		{v: customPoint{1, 2}, want: "1:2"},
+		{v: struct{ Point customPoint }{customPoint{3, 4}}, want: "{\nPoint 3:4\n}\n"},
+		{v: &struct{ Counter customCounter }{customCounter(5)}, want: "{\nCounter #5\n}\n"},
+	}
+
+	for _, item := range testCases {
+		out, err := Marshal(item.v, nil)
+		if s := checkMarshal(item.v, out, item.want, err); s != "" {
+			t.Error(s)
+		}
+	}
+}
+
+func TestCustomAppendMarshal(t *testing.T) {
+	testCases := []struct {
+		v    any
+		want string
+	}{
+		{v: customID(255), want: "id-ff"},
+		{v: []customID{1, 16}, want: "[\nid-1\nid-10\n]\n"},
+		{v: struct{ ID customID }{42}, want: "{\nID id-2a\n}\n"},
+	}
+
+	for _, item := range testCases {
+		out, err := Marshal(item.v, nil)
+		if s := checkMarshal(item.v, out, item.want, err); s != "" {
+			t.Error(s)
+		}
+	}
+
+	// AppendNano writes to the output without a slice per value
+	ids := make([]customID, 100)
+	hexes := make([]customHex, len(ids))
+	for i := range ids {
+		ids[i] = customID(1000 + i)
+		hexes[i] = customHex(1000 + i)
+	}
+	appended := testing.AllocsPerRun(10, func() { Marshal(ids, nil) })
+	marshaled := testing.AllocsPerRun(10, func() { Marshal(hexes, nil) })
+	if appended >= float64(len(ids)) || marshaled-appended < float64(len(ids)) {
+		t.Errorf("[Marshal] allocations of AppendNano: %v; MarshalNano: %v", appended, marshaled)
+	}
+}
+
+func TestMetaNestedMarshal(t *testing.T) {
+	type server struct {
+		Host  string
+		Ports []int
+	}
+	in := struct {
+		Server  server
+		Servers []server
+		Labels  map[string]string
+	}{
+		server{"localhost", []int{80, 443}},
+		[]server{{"a", nil}, {"b", nil}},
+		map[string]string{"env": "prod"},
+	}
+	ports := nanometadata.CreateMetadata(" Ports", false)
+	ports.AddItem(1, nanometadata.CreateMetadata(" TLS", false))
+	srv := nanometadata.CreateMetadata(" Main server", false)
+	srv.AddField("Host", nanometadata.CreateMetadata(" Host name", false))
+	srv.AddField("Ports", ports)
+	second := nanometadata.CreateMetadata(" Second server", false)
+	second.AddField("Host", nanometadata.CreateMetadata(" Backup", false))
+	srvs := &nanometadata.Metadata{}
+	srvs.AddItem(1, second)
+	labels := &nanometadata.Metadata{}
+	labels.AddField("env", nanometadata.CreateMetadata(" Environment", false))
+	meta := &nanometadata.Metadata{}
+	meta.AddField("Server", srv)
+	meta.AddField("Servers", srvs)
+	meta.AddField("Labels", labels)
+	want := `{
+// Main server
+Server {
+// Host name
+Host localhost
+// Ports
+Ports [
+80
+// TLS
+443
+]
+}
+Servers [
+{
+Host a
+}
+// Second server
+{
+// Backup
+Host b
+}
+]
+Labels {
+// Environment
+env prod
+}
+}
+`
+	out, err := Marshal(in, meta)
+	if s := checkMarshal(in, out, want, err); s != "" {
+		t.Error(s)
+	}
+
+	// the metadata of the element is used by the items without their own metadata
+	elem := &nanometadata.Metadata{}
+	elem.AddField("Host", nanometadata.CreateMetadata(" Host name", false))
+	srvs.SetElem(elem)
+	labels = &nanometadata.Metadata{}
+	labels.SetElem(&nanometadata.Metadata{Trailing: " a label"})
+	meta.AddField("Labels", labels)
+	want = strings.Replace(want, "Servers [\n{\nHost a", "Servers [\n{\n// Host name\nHost a", 1)
+	want = strings.Replace(want, "// Environment\nenv prod", "env prod // a label", 1)
+	out, err = Marshal(in, meta)
+	if s := checkMarshal(in, out, want, err); s != "" {
+		t.Error(s)
+	}
+}
+
+func TestMetaTrailingMarshal(t *testing.T) {
+	in := struct {
+		Port  int
+		URL   string
+		Hosts []string
+		Text  string
+	}{8080, "a // b", []string{"a", "b"}, "line 1\nline 2"}
+	hosts := nanometadata.Metadata{Trailing: " ignored"}
+	hosts.AddItem(1, &nanometadata.Metadata{Trailing: " the second host"})
+	meta := &nanometadata.Metadata{}
+	meta.AddField("Port", &nanometadata.Metadata{Trailing: " default HTTP port"})
+	meta.AddField("URL", &nanometadata.Metadata{Trailing: "escaped"})
+	meta.AddField("Hosts", &hosts)
+	meta.AddField("Text", &nanometadata.Metadata{Trailing: " ignored"})
+	want := "{\nPort 8080 // default HTTP port\nURL a \\// b //escaped\nHosts [\na\nb // the second host\n]\nText `\nline 1\nline 2\n`\n}\n"
+	out, err := Marshal(in, meta)
+	if s := checkMarshal(in, out, want, err); s != "" {
+		t.Error(s)
+	}
+	// a value which contains the escaped delimiter is written as a multi-line value
+	want = "`\na \\// b\n`\n"
+	out, err = Marshal("a \\// b", &nanometadata.Metadata{Trailing: " ignored"})
+	if s := checkMarshal(in, out, want, err); s != "" {
+		t.Error(s)
+	}
+}
+
+type commentedServer struct {
+	Host string `comment:"Host name"`
+	Port int    `comment:"Listening port"`
+	TLS  bool
+}
+
+func (s *commentedServer) NanoComments() map[string]string {
+	return map[string]string{"Port": "Listening port\nThe default port is 8080", "TLS": "Enables TLS"}
+}
+
+func TestCommentTagMarshal(t *testing.T) {
+	in := struct {
+		Name   string `comment:"Service name" nano:"name"`
+		Server *commentedServer
+		Debug  bool `comment:"Debug */ mode"`
+	}{"api", &commentedServer{"localhost", 8080, true}, true}
+	meta := &nanometadata.Metadata{}
+	meta.AddField("Debug", nanometadata.CreateMetadata(" Overridden", false))
+	testCases := []struct {
+		opts []MarshalOption
+		want string
+	}{
+		{
+			want: "{\n// Service name\nname api\nServer {\n// Host name\nHost localhost\n// Listening port\n// The default port is 8080\nPort 8080\n// Enables TLS\nTLS true\n}\n// Overridden\nDebug true\n}\n",
+		},
+		{
+			opts: []MarshalOption{WithCommentStyle(BlockComments)},
+			want: "{\n/* Service name */\nname api\nServer {\n/* Host name */\nHost localhost\n/* Listening port\nThe default port is 8080 */\nPort 8080\n/* Enables TLS */\nTLS true\n}\n// Overridden\nDebug true\n}\n",
+		},
+	}
+	for _, tc := range testCases {
+		out, err := Marshal(in, meta, tc.opts...)
+		if s := checkMarshal(in, out, tc.want, err); s != "" {
+			t.Error(s)
+		}
+	}
+	// the end of a block comment cannot be written in a block comment
+	want := "{\n/* Service name */\nname api\n// Debug */ mode\nDebug true\n}\n"
+	in.Server = nil
+	out, err := Marshal(in, nil, WithCommentStyle(BlockComments))
+	if s := checkMarshal(in, out, want, err); s != "" {
+		t.Error(s)
+	}
+}
+
+func TestRemainMarshal(t *testing.T) {
+	in := struct {
+		Name  string
+		Extra map[string]any `nano:",remain"`
+	}{"api", map[string]any{"b": []int{1}, "a": "x"}}
+	want := "{\nName api\na x\nb [\n1\n]\n}\n"
+	out, err := Marshal(in, nil)
+	if s := checkMarshal(in, out, want, err); s != "" {
+		t.Error(s)
+	}
+	// an empty raw message is written as an empty value
+	raw := struct {
+		Name  string
+		Extra map[string]RawMessage `nano:",remain"`
+	}{"api", map[string]RawMessage{"a": nil, "b": {}}}
+	want = "{\nName api\na \nb \n}\n"
+	out, err = Marshal(raw, nil)
+	if s := checkMarshal(raw, out, want, err); s != "" {
+		t.Error(s)
+	}
+	if out, err = Marshal(RawMessage(nil), nil); err != nil || len(out) != 0 {
+		t.Errorf("[Marshal] out: %q; error: %v", out, err)
+	}
+	invalid := struct {
+		Extra []string `nano:",remain"`
+	}{[]string{"a"}}
+	if _, err := Marshal(invalid, nil); err == nil {
+		t.Errorf("[Marshal] want: error")
+	}
+}
@@ -0,0 +1,124 @@
+package nanomarkup
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/nanomarkup/nanomarkup.go/internal/nanotree"
+	"github.com/nanomarkup/nanomarkup.go/nanocomment"
+)
+
+// mergeOptions is the configuration of Merge.
+type mergeOptions struct {
+	arrays ArrayStrategy
+	key    string
+}
+
+// readMergeDocument reads the value of the data and the comments after it.
+// The value is nil if the data is empty.
+func readMergeDocument(data []byte) (*nanotree.Node, nanocomment.Comments, error) {
+	if errs := validate(data); len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+	return nanotree.Parse(data)
+}
+
+// writeMergeDocument writes the value and the comments after it.
+func writeMergeDocument(n *nanotree.Node, footer nanocomment.Comments) ([]byte, error) {
+	out := bytes.Buffer{}
+	enc := NewEncoder(&out)
+	if err := nanotree.WriteDocument(enc, n, footer); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// isDelete reports whether the value is the deletion marker.
+func isDelete(n *nanotree.Node) bool {
+	return n.Kind == 0 && n.Value == DeleteMarker
+}
+
+// clean removes the items of entities which are marked to delete.
+func clean(n *nanotree.Node) *nanotree.Node {
+	for i := 0; i < len(n.Items); i++ {
+		if n.Kind == '{' && isDelete(n.Items[i]) {
+			n.Remove(i)
+			i--
+		} else {
+			clean(n.Items[i])
+		}
+	}
+	return n
+}
+
+// mergeValues merges the overlay into the base value.
+// The comments of the base value are kept, the comments of the overlay are used for new items only.
+func mergeValues(base, overlay *nanotree.Node, o *mergeOptions) *nanotree.Node {
+	res := overlay
+	switch {
+	case base.Kind == '{' && overlay.Kind == '{':
+		for i, it := range overlay.Items {
+			key := overlay.Keys[i]
+			j := base.Index(key)
+			if isDelete(it) {
+				if j >= 0 {
+					base.Remove(j)
+				}
+			} else if j >= 0 {
+				base.Items[j] = mergeValues(base.Items[j], it, o)
+			} else {
+				base.Add(key, clean(it))
+			}
+		}
+		res = base
+	case base.Kind == '[' && overlay.Kind == '[' && o.arrays == AppendArrays:
+		for _, it := range overlay.Items {
+			base.Add("", clean(it))
+		}
+		res = base
+	case base.Kind == '[' && overlay.Kind == '[' && o.arrays == MergeArraysByKey:
+		for _, it := range overlay.Items {
+			if j := find(base, it, o.key); j >= 0 {
+				base.Items[j] = mergeValues(base.Items[j], it, o)
+			} else {
+				base.Add("", clean(it))
+			}
+		}
+		res = base
+	default:
+		res = clean(overlay)
+		if len(base.End) > 0 {
+			res.End = base.End
+		}
+		if base.Trailing != "" {
+			res.Trailing = base.Trailing
+		}
+	}
+	if len(base.Comments) > 0 {
+		res.Comments = base.Comments
+	}
+	return res
+}
+
+// find returns the index of the entity of the array which has the same value of the key, -1 if it is not found.
+func find(n, item *nanotree.Node, key string) int {
+	if item.Kind != '{' {
+		return -1
+	}
+	i := item.Index(key)
+	if i < 0 || item.Items[i].Kind != 0 {
+		return -1
+	}
+	for j, it := range n.Items {
+		if it.Kind != '{' {
+			continue
+		}
+		if k := it.Index(key); k >= 0 && it.Items[k].Kind == 0 && it.Items[k].Value == item.Items[i].Value {
+			return j
+		}
+	}
+	return -1
+}
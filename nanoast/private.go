@@ -0,0 +1,420 @@
+package nanoast
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	nanomarkup "github.com/nanomarkup/nanomarkup.go"
+	"github.com/nanomarkup/nanomarkup.go/nanodecoder"
+	"github.com/nanomarkup/nanomarkup.go/nanoerror"
+	"github.com/nanomarkup/nanomarkup.go/nanostr"
+)
+
+// base is the layout of a node as written.
+type base struct {
+	pos Position
+	// the indentation of the line and the spaces after a delimiter
+	indent string
+	trail  string
+	parsed bool
+}
+
+// scalarParsed is the value and the comment of a scalar as they are parsed.
+type scalarParsed struct {
+	value   string
+	comment string
+}
+
+// closing is the layout of the closing bracket of an entity or an array.
+type closing struct {
+	indent string
+	trail  string
+	parsed bool
+}
+
+// parser reads the nodes of nano data line by line.
+type parser struct {
+	lines []string
+	index int
+}
+
+// printer writes the nodes keeping the layout of the parsed nodes.
+type printer struct {
+	out []byte
+	// the indentation of one level which is used for nested items of new values
+	unit string
+}
+
+func (b *base) node() *base {
+	return b
+}
+
+func parse(data []byte) (*Document, error) {
+	if errs := nanomarkup.Validate(data); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	doc := &Document{}
+	p := parser{lines: strings.Split(string(data), "\n")}
+	if n := len(p.lines); p.lines[n-1] == "" {
+		p.lines = p.lines[:n-1]
+	} else {
+		doc.noEOL = true
+	}
+	for ; p.index < len(p.lines); p.index++ {
+		if c := p.comment(); c != nil {
+			doc.Nodes = append(doc.Nodes, c)
+			continue
+		}
+		line := p.lines[p.index]
+		item := strings.TrimLeft(line, " \t")
+		n, err := p.value(line[:len(line)-len(item)], item)
+		if err != nil {
+			return nil, err
+		}
+		doc.Nodes = append(doc.Nodes, n)
+	}
+	return doc, nil
+}
+
+// comment returns the comment or the blank line at the current line, nil if it is not a comment.
+func (p *parser) comment() *Comment {
+	line := p.lines[p.index]
+	item := strings.TrimLeft(line, " \t")
+	indent := line[:len(line)-len(item)]
+	c := &Comment{base: base{pos: Position{p.index + 1, len(indent) + 1}, indent: indent, parsed: true}, Text: item}
+	if len(item) == 0 || strings.HasPrefix(item, "//") {
+		return c
+	} else if !strings.HasPrefix(item, "/*") {
+		return nil
+	}
+	// check MultilineCommentEndOpCode
+	if b := strings.TrimRight(item[2:], " "); len(b) > 1 && strings.HasSuffix(b, "*/") {
+		return c
+	}
+	start := p.index
+	for p.index++; p.index < len(p.lines); p.index++ {
+		if strings.HasSuffix(strings.TrimRight(p.lines[p.index], " "), "*/") {
+			break
+		}
+	}
+	c.Text = strings.Join(append([]string{item}, p.lines[start+1:p.index+1]...), "\n")
+	return c
+}
+
+// value reads the value which begins with the item, the indent is the indentation of the value.
+func (p *parser) value(indent, item string) (Node, error) {
+	line := p.lines[p.index]
+	b := base{pos: Position{p.index + 1, len(line) - len(item) + 1}, indent: indent, parsed: true}
+	val := strings.TrimRight(item, " \t")
+	if len(val) == 1 {
+		b.trail = item[1:]
+		switch val[0] {
+		case 91: // [
+			a := &Array{base: b}
+			return a, p.items(&a.Items, &a.end, false)
+		case 123: // {
+			e := &Entity{base: b}
+			return e, p.items(&e.Items, &e.end, true)
+		case 96: // `
+			start := p.index
+			for p.index++; p.index < len(p.lines); p.index++ {
+				if p.lines[p.index] == "`" {
+					break
+				}
+			}
+			m := &MultiLine{base: b, lines: p.lines[start+1 : p.index]}
+			// decode the content in the same way as the decoder
+			d := nanodecoder.Decoder{}
+			lines := make([][]byte, 0, p.index-start)
+			for _, l := range p.lines[start+1 : p.index+1] {
+				lines = append(lines, []byte(l))
+			}
+			d.Init(lines)
+			s, err := nanostr.Unmarshal(&d, []byte(item))
+			if err != nil {
+				return nil, err
+			}
+			m.Value = string(s)
+			m.parsed = m.Value
+			return m, nil
+		}
+	}
+	text, comment, ok := nanostr.CutComment([]byte(item))
+	s := &Scalar{base: base{pos: b.pos, indent: indent, parsed: true}, Comment: comment, text: string(text)}
+	s.Value = string(nanostr.Value(text))
+	s.parsed = scalarParsed{s.Value, s.Comment}
+	if ok {
+		s.trail = item[len(text):]
+	}
+	return s, nil
+}
+
+// items reads the items of an entity or an array up to the closing bracket.
+func (p *parser) items(items *[]Node, end *closing, entity bool) error {
+	for p.index++; p.index < len(p.lines); p.index++ {
+		if c := p.comment(); c != nil {
+			*items = append(*items, c)
+			continue
+		}
+		line := p.lines[p.index]
+		item := strings.TrimLeft(line, " \t")
+		indent := line[:len(line)-len(item)]
+		if val := strings.TrimRight(item, " \t"); val == "}" || val == "]" {
+			*end = closing{indent: indent, trail: item[1:], parsed: true}
+			return nil
+		}
+		if !entity {
+			n, err := p.value(indent, item)
+			if err != nil {
+				return err
+			}
+			*items = append(*items, n)
+			continue
+		}
+		pair := &Pair{base: base{pos: Position{p.index + 1, len(indent) + 1}, indent: indent, parsed: true}, Key: item}
+		value := ""
+		if space := strings.IndexByte(item, 32); space > 0 { // space
+			value = strings.TrimLeft(item[space+1:], " \t")
+			pair.Key = item[:space]
+			pair.sep = item[space : len(item)-len(value)]
+		}
+		n, err := p.value("", value)
+		if err != nil {
+			return err
+		}
+		pair.Value = n
+		*items = append(*items, pair)
+	}
+	// the data is validated, so it cannot happen
+	return &nanoerror.InvalidEntityError{Context: "Parse", Entity: "", Err: fmt.Errorf("the closing bracket is missing")}
+}
+
+func (p *printer) document(doc *Document) {
+	p.unit = "\t"
+	for _, n := range doc.Nodes {
+		if unit, ok := indentUnit(n, ""); ok {
+			p.unit = unit
+			break
+		}
+	}
+	for _, n := range doc.Nodes {
+		p.node(n, "")
+	}
+	if doc.noEOL && len(p.out) > 0 {
+		p.out = p.out[:len(p.out)-1]
+	}
+}
+
+// node writes the node, the indent is used if the node is not parsed.
+func (p *printer) node(n Node, indent string) {
+	b := n.node()
+	if b.parsed {
+		indent = b.indent
+	}
+	switch n := n.(type) {
+	case *Comment:
+		p.out = append(p.out, indent...)
+		p.out = append(p.out, n.Text...)
+		p.out = append(p.out, '\n')
+	case *Pair:
+		head := make([]byte, 0, len(indent)+len(n.Key)+len(n.sep))
+		head = append(head, indent...)
+		head = append(head, n.Key...)
+		sep := n.sep
+		if !b.parsed || (sep == "" && !isEmpty(n.Value)) {
+			sep = " "
+		}
+		if !isEmpty(n.Value) || n.Value.node().parsed {
+			head = append(head, sep...)
+		}
+		p.value(n.Value, indent, head)
+	default:
+		p.value(n, indent, []byte(indent))
+	}
+}
+
+// value writes the value after the head, the indent is the indentation of the line.
+func (p *printer) value(n Node, indent string, head []byte) {
+	p.out = append(p.out, head...)
+	b := n.node()
+	switch n := n.(type) {
+	case *Scalar:
+		if needsMultiLine(n.Value) {
+			p.out = nanostr.AppendMultiline(p.out, n.Value)
+			return
+		}
+		if b.parsed && n.Value == n.parsed.value {
+			p.out = append(p.out, n.text...)
+		} else {
+			p.out = nanostr.Append(p.out, n.Value)
+		}
+		if b.parsed && n.Comment == n.parsed.comment {
+			p.out = append(p.out, b.trail...)
+		} else if n.Comment != "" {
+			if last := p.out[len(p.out)-1]; last != ' ' {
+				p.out = append(p.out, ' ')
+			}
+			p.out = append(p.out, "//"...)
+			p.out = append(p.out, n.Comment...)
+		}
+		p.out = append(p.out, '\n')
+	case *MultiLine:
+		p.out = append(p.out, '`')
+		p.out = append(p.out, b.trail...)
+		p.out = append(p.out, '\n')
+		if b.parsed && n.Value == n.parsed {
+			for _, l := range n.lines {
+				p.out = append(p.out, l...)
+				p.out = append(p.out, '\n')
+			}
+		} else {
+			// write the escaped content without the backticks
+			m := nanostr.AppendMultiline(nil, n.Value)
+			p.out = append(p.out, m[2:len(m)-2]...)
+		}
+		p.out = append(p.out, "`\n"...)
+	case *Entity:
+		p.collection('{', n.Items, b, &n.end, indent)
+	case *Array:
+		p.collection('[', n.Items, b, &n.end, indent)
+	}
+}
+
+// collection writes the items of an entity or an array and the closing bracket.
+func (p *printer) collection(delim byte, items []Node, b *base, end *closing, indent string) {
+	p.out = append(p.out, delim)
+	p.out = append(p.out, b.trail...)
+	p.out = append(p.out, '\n')
+	child := indent + p.unit
+	for _, it := range items {
+		// use the indentation of the parsed items for new ones
+		if isIndented(it) {
+			child = it.node().indent
+			break
+		}
+	}
+	for _, it := range items {
+		p.node(it, child)
+	}
+	if end.parsed {
+		indent = end.indent
+	}
+	p.out = append(p.out, indent...)
+	p.out = append(p.out, delim+2) // ], }
+	p.out = append(p.out, end.trail...)
+	p.out = append(p.out, '\n')
+}
+
+// indentUnit returns the indentation of one level of the first nested item of the value
+// which is indented more than the line of the value.
+func indentUnit(n Node, indent string) (string, bool) {
+	if n.node().parsed {
+		indent = n.node().indent
+	}
+	var items []Node
+	switch n := n.(type) {
+	case *Pair:
+		return indentUnit(n.Value, indent)
+	case *Entity:
+		items = n.Items
+	case *Array:
+		items = n.Items
+	}
+	for _, it := range items {
+		if isIndented(it) && len(it.node().indent) > len(indent) && strings.HasPrefix(it.node().indent, indent) {
+			return it.node().indent[len(indent):], true
+		}
+	}
+	for _, it := range items {
+		if unit, ok := indentUnit(it, indent); ok {
+			return unit, true
+		}
+	}
+	return "", false
+}
+
+// isIndented reports whether the node is parsed and its indentation is known.
+func isIndented(n Node) bool {
+	c, ok := n.(*Comment)
+	return n.node().parsed && (!ok || c.Text != "")
+}
+
+// isValue reports whether the node is a value.
+func isValue(n Node) bool {
+	switch n.(type) {
+	case *Entity, *Array, *Scalar, *MultiLine:
+		return true
+	}
+	return false
+}
+
+// isEmpty reports whether the value is an empty scalar.
+func isEmpty(n Node) bool {
+	s, ok := n.(*Scalar)
+	return ok && s.Value == ""
+}
+
+// needsMultiLine reports whether the value cannot be written as a scalar.
+func needsMultiLine(value string) bool {
+	if len(value) > 0 && (value[0] == 32 || value[0] == 9) { // space, tab
+		return true
+	}
+	return bytes.HasPrefix(nanostr.Append(nil, value), []byte("`"))
+}
+
+// rename changes the key of the pair, the context is the name of the calling method.
+func (p *Pair) rename(context, key string) error {
+	if key == "" || strings.ContainsAny(key, " \t\r\n") || strings.HasPrefix(key, "//") || strings.HasPrefix(key, "/*") || key == "}" || key == "]" {
+		return &nanoerror.InvalidArgumentError{Context: context, Err: fmt.Errorf("invalid key: %q", key)}
+	}
+	p.Key = key
+	return nil
+}
+
+// setValue replaces the value of the pair, the context is the name of the calling method.
+func (p *Pair) setValue(context string, v Node) error {
+	if !isValue(v) {
+		return invalidNode(context, v)
+	}
+	keepComment(p.Value, v)
+	p.Value = v
+	return nil
+}
+
+// keepComment copies the trailing comment of a replaced scalar to the new scalar without a comment.
+func keepComment(old, v Node) {
+	if old, ok := old.(*Scalar); ok {
+		if s, ok := v.(*Scalar); ok && s.Comment == "" {
+			s.Comment = old.Comment
+		}
+	}
+}
+
+func invalidNode(context string, n Node) error {
+	return &nanoerror.InvalidArgumentError{Context: context, Err: fmt.Errorf("invalid node: %T", n)}
+}
+
+func indexError(context string, i int) error {
+	return &nanoerror.InvalidArgumentError{Context: context, Err: fmt.Errorf("index %d is out of range", i)}
+}
+
+// insert inserts the node at the index.
+func insert(items []Node, i int, n Node) ([]Node, error) {
+	if i < 0 || i > len(items) {
+		return nil, indexError("Insert", i)
+	}
+	items = append(items, nil)
+	copy(items[i+1:], items[i:])
+	items[i] = n
+	return items, nil
+}
+
+// removeAt removes the node at the index.
+func removeAt(items []Node, i int) ([]Node, error) {
+	if i < 0 || i >= len(items) {
+		return nil, indexError("RemoveAt", i)
+	}
+	return append(items[:i], items[i+1:]...), nil
+}
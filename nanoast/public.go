@@ -0,0 +1,288 @@
+// Package nanoast parses nano documents into syntax trees which keep everything as written:
+// comments, blank lines, indentation, multi-line values and the original text of scalars.
+//
+// The printer reproduces an unmodified tree byte for byte. The nodes which are added
+// or changed are written using the indentation of their neighbours.
+package nanoast
+
+import (
+	"strings"
+)
+
+// Position is a location in the source, Line and Column start from 1.
+// The position of a node which is not parsed is zero.
+type Position struct {
+	Line   int
+	Column int
+}
+
+// Node is a node of a syntax tree: *Entity, *Array, *Pair, *Scalar, *MultiLine or *Comment.
+type Node interface {
+	Pos() Position
+	node() *base
+}
+
+// Document is a parsed nano document, Nodes contains comments and at most one value.
+type Document struct {
+	Nodes []Node
+	// the data does not end with a new line
+	noEOL bool
+}
+
+// Entity is an entity, Items contains pairs and comments.
+type Entity struct {
+	base
+	Items []Node
+	end   closing
+}
+
+// Array is an array, Items contains values and comments.
+type Array struct {
+	base
+	Items []Node
+	end   closing
+}
+
+// Pair is an item of an entity.
+type Pair struct {
+	base
+	Key   string
+	Value Node
+	// the spaces between the key and the value
+	sep string
+}
+
+// Scalar is a single-line value, Value is the decoded text including trailing spaces.
+// Comment is the text of a trailing comment after "//", it is empty if there is no comment.
+type Scalar struct {
+	base
+	Value   string
+	Comment string
+	text    string
+	parsed  scalarParsed
+}
+
+// MultiLine is a multi-line value.
+type MultiLine struct {
+	base
+	Value string
+	// the source lines of the content which are written if the value is not changed
+	lines  []string
+	parsed string
+}
+
+// Comment is a single-line or multi-line comment or a blank line.
+// Text is the comment as written like "// text" or "/* text */", it is empty for a blank line.
+type Comment struct {
+	base
+	Text string
+}
+
+// Parse parses the nano-encoded data.
+func Parse(data []byte) (*Document, error) {
+	return parse(data)
+}
+
+// Print returns the nano encoding of the document.
+func Print(doc *Document) []byte {
+	p := printer{}
+	p.document(doc)
+	return p.out
+}
+
+// Value returns the value of the document, nil if the document does not contain a value.
+func (d *Document) Value() Node {
+	for _, n := range d.Nodes {
+		if _, ok := n.(*Comment); !ok {
+			return n
+		}
+	}
+	return nil
+}
+
+// SetValue replaces the value of the document or adds it after the comments.
+func (d *Document) SetValue(v Node) error {
+	if !isValue(v) {
+		return invalidNode("SetValue", v)
+	}
+	for i, n := range d.Nodes {
+		if _, ok := n.(*Comment); !ok {
+			d.Nodes[i] = v
+			return nil
+		}
+	}
+	d.Nodes = append(d.Nodes, v)
+	return nil
+}
+
+// NewEntity returns an empty entity.
+func NewEntity() *Entity {
+	return &Entity{}
+}
+
+// NewArray returns an empty array.
+func NewArray() *Array {
+	return &Array{}
+}
+
+// NewValue returns a scalar or a multi-line value if the value cannot be written on one line.
+func NewValue(value string) Node {
+	if needsMultiLine(value) {
+		return &MultiLine{Value: value}
+	}
+	return &Scalar{Value: value}
+}
+
+// NewPair returns an item of an entity.
+func NewPair(key string, value Node) (*Pair, error) {
+	p := &Pair{}
+	if err := p.rename("NewPair", key); err != nil {
+		return nil, err
+	}
+	if err := p.setValue("NewPair", value); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// NewComment returns a single-line comment or a multi-line comment if the text contains new lines.
+func NewComment(text string) *Comment {
+	if strings.Contains(text, "\n") {
+		return &Comment{Text: "/*" + text + "*/"}
+	}
+	return &Comment{Text: "//" + text}
+}
+
+// Pos returns the position of the node in the source.
+func (b *base) Pos() Position {
+	return b.pos
+}
+
+// Get returns the first pair of the entity which has the key, nil if it is not found.
+func (e *Entity) Get(key string) *Pair {
+	for _, n := range e.Items {
+		if p, ok := n.(*Pair); ok && p.Key == key {
+			return p
+		}
+	}
+	return nil
+}
+
+// Set replaces the value of the first pair which has the key or adds a new pair to the end of the entity.
+// The trailing comment of a replaced scalar is kept if the new scalar has no comment.
+func (e *Entity) Set(key string, value Node) (*Pair, error) {
+	if p := e.Get(key); p != nil {
+		return p, p.setValue("Set", value)
+	}
+	p := &Pair{}
+	if err := p.rename("Set", key); err != nil {
+		return nil, err
+	}
+	if err := p.setValue("Set", value); err != nil {
+		return nil, err
+	}
+	e.Items = append(e.Items, p)
+	return p, nil
+}
+
+// Insert inserts a pair or a comment at the index of Items.
+func (e *Entity) Insert(i int, n Node) error {
+	switch n.(type) {
+	case *Pair, *Comment:
+	default:
+		return invalidNode("Insert", n)
+	}
+	items, err := insert(e.Items, i, n)
+	if err != nil {
+		return err
+	}
+	e.Items = items
+	return nil
+}
+
+// Remove removes all pairs of the entity which have the key and reports whether a pair is removed.
+func (e *Entity) Remove(key string) bool {
+	removed := false
+	for i := 0; i < len(e.Items); i++ {
+		if p, ok := e.Items[i].(*Pair); ok && p.Key == key {
+			e.Items = append(e.Items[:i], e.Items[i+1:]...)
+			removed = true
+			i--
+		}
+	}
+	return removed
+}
+
+// RemoveAt removes the item at the index of Items.
+func (e *Entity) RemoveAt(i int) error {
+	items, err := removeAt(e.Items, i)
+	if err != nil {
+		return err
+	}
+	e.Items = items
+	return nil
+}
+
+// Values returns the values of the array without comments.
+func (a *Array) Values() []Node {
+	res := []Node{}
+	for _, n := range a.Items {
+		if _, ok := n.(*Comment); !ok {
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+// Append adds a value or a comment to the end of the array.
+func (a *Array) Append(n Node) error {
+	return a.Insert(len(a.Items), n)
+}
+
+// Insert inserts a value or a comment at the index of Items.
+func (a *Array) Insert(i int, n Node) error {
+	if _, ok := n.(*Comment); !ok && !isValue(n) {
+		return invalidNode("Insert", n)
+	}
+	items, err := insert(a.Items, i, n)
+	if err != nil {
+		return err
+	}
+	a.Items = items
+	return nil
+}
+
+// SetAt replaces the item at the index of Items by a value or a comment.
+// The trailing comment of a replaced scalar is kept if the new scalar has no comment.
+func (a *Array) SetAt(i int, n Node) error {
+	if _, ok := n.(*Comment); !ok && !isValue(n) {
+		return invalidNode("SetAt", n)
+	}
+	if i < 0 || i >= len(a.Items) {
+		return indexError("SetAt", i)
+	}
+	keepComment(a.Items[i], n)
+	a.Items[i] = n
+	return nil
+}
+
+// RemoveAt removes the item at the index of Items.
+func (a *Array) RemoveAt(i int) error {
+	items, err := removeAt(a.Items, i)
+	if err != nil {
+		return err
+	}
+	a.Items = items
+	return nil
+}
+
+// Rename changes the key of the pair.
+func (p *Pair) Rename(key string) error {
+	return p.rename("Rename", key)
+}
+
+// SetValue replaces the value of the pair.
+// The trailing comment of a replaced scalar is kept if the new scalar has no comment.
+func (p *Pair) SetValue(v Node) error {
+	return p.setValue("SetValue", v)
+}
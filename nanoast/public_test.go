@@ -0,0 +1,227 @@
+package nanoast
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nanomarkup/nanomarkup.go/nanoerror"
+)
+
+const testDoc = "// the service\n\n{\n    name   api  \n\t// the server\n  server {  \n    host localhost\n    /* a multi-line\n       comment */\n    port 8080\n\n    empty\n    spaces   \n  }\n  text `  \n  line 1\n\nline 3\n`\n  list [\n    a\n    {\n      x 1\n    }\n    [\n    ]\n  ]\n}\n// end"
+
+func TestPrint(t *testing.T) {
+	inputs := []string{
+		testDoc,
+		"",
+		"\n\n",
+		"value",
+		"// only a comment\n",
+		"{\n}\n",
+		"[\n`\n{\n`\n]\n",
+		"{\n  port 8080   // the port\n  url a \\// b //c\n}\n",
+	}
+	for _, in := range inputs {
+		doc, err := Parse([]byte(in))
+		if err != nil {
+			t.Errorf("[Parse] in: %q; error: %v", in, err)
+			continue
+		}
+		if out := Print(doc); string(out) != in {
+			t.Errorf("[Print] out: %q; want: %q", out, in)
+		}
+	}
+	if _, err := Parse([]byte("{\nkey value\n")); err == nil {
+		t.Errorf("[Parse] want: error")
+	}
+}
+
+func TestParse(t *testing.T) {
+	doc, err := Parse([]byte(testDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Nodes) != 4 {
+		t.Fatalf("[Parse] nodes: %d; want: 4", len(doc.Nodes))
+	}
+	if c, ok := doc.Nodes[1].(*Comment); !ok || c.Text != "" || c.Pos() != (Position{2, 1}) {
+		t.Errorf("[Parse] blank line: %+v", doc.Nodes[1])
+	}
+	e, ok := doc.Value().(*Entity)
+	if !ok || e.Pos() != (Position{3, 1}) {
+		t.Fatalf("[Parse] value: %+v", doc.Value())
+	}
+	if p := e.Get("name"); p == nil || p.Pos() != (Position{4, 5}) || p.Value.(*Scalar).Value != "api  " || p.Value.Pos() != (Position{4, 12}) {
+		t.Errorf("[Parse] name: %+v", p)
+	}
+	server := e.Get("server").Value.(*Entity)
+	if c, ok := server.Items[1].(*Comment); !ok || c.Text != "/* a multi-line\n       comment */" || c.Pos() != (Position{8, 5}) {
+		t.Errorf("[Parse] comment: %+v", server.Items[1])
+	}
+	if p := server.Get("empty"); p == nil || p.Value.(*Scalar).Value != "" {
+		t.Errorf("[Parse] empty: %+v", p)
+	}
+	if m, ok := e.Get("text").Value.(*MultiLine); !ok || m.Value != "  line 1\n\nline 3" || m.Pos() != (Position{15, 8}) {
+		t.Errorf("[Parse] text: %+v", e.Get("text").Value)
+	}
+	list := e.Get("list").Value.(*Array)
+	if len(list.Values()) != 3 || list.Values()[1].Pos() != (Position{22, 5}) {
+		t.Errorf("[Parse] list: %+v", list.Values())
+	}
+}
+
+func TestTrailingComment(t *testing.T) {
+	doc, err := Parse([]byte("{\n  port 8080   // the port\n  url a \\// b //c\n  path a//b\n  name // the name\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := doc.Value().(*Entity)
+	tests := []struct {
+		key     string
+		value   string
+		comment string
+	}{
+		{"port", "8080", " the port"},
+		{"url", "a // b", "c"},
+		{"path", "a//b", ""},
+		{"name", "", " the name"},
+	}
+	for _, tt := range tests {
+		s := e.Get(tt.key).Value.(*Scalar)
+		if s.Value != tt.value || s.Comment != tt.comment {
+			t.Errorf("[Parse] %s: %q, comment: %q; want: %q, comment: %q", tt.key, s.Value, s.Comment, tt.value, tt.comment)
+		}
+	}
+	e.Get("port").Value.(*Scalar).Comment = " changed"
+	e.Get("url").Value.(*Scalar).Value = "b // a"
+	e.Get("path").Value.(*Scalar).Comment = " a path"
+	e.Get("name").Value.(*Scalar).Comment = " a name"
+	if _, err = e.Set("new", &Scalar{Value: "1", Comment: " new"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "{\n  port 8080 // changed\n  url b \\// a //c\n  path a//b // a path\n  name // a name\n  new 1 // new\n}\n"
+	if out := Print(doc); string(out) != want {
+		t.Errorf("[Print] out: %q; want: %q", out, want)
+	}
+
+	// the comment of a replaced scalar is kept
+	if _, err = e.Set("port", NewValue("9090")); err != nil {
+		t.Fatal(err)
+	}
+	if err = e.Get("new").SetValue(&Scalar{Value: "2", Comment: " two"}); err != nil {
+		t.Fatal(err)
+	}
+	want = "{\n  port 9090 // changed\n  url b \\// a //c\n  path a//b // a path\n  name // a name\n  new 2 // two\n}\n"
+	if out := Print(doc); string(out) != want {
+		t.Errorf("[Print] out: %q; want: %q", out, want)
+	}
+
+	var argErr *nanoerror.InvalidArgumentError
+	if _, err = e.Set("a b", NewValue("1")); !errors.As(err, &argErr) || argErr.Context != "Set" {
+		t.Errorf("[Set] error: %v; want: the Set context", err)
+	}
+}
+
+func TestMutate(t *testing.T) {
+	doc, err := Parse([]byte(testDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := doc.Value().(*Entity)
+	server := e.Get("server").Value.(*Entity)
+	if _, err = server.Set("port", NewValue("9090")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = server.Set("tls", NewEntity()); err != nil {
+		t.Fatal(err)
+	}
+	tls := server.Get("tls").Value.(*Entity)
+	if _, err = tls.Set("cert", NewValue("a.pem")); err != nil {
+		t.Fatal(err)
+	}
+	if err = server.Insert(0, NewComment(" the host")); err != nil {
+		t.Fatal(err)
+	}
+	if !server.Remove("empty") || server.Remove("missing") {
+		t.Errorf("[Remove] unexpected result")
+	}
+	if err = e.Get("name").Rename("title"); err != nil {
+		t.Fatal(err)
+	}
+	if err = e.Get("text").SetValue(NewValue("{ not an entity")); err != nil {
+		t.Fatal(err)
+	}
+	list := e.Get("list").Value.(*Array)
+	if err = list.Append(NewValue("line 1\nline 2")); err != nil {
+		t.Fatal(err)
+	}
+	if err = list.RemoveAt(0); err != nil {
+		t.Fatal(err)
+	}
+	want := "// the service\n\n{\n    title   api  \n\t// the server\n  server {  \n    // the host\n    host localhost\n    /* a multi-line\n       comment */\n    port 9090\n\n    spaces   \n    tls {\n        cert a.pem\n    }\n  }\n  text `\n{ not an entity\n`\n  list [\n    {\n      x 1\n    }\n    [\n    ]\n    `\nline 1\nline 2\n`\n  ]\n}\n// end"
+	out := Print(doc)
+	if string(out) != want {
+		t.Errorf("[Print] out: %q; want: %q", out, want)
+	}
+	if doc, err = Parse(out); err != nil {
+		t.Errorf("[Parse] error: %v", err)
+	} else if v := doc.Value().(*Entity).Get("text").Value.(*MultiLine).Value; v != "{ not an entity" {
+		t.Errorf("[Parse] text: %q", v)
+	}
+
+	// a line of a backtick is escaped
+	if err = e.Get("text").SetValue(NewValue("a\n`")); err != nil {
+		t.Fatal(err)
+	}
+	if doc, err = Parse(Print(&Document{Nodes: []Node{e}})); err != nil {
+		t.Errorf("[Parse] error: %v", err)
+	} else if v := doc.Value().(*Entity).Get("text").Value.(*MultiLine).Value; v != "a\n`" {
+		t.Errorf("[Parse] text: %q", v)
+	}
+
+	invalid := []error{
+		e.Get("title").Rename("a b"),
+		e.Get("title").Rename(""),
+		e.Insert(0, NewValue("x")),
+		list.Insert(0, &Pair{}),
+		list.Insert(10, NewValue("x")),
+		list.RemoveAt(-1),
+		list.SetAt(10, NewValue("x")),
+		list.SetAt(0, &Pair{}),
+		e.Get("title").SetValue(NewComment("x")),
+	}
+	for i, err := range invalid {
+		if err == nil {
+			t.Errorf("[Mutate] case %d; want: error", i)
+		}
+	}
+
+	// an array item is replaced in place
+	if doc, err = Parse([]byte("[\n  a // the first\n  // b\n  c\n]\n")); err != nil {
+		t.Fatal(err)
+	}
+	arr := doc.Value().(*Array)
+	if err = arr.SetAt(0, NewValue("x")); err != nil {
+		t.Fatal(err)
+	}
+	if err = arr.SetAt(2, NewEntity()); err != nil {
+		t.Fatal(err)
+	}
+	if out, want := Print(doc), "[\n  x // the first\n  // b\n  {\n  }\n]\n"; string(out) != want {
+		t.Errorf("[SetAt] out: %q; want: %q", out, want)
+	}
+
+	doc = &Document{}
+	root := NewEntity()
+	if err = doc.SetValue(root); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = root.Set("a", NewArray()); err != nil {
+		t.Fatal(err)
+	}
+	if err = root.Get("a").Value.(*Array).Append(NewValue("1")); err != nil {
+		t.Fatal(err)
+	}
+	if out = Print(doc); string(out) != "{\n\ta [\n\t\t1\n\t]\n}\n" {
+		t.Errorf("[Print] out: %q", out)
+	}
+}
@@ -23,3 +23,28 @@ func (c Comments) String() string {
 	}
 	return out
 }
+
+// Remove removes the comment at the index.
+func (c *Comments) Remove(index int) {
+	*c = append((*c)[:index], (*c)[index+1:]...)
+}
+
+// Replace replaces the comment at the index.
+func (c *Comments) Replace(index int, value string, multiline bool) {
+	(*c)[index] = &Comment{value, multiline}
+}
+
+// Text returns the text of the comment without the comment delimiters.
+func (c *Comment) Text() string {
+	return c.value
+}
+
+// IsMultiline reports whether the comment is written between /* and */.
+func (c *Comment) IsMultiline() bool {
+	return c.multiline
+}
+
+// IsBlankLine reports whether the comment is an empty line.
+func (c *Comment) IsBlankLine() bool {
+	return !c.multiline && c.value == ""
+}
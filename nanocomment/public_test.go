@@ -0,0 +1,32 @@
+package nanocomment
+
+import "testing"
+
+func TestComments(t *testing.T) {
+	c := Comments{}
+	c.Add(" first", false)
+	c.Add("", false)
+	c.Add(" second ", true)
+	c.Add("", true)
+	tests := []struct {
+		text      string
+		multiline bool
+		blank     bool
+	}{
+		{" first", false, false},
+		{"", false, true},
+		{" second ", true, false},
+		{"", true, false},
+	}
+	for i, tt := range tests {
+		if c[i].Text() != tt.text || c[i].IsMultiline() != tt.multiline || c[i].IsBlankLine() != tt.blank {
+			t.Errorf("[Comment %d] text: %q, multiline: %t, blank line: %t", i, c[i].Text(), c[i].IsMultiline(), c[i].IsBlankLine())
+		}
+	}
+	c.Remove(1)
+	c.Replace(2, " last", false)
+	want := "// first\n/* second */\n// last\n"
+	if out := string(Marshal(c)); out != want {
+		t.Errorf("[Comments] out: %q\nwant: %q", out, want)
+	}
+}
@@ -0,0 +1,189 @@
+package nanoconfig
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nanomarkup/nanomarkup.go/internal/nanotree"
+	"github.com/nanomarkup/nanomarkup.go/nanocomment"
+	"github.com/nanomarkup/nanomarkup.go/nanoerror"
+)
+
+const iniContext string = "INI"
+
+func parseINI(data []byte) (*nanotree.Node, error) {
+	root := nanotree.NewEntity()
+	curr := root
+	comments := nanocomment.Comments{}
+	for i, line := range strings.Split(string(data), "\n") {
+		num := i + 1
+		text := strings.TrimSpace(line)
+		if text == "" {
+			continue
+		} else if text[0] == ';' || text[0] == '#' {
+			comments.Add(strings.TrimRight(text[1:], " \t"), false)
+			continue
+		}
+		col := strings.Index(line, text) + 1
+		if text[0] == '[' {
+			end := strings.IndexByte(text, ']')
+			name := ""
+			if end > 0 {
+				name = strings.TrimSpace(text[1:end])
+			}
+			if name == "" || !isComment(text[end+1:]) {
+				return nil, syntaxError(iniContext, num, col, "invalid section: %s", text)
+			}
+			curr = root
+			for _, part := range strings.Split(name, ".") {
+				part = strings.TrimSpace(part)
+				if part == "" || strings.ContainsAny(part, " \t") {
+					return nil, syntaxError(iniContext, num, col, "invalid section: %s", text)
+				}
+				it := curr.Get(part)
+				if it == nil {
+					it = nanotree.NewEntity()
+					curr.Add(part, it)
+				} else if it.Kind != '{' {
+					return nil, syntaxError(iniContext, num, col, "the %s section conflicts with a key", name)
+				}
+				curr = it
+			}
+			curr.Comments.Adds(comments)
+			comments = nanocomment.Comments{}
+			continue
+		}
+		pos := strings.IndexAny(text, "=:")
+		if pos <= 0 {
+			return nil, syntaxError(iniContext, num, col, "invalid key: %s", text)
+		}
+		key := strings.TrimSpace(text[:pos])
+		value, comment, err := parseINIValue(strings.TrimSpace(text[pos+1:]))
+		if err != nil {
+			return nil, syntaxError(iniContext, num, col+pos+1, "%s", err.Error())
+		} else if comment != "" {
+			// the comment at the end of the line belongs to the key
+			comments.Add(comment, false)
+		}
+		array := strings.HasSuffix(key, "[]")
+		if array {
+			key = strings.TrimSpace(key[:len(key)-2])
+		}
+		if key == "" || strings.ContainsAny(key, " \t") {
+			return nil, syntaxError(iniContext, num, col, "invalid key: %s", key)
+		}
+		it := curr.Get(key)
+		if array {
+			if it == nil {
+				it = nanotree.NewArray()
+				curr.Add(key, it)
+			} else if it.Kind != '[' {
+				return nil, syntaxError(iniContext, num, col, "the %s key is duplicated", key)
+			}
+			it.Comments.Adds(comments)
+			it.Add("", nanotree.NewValue(value))
+		} else if it != nil {
+			return nil, syntaxError(iniContext, num, col, "the %s key is duplicated", key)
+		} else {
+			it = nanotree.NewValue(value)
+			it.Comments = comments
+			curr.Add(key, it)
+		}
+		comments = nanocomment.Comments{}
+	}
+	curr.End = comments
+	return root, nil
+}
+
+// parseINIValue returns a double-quoted or unquoted value and the text of the comment at the end of the line.
+func parseINIValue(text string) (string, string, error) {
+	if strings.HasPrefix(text, "\"") {
+		for i := 1; i < len(text); i++ {
+			if text[i] == '\\' {
+				i++
+			} else if text[i] == '"' {
+				if !isComment(text[i+1:]) {
+					return "", "", fmt.Errorf("unexpected data after the value: %s", text)
+				}
+				value, err := strconv.Unquote(text[:i+1])
+				return value, commentText(text[i+1:]), err
+			}
+		}
+		return "", "", fmt.Errorf("'\"' is missing")
+	}
+	comment := ""
+	if i := strings.IndexFunc(text, func(r rune) bool { return r == ';' || r == '#' }); i > 0 && (text[i-1] == ' ' || text[i-1] == '\t') {
+		comment = commentText(text[i:])
+		text = text[:i]
+	}
+	return strings.TrimSpace(text), comment, nil
+}
+
+func isComment(text string) bool {
+	text = strings.TrimSpace(text)
+	return text == "" || text[0] == ';' || text[0] == '#'
+}
+
+// commentText returns the text of the comment without the marker.
+func commentText(text string) string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return ""
+	}
+	return strings.TrimRight(text[1:], " \t")
+}
+
+// appendINI appends the keys of the section followed by the nested sections.
+func appendINI(dst []byte, n *nanotree.Node, path string) ([]byte, error) {
+	for i, k := range n.Keys {
+		it := n.Items[i]
+		if it.Kind == '{' {
+			continue
+		}
+		dst = appendComments(dst, it.Comments, ";")
+		if it.Kind == 0 {
+			dst = appendINIKey(dst, k, it.Value)
+			continue
+		}
+		for _, v := range it.Items {
+			if v.Kind != 0 {
+				return nil, &nanoerror.InvalidEntityError{Context: iniContext, Entity: k, Err: fmt.Errorf("an item of an array must be a value")}
+			}
+			dst = appendINIKey(dst, k+"[]", v.Value)
+		}
+	}
+	dst = appendComments(dst, n.End, ";")
+	for i, k := range n.Keys {
+		it := n.Items[i]
+		if it.Kind != '{' {
+			continue
+		}
+		name := k
+		if path != "" {
+			name = path + "." + k
+		}
+		if len(dst) > 0 {
+			dst = append(dst, '\n')
+		}
+		dst = appendComments(dst, it.Comments, ";")
+		dst = append(dst, '[')
+		dst = append(dst, name...)
+		dst = append(dst, "]\n"...)
+		var err error
+		if dst, err = appendINI(dst, it, name); err != nil {
+			return nil, err
+		}
+	}
+	return dst, nil
+}
+
+func appendINIKey(dst []byte, key, value string) []byte {
+	dst = append(dst, key...)
+	dst = append(dst, " = "...)
+	if value != strings.TrimSpace(value) || strings.ContainsAny(value, ";#\"\n\r\t") {
+		return append(strconv.AppendQuote(dst, value), '\n')
+	}
+	dst = append(dst, value...)
+	return append(dst, '\n')
+}
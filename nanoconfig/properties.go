@@ -0,0 +1,274 @@
+package nanoconfig
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nanomarkup/nanomarkup.go/internal/nanotree"
+	"github.com/nanomarkup/nanomarkup.go/nanocomment"
+)
+
+const propertiesContext string = "Properties"
+
+// segment is a part of a key of a property.
+type segment struct {
+	key   string
+	index int
+}
+
+func parseProperties(data []byte) (*nanotree.Node, error) {
+	root := nanotree.NewEntity()
+	// the entities which keys are indexes of arrays
+	indexed := map[*nanotree.Node]bool{}
+	comments := nanocomment.Comments{}
+	lines := strings.Split(string(data), "\n")
+	for i := 0; i < len(lines); i++ {
+		num := i + 1
+		text := strings.TrimLeft(strings.TrimSuffix(lines[i], "\r"), " \t\f")
+		if text == "" {
+			continue
+		} else if text[0] == '#' || text[0] == '!' {
+			comments.Add(strings.TrimRight(text[1:], " \t"), false)
+			continue
+		}
+		// join the continuation lines
+		for continued(text) && i+1 < len(lines) {
+			i++
+			text = text[:len(text)-1] + strings.TrimLeft(strings.TrimSuffix(lines[i], "\r"), " \t\f")
+		}
+		key, value, err := splitProperty(text)
+		if err != nil {
+			return nil, syntaxError(propertiesContext, num, 1, "%s", err.Error())
+		}
+		path, err := parsePath(key)
+		if err != nil {
+			return nil, syntaxError(propertiesContext, num, 1, "%s", err.Error())
+		}
+		for _, s := range path {
+			if strings.ContainsAny(s.key, " \t\n") {
+				// a nano key cannot contain whitespaces
+				return nil, syntaxError(propertiesContext, num, 1, "the key cannot be converted: %q", key)
+			}
+		}
+		curr := root
+		for j, s := range path {
+			name := s.key
+			if s.index >= 0 {
+				name = strconv.Itoa(s.index)
+			}
+			it := curr.Get(name)
+			if j == len(path)-1 {
+				if it != nil {
+					return nil, syntaxError(propertiesContext, num, 1, "the %s key is duplicated", key)
+				}
+				it = nanotree.NewValue(value)
+				it.Comments = comments
+				curr.Add(name, it)
+				break
+			}
+			if it == nil {
+				// the comments belong to the first new entity of the key
+				it = nanotree.NewEntity()
+				it.Comments = comments
+				comments = nanocomment.Comments{}
+				curr.Add(name, it)
+				indexed[it] = path[j+1].index >= 0
+			} else if it.Kind != '{' || indexed[it] != (path[j+1].index >= 0) {
+				return nil, syntaxError(propertiesContext, num, 1, "the %s key conflicts with another key", key)
+			}
+			curr = it
+		}
+		comments = nanocomment.Comments{}
+	}
+	root.End = comments
+	toArrays(root, indexed)
+	return root, nil
+}
+
+// toArrays converts the entities which keys are indexes to arrays ordered by the indexes.
+func toArrays(n *nanotree.Node, indexed map[*nanotree.Node]bool) {
+	for _, it := range n.Items {
+		toArrays(it, indexed)
+	}
+	if !indexed[n] {
+		return
+	}
+	pos := make([]int, len(n.Keys))
+	values := make([]int, len(n.Keys))
+	for i, k := range n.Keys {
+		pos[i] = i
+		values[i], _ = strconv.Atoi(k)
+	}
+	sort.SliceStable(pos, func(a, b int) bool {
+		return values[pos[a]] < values[pos[b]]
+	})
+	items := make([]*nanotree.Node, len(pos))
+	for i, p := range pos {
+		items[i] = n.Items[p]
+	}
+	n.Kind = '['
+	n.Keys = nil
+	n.Items = items
+}
+
+// continued reports whether the line ends with an odd number of backslashes.
+func continued(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// splitProperty returns the unescaped key and value of the line.
+func splitProperty(text string) (string, string, error) {
+	end := len(text)
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' {
+			i++
+		} else if strings.IndexByte("=: \t\f", text[i]) >= 0 {
+			end = i
+			break
+		}
+	}
+	key, err := unescapeProperty(text[:end])
+	if err != nil {
+		return "", "", err
+	}
+	rest := strings.TrimLeft(text[end:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+	value, err := unescapeProperty(rest)
+	return key, value, err
+}
+
+func unescapeProperty(text string) (string, error) {
+	if !strings.Contains(text, "\\") {
+		return text, nil
+	}
+	out := strings.Builder{}
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c != '\\' || i+1 == len(text) {
+			out.WriteByte(c)
+			continue
+		}
+		i++
+		switch text[i] {
+		case 't':
+			out.WriteByte('\t')
+		case 'n':
+			out.WriteByte('\n')
+		case 'r':
+			out.WriteByte('\r')
+		case 'f':
+			out.WriteByte('\f')
+		case 'u':
+			if i+4 >= len(text) {
+				return "", fmt.Errorf("invalid unicode escape: %s", text[i-1:])
+			}
+			r, err := strconv.ParseUint(text[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("invalid unicode escape: %s", text[i-1:i+5])
+			}
+			out.WriteRune(rune(r))
+			i += 4
+		default:
+			out.WriteByte(text[i])
+		}
+	}
+	return out.String(), nil
+}
+
+// parsePath splits the key by dots and indexes in square brackets, for example servers[0].host.
+func parsePath(key string) ([]segment, error) {
+	path := []segment{}
+	for _, part := range strings.Split(key, ".") {
+		name, rest, _ := strings.Cut(part, "[")
+		if name != "" {
+			path = append(path, segment{key: name, index: -1})
+		} else if len(path) == 0 || rest == "" && !strings.Contains(part, "[") {
+			return nil, fmt.Errorf("invalid key: %s", key)
+		}
+		for rest != "" {
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("']' is missing: %s", key)
+			}
+			index, err := strconv.Atoi(rest[:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid index: %s", key)
+			}
+			path = append(path, segment{index: index})
+			if rest = rest[end+1:]; rest != "" {
+				if rest[0] != '[' {
+					return nil, fmt.Errorf("invalid key: %s", key)
+				}
+				rest = rest[1:]
+			}
+		}
+	}
+	if len(path) == 0 {
+		return nil, fmt.Errorf("a key is missing")
+	}
+	return path, nil
+}
+
+// appendProperties appends the values of the entity or the array, the keys are joined by dots.
+func appendProperties(dst []byte, n *nanotree.Node, path string) []byte {
+	for i, it := range n.Items {
+		name := ""
+		if n.Kind == '[' {
+			name = path + "[" + strconv.Itoa(i) + "]"
+		} else if path == "" {
+			name = n.Keys[i]
+		} else {
+			name = path + "." + n.Keys[i]
+		}
+		dst = appendComments(dst, it.Comments, "#")
+		if it.Kind != 0 {
+			dst = appendProperties(dst, it, name)
+			continue
+		}
+		dst = appendEscaped(dst, name, true)
+		dst = append(dst, '=')
+		dst = appendEscaped(dst, it.Value, false)
+		dst = append(dst, '\n')
+	}
+	return appendComments(dst, n.End, "#")
+}
+
+// appendEscaped appends the key or the value escaping the special characters.
+func appendEscaped(dst []byte, text string, key bool) []byte {
+	for i, r := range text {
+		switch r {
+		case '\\':
+			dst = append(dst, `\\`...)
+		case '\n':
+			dst = append(dst, `\n`...)
+		case '\r':
+			dst = append(dst, `\r`...)
+		case '\t':
+			dst = append(dst, `\t`...)
+		case '\f':
+			dst = append(dst, `\f`...)
+		case ' ':
+			if key || i == 0 {
+				dst = append(dst, '\\')
+			}
+			dst = append(dst, ' ')
+		case '=', ':', '#', '!':
+			if key || i == 0 {
+				dst = append(dst, '\\')
+			}
+			dst = append(dst, byte(r))
+		default:
+			dst = utf8.AppendRune(dst, r)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,84 @@
+package nanoconfig
+
+// FromINI converts the INI data to nano.
+//
+// Sections are converted to entities, the names of sections are split by dots to nested entities
+// and the parts of names cannot contain whitespaces since they are converted to nano keys.
+// The keys with the [] suffix are collected to arrays. Comments which start with ';' or '#'
+// are converted to comments of the next key or section. A value can be double-quoted.
+func FromINI(data []byte) ([]byte, error) {
+	n, err := parseINI(data)
+	if err != nil {
+		return nil, err
+	}
+	return writeDocument(n)
+}
+
+// ToINI converts the nano-encoded entity to INI.
+//
+// The values of the entity are written before the sections, nested entities are written
+// as sections with dotted names and arrays of values as keys with the [] suffix.
+// Arrays of entities cannot be converted.
+func ToINI(data []byte) ([]byte, error) {
+	n, err := readDocument(data, iniContext)
+	if err != nil {
+		return nil, err
+	}
+	dst := appendComments([]byte{}, n.Comments, ";")
+	return appendINI(dst, n, "")
+}
+
+// FromTOML converts the TOML data to nano.
+//
+// Tables are converted to entities, arrays of tables to arrays of entities
+// and all scalars to values. Comments are converted to comments of the next item,
+// a comment at the end of a line is converted to a comment of the item of the line.
+func FromTOML(data []byte) ([]byte, error) {
+	n, err := parseTOML(data)
+	if err != nil {
+		return nil, err
+	}
+	return writeDocument(n)
+}
+
+// ToTOML converts the nano-encoded entity to TOML.
+//
+// Nested entities are written as tables and arrays of entities as arrays of tables,
+// other arrays and empty entities are written inline. The values which look like booleans,
+// numbers or date-times are written as they are, other values are written as strings.
+// The comments of inline values are skipped.
+func ToTOML(data []byte) ([]byte, error) {
+	n, err := readDocument(data, tomlContext)
+	if err != nil {
+		return nil, err
+	}
+	dst := appendComments([]byte{}, n.Comments, "#")
+	return appendTOML(dst, n, ""), nil
+}
+
+// FromProperties converts the Java properties data to nano.
+//
+// The keys are split by dots to nested entities and the indexes in square brackets,
+// like servers[0].host, are converted to arrays. Comments which start with '#' or '!'
+// are converted to comments of the next key. The parts of keys cannot contain whitespaces,
+// such as escaped spaces, since nano keys cannot contain them, these keys are reported as an error.
+func FromProperties(data []byte) ([]byte, error) {
+	n, err := parseProperties(data)
+	if err != nil {
+		return nil, err
+	}
+	return writeDocument(n)
+}
+
+// ToProperties converts the nano-encoded entity to Java properties.
+//
+// The keys of nested entities are joined by dots and the items of arrays are written
+// with indexes in square brackets. Empty entities and arrays are skipped.
+func ToProperties(data []byte) ([]byte, error) {
+	n, err := readDocument(data, propertiesContext)
+	if err != nil {
+		return nil, err
+	}
+	dst := appendComments([]byte{}, n.Comments, "#")
+	return appendProperties(dst, n, ""), nil
+}
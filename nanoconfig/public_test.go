@@ -0,0 +1,153 @@
+package nanoconfig
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nanomarkup/nanomarkup.go/nanoerror"
+)
+
+const testINI = `; global settings
+name = api
+debug = "a ; b"
+
+; the server
+[server]
+host = localhost
+port = 8080 ; inline
+hosts[] = a
+hosts[] = b
+
+[server.tls]
+enabled = true
+`
+
+const testININano = "{\n// global settings\nname api\ndebug a ; b\n// the server\nserver {\nhost localhost\n// inline\nport 8080\nhosts [\na\nb\n]\ntls {\nenabled true\n}\n}\n}\n"
+
+func TestINI(t *testing.T) {
+	out, err := FromINI([]byte(testINI))
+	if err != nil || string(out) != testININano {
+		t.Errorf("[FromINI] out: %q; want: %q; error: %v", out, testININano, err)
+	}
+	want := "; global settings\nname = api\ndebug = \"a ; b\"\n\n; the server\n[server]\nhost = localhost\n; inline\nport = 8080\nhosts[] = a\nhosts[] = b\n\n[server.tls]\nenabled = true\n"
+	if out, err = ToINI([]byte(testININano)); err != nil || string(out) != want {
+		t.Errorf("[ToINI] out: %q; want: %q; error: %v", out, want, err)
+	}
+	if out, err = FromINI(out); err != nil || string(out) != testININano {
+		t.Errorf("[FromINI] out: %q; want: %q; error: %v", out, testININano, err)
+	}
+
+	invalid := []string{"[]\n", "[a\n", "[a b]\n", "[a.]\n", "a\n", "a = 1\na = 2\n", "a = 1\n[a]\n", "a b = 1\n", "a = \"x\n"}
+	for _, item := range invalid {
+		if _, err = FromINI([]byte(item)); err == nil {
+			t.Errorf("[FromINI] in: %s; want: error", item)
+		}
+	}
+	invalid = []string{"[\n]\n", "{\na [\n{\n}\n]\n}\n"}
+	for _, item := range invalid {
+		if _, err = ToINI([]byte(item)); err == nil {
+			t.Errorf("[ToINI] in: %s; want: error", item)
+		}
+	}
+}
+
+const testTOML = `# Service configuration
+title = "TOML \"example\""
+port = 8080 # the port
+ratio = 1.5e3
+date = 1979-05-27 07:32:00Z
+path = 'C:\Users'
+text = """
+first
+second"""
+list = [
+  1, # one
+  [2, "x"],
+]
+point = { x = 1, y = { z = "a b" } }
+a.b = 1
+
+[server]
+host = "localhost"
+
+[server."tls.config"]
+enabled = true
+
+# the products
+[[products]]
+name = "Hammer"
+
+[[products]]
+name = "Nail"
+[products.size]
+mm = 10
+`
+
+const testTOMLNano = "{\n// Service configuration\ntitle TOML \"example\"\n// the port\nport 8080\nratio 1.5e3\ndate 1979-05-27 07:32:00Z\npath C:\\Users\ntext `\nfirst\nsecond\n`\nlist [\n1\n// one\n[\n2\nx\n]\n]\npoint {\nx 1\ny {\nz a b\n}\n}\na {\nb 1\n}\nserver {\nhost localhost\ntls.config {\nenabled true\n}\n}\n// the products\nproducts [\n{\nname Hammer\n}\n{\nname Nail\nsize {\nmm 10\n}\n}\n]\n}\n"
+
+func TestTOML(t *testing.T) {
+	out, err := FromTOML([]byte(testTOML))
+	if err != nil || string(out) != testTOMLNano {
+		t.Errorf("[FromTOML] out: %q; want: %q; error: %v", out, testTOMLNano, err)
+	}
+}
+
+func TestTOMLRoundTrip(t *testing.T) {
+	want := "# Service configuration\ntitle = \"TOML \\\"example\\\"\"\n# the port\nport = 8080\nratio = 1.5e3\ndate = 1979-05-27 07:32:00Z\npath = \"C:\\\\Users\"\ntext = \"\"\"\nfirst\nsecond\"\"\"\nlist = [1, [2, \"x\"]]\n\n[point]\nx = 1\n\n[point.y]\nz = \"a b\"\n\n[a]\nb = 1\n\n[server]\nhost = \"localhost\"\n\n[server.\"tls.config\"]\nenabled = true\n\n# the products\n[[products]]\nname = \"Hammer\"\n\n[[products]]\nname = \"Nail\"\n\n[products.size]\nmm = 10\n"
+	out, err := ToTOML([]byte(testTOMLNano))
+	if err != nil || string(out) != want {
+		t.Errorf("[ToTOML] out: %q; want: %q; error: %v", out, want, err)
+	}
+	if out, err = FromTOML(out); err != nil {
+		t.Errorf("[FromTOML] error: %v", err)
+	}
+
+	invalid := []string{"a = \n", "a = 1\na = 2\n", "[a]\n[a]\n", "a = 1\n[a]\n", "a = [1 2]\n", "a = \"x\n", "a = b\n", "a = 1 2\n", "[[a]]\n[a]\n", "a = {b = 1}\n[a]\n", "a = '''x\n", "a = \"\\q\"\n"}
+	for _, item := range invalid {
+		if _, err = FromTOML([]byte(item)); err == nil {
+			t.Errorf("[FromTOML] in: %s; want: error", item)
+		}
+	}
+	if _, err = ToTOML([]byte("value")); err == nil {
+		t.Errorf("[ToTOML] in: value; want: error")
+	}
+}
+
+const testProperties = `# Service configuration
+name=api
+server.host : localhost
+server.port 8080
+! the servers
+servers[1].host=b
+servers[0].host=a
+message=hello \
+        world\nsecond line
+key\=x=\u0041\=
+`
+
+const testPropertiesNano = "{\n// Service configuration\nname api\nserver {\nhost localhost\nport 8080\n}\n// the servers\nservers [\n{\nhost a\n}\n{\nhost b\n}\n]\nmessage `\nhello world\nsecond line\n`\nkey=x A=\n}\n"
+
+func TestProperties(t *testing.T) {
+	out, err := FromProperties([]byte(testProperties))
+	if err != nil || string(out) != testPropertiesNano {
+		t.Errorf("[FromProperties] out: %q; want: %q; error: %v", out, testPropertiesNano, err)
+	}
+
+	in := "// Service configuration\n{\nname api\nserver {\nhost localhost\nport 8080\n}\n// the servers\nservers [\n{\nhost a\n}\n{\nhost b\n}\n]\nmessage `\nhello world\nsecond line\n`\nempty {\n}\nvalue  a=b\nkey=x A=\n}\n"
+	want := "# Service configuration\nname=api\nserver.host=localhost\nserver.port=8080\n# the servers\nservers[0].host=a\nservers[1].host=b\nmessage=hello world\\nsecond line\nvalue=a=b\nkey\\=x=A=\n"
+	if out, err = ToProperties([]byte(in)); err != nil || string(out) != want {
+		t.Errorf("[ToProperties] out: %q; want: %q; error: %v", out, want, err)
+	}
+
+	invalid := []string{"a=1\na=2\n", "a=1\na.b=2\n", "a.b=1\na[0]=2\n", "a[x]=1\n", "a[0=1\n", ".a=1\n", "a=\\u00\n", "a.b\\tc=1\n"}
+	for _, item := range invalid {
+		if _, err = FromProperties([]byte(item)); err == nil {
+			t.Errorf("[FromProperties] in: %s; want: error", item)
+		}
+	}
+	// a nano key cannot contain the escaped spaces
+	var serr *nanoerror.SyntaxError
+	if _, err = FromProperties([]byte("# keys\nkey\\ with\\ space = v\n")); !errors.As(err, &serr) || serr.Line != 2 {
+		t.Errorf("[FromProperties] in: a key with spaces; error: %v", err)
+	}
+}
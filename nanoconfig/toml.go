@@ -0,0 +1,637 @@
+package nanoconfig
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nanomarkup/nanomarkup.go/internal/nanotree"
+	"github.com/nanomarkup/nanomarkup.go/nanocomment"
+)
+
+const tomlContext string = "TOML"
+
+var (
+	tomlBareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	tomlScalars = []*regexp.Regexp{
+		regexp.MustCompile(`^(true|false)$`),
+		regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)$`),
+		regexp.MustCompile(`^0x[0-9A-Fa-f](_?[0-9A-Fa-f])*$`),
+		regexp.MustCompile(`^0o[0-7](_?[0-7])*$`),
+		regexp.MustCompile(`^0b[01](_?[01])*$`),
+		regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)((\.[0-9](_?[0-9])*)([eE][+-]?[0-9](_?[0-9])*)?|[eE][+-]?[0-9](_?[0-9])*)$`),
+		regexp.MustCompile(`^[+-]?(inf|nan)$`),
+		regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}([Tt ][0-9]{2}:[0-9]{2}:[0-9]{2}(\.[0-9]+)?([Zz]|[+-][0-9]{2}:[0-9]{2})?)?$`),
+		regexp.MustCompile(`^[0-9]{2}:[0-9]{2}:[0-9]{2}(\.[0-9]+)?$`),
+	}
+)
+
+// tomlParser reads TOML data into an entity.
+type tomlParser struct {
+	data     string
+	pos      int
+	line     int
+	lineBeg  int
+	root     *nanotree.Node
+	curr     *nanotree.Node
+	comments nanocomment.Comments
+	// the explicitly defined tables
+	defined map[*nanotree.Node]bool
+	// the arrays of tables which are defined by headers
+	tables map[*nanotree.Node]bool
+	// the inline tables and arrays which cannot be extended
+	inline map[*nanotree.Node]bool
+}
+
+func parseTOML(data []byte) (*nanotree.Node, error) {
+	p := tomlParser{
+		data:    strings.ReplaceAll(string(data), "\r\n", "\n"),
+		line:    1,
+		root:    nanotree.NewEntity(),
+		defined: map[*nanotree.Node]bool{},
+		tables:  map[*nanotree.Node]bool{},
+		inline:  map[*nanotree.Node]bool{},
+	}
+	p.curr = p.root
+	for p.skipSpace(); p.pos < len(p.data); p.skipSpace() {
+		var err error
+		switch p.data[p.pos] {
+		case '\n':
+			p.newLine()
+			continue
+		case '#':
+			p.comments.Add(p.comment(), false)
+			continue
+		case '[':
+			err = p.parseTable()
+		default:
+			err = p.parseKeyValue(p.curr)
+		}
+		if err == nil {
+			err = p.endLine()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	p.curr.End = p.comments
+	return p.root, nil
+}
+
+func (p *tomlParser) parseTable() error {
+	p.pos++
+	array := p.peek('[')
+	if array {
+		p.pos++
+	}
+	p.skipSpace()
+	path, err := p.key()
+	if err != nil {
+		return err
+	}
+	p.skipSpace()
+	if !p.peek(']') || array && !strings.HasPrefix(p.data[p.pos:], "]]") {
+		return p.error("']' is missing")
+	}
+	p.pos++
+	if array {
+		p.pos++
+	}
+	// the comment at the end of the line belongs to the table
+	if p.skipSpace(); p.peek('#') {
+		p.comments.Add(p.comment(), false)
+	}
+	curr := p.root
+	for _, k := range path[:len(path)-1] {
+		if curr, err = p.table(curr, k, true); err != nil {
+			return err
+		}
+	}
+	k := path[len(path)-1]
+	it := curr.Get(k)
+	if array {
+		table := nanotree.NewEntity()
+		if it == nil {
+			// the comments before the first table belong to the array
+			it = nanotree.NewArray()
+			it.Comments = p.comments
+			curr.Add(k, it)
+			p.tables[it] = true
+		} else if !p.tables[it] {
+			return p.error("the %s key is not an array of tables", k)
+		} else {
+			table.Comments = p.comments
+		}
+		it.Add("", table)
+		p.curr = table
+	} else {
+		if it == nil {
+			it = nanotree.NewEntity()
+			curr.Add(k, it)
+		} else if it.Kind != '{' || p.defined[it] || p.inline[it] {
+			return p.error("the %s table is defined twice", k)
+		}
+		it.Comments.Adds(p.comments)
+		p.defined[it] = true
+		p.curr = it
+	}
+	p.comments = nanocomment.Comments{}
+	return nil
+}
+
+// table returns the entity of the key creating it if required, the last table of an array of tables is used.
+func (p *tomlParser) table(n *nanotree.Node, key string, header bool) (*nanotree.Node, error) {
+	it := n.Get(key)
+	if it == nil {
+		it = nanotree.NewEntity()
+		n.Add(key, it)
+		return it, nil
+	}
+	if it.Kind == '[' && p.tables[it] && header {
+		return it.Items[len(it.Items)-1], nil
+	} else if it.Kind != '{' || p.inline[it] || !header && p.defined[it] {
+		return nil, p.error("the %s key is already defined", key)
+	}
+	return it, nil
+}
+
+func (p *tomlParser) parseKeyValue(n *nanotree.Node) error {
+	path, err := p.key()
+	if err != nil {
+		return err
+	}
+	p.skipSpace()
+	if !p.peek('=') {
+		return p.error("'=' is missing")
+	}
+	p.pos++
+	p.skipSpace()
+	value, err := p.value()
+	if err != nil {
+		return err
+	}
+	for _, k := range path[:len(path)-1] {
+		if n, err = p.table(n, k, false); err != nil {
+			return err
+		}
+	}
+	k := path[len(path)-1]
+	if n.Get(k) != nil {
+		return p.error("the %s key is duplicated", k)
+	}
+	// the comment at the end of the line belongs to the key
+	if p.skipSpace(); p.peek('#') {
+		p.comments.Add(p.comment(), false)
+	}
+	value.Comments = p.comments
+	p.comments = nanocomment.Comments{}
+	n.Add(k, value)
+	return nil
+}
+
+// key reads a dotted key.
+func (p *tomlParser) key() ([]string, error) {
+	path := []string{}
+	for {
+		var k string
+		var err error
+		switch {
+		case p.peek('"'):
+			k, err = p.basicString()
+		case p.peek('\''):
+			k, err = p.literalString()
+		default:
+			start := p.pos
+			for p.pos < len(p.data) && isBareKeyChar(p.data[p.pos]) {
+				p.pos++
+			}
+			if k = p.data[start:p.pos]; k == "" {
+				return nil, p.error("a key is missing")
+			}
+		}
+		if err != nil {
+			return nil, err
+		} else if k == "" || strings.ContainsAny(k, " \t\n") {
+			return nil, p.error("the key cannot be converted: %q", k)
+		}
+		path = append(path, k)
+		p.skipSpace()
+		if !p.peek('.') {
+			return path, nil
+		}
+		p.pos++
+		p.skipSpace()
+	}
+}
+
+func (p *tomlParser) value() (*nanotree.Node, error) {
+	if p.pos >= len(p.data) {
+		return nil, p.error("a value is missing")
+	}
+	switch {
+	case strings.HasPrefix(p.data[p.pos:], `"""`):
+		s, err := p.multilineString(`"""`)
+		return nanotree.NewValue(s), err
+	case strings.HasPrefix(p.data[p.pos:], `'''`):
+		s, err := p.multilineString(`'''`)
+		return nanotree.NewValue(s), err
+	case p.peek('"'):
+		s, err := p.basicString()
+		return nanotree.NewValue(s), err
+	case p.peek('\''):
+		s, err := p.literalString()
+		return nanotree.NewValue(s), err
+	case p.peek('['):
+		return p.array()
+	case p.peek('{'):
+		return p.inlineTable()
+	}
+	start := p.pos
+	for p.pos < len(p.data) && strings.IndexByte(" \t\n,]}#", p.data[p.pos]) < 0 {
+		p.pos++
+	}
+	// a date can be separated from a time by a space
+	if p.pos+3 < len(p.data) && p.data[p.pos] == ' ' && isDigit(p.data[p.pos+1]) && isDigit(p.data[p.pos+2]) && p.data[p.pos+3] == ':' {
+		for p.pos++; p.pos < len(p.data) && strings.IndexByte(" \t\n,]}#", p.data[p.pos]) < 0; p.pos++ {
+		}
+	}
+	s := p.data[start:p.pos]
+	if !isTOMLScalar(s) {
+		return nil, p.error("invalid value: %s", s)
+	}
+	return nanotree.NewValue(s), nil
+}
+
+func (p *tomlParser) array() (*nanotree.Node, error) {
+	p.pos++
+	n := nanotree.NewArray()
+	p.inline[n] = true
+	comments := nanocomment.Comments{}
+	p.skipBlank(&comments)
+	if p.peek(']') {
+		p.pos++
+		n.End = comments
+		return n, nil
+	}
+	for {
+		it, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		it.Comments = comments
+		comments = nanocomment.Comments{}
+		n.Add("", it)
+		p.skipBlank(&comments)
+		if p.peek(',') {
+			p.pos++
+			p.skipBlank(&comments)
+		} else if !p.peek(']') {
+			return nil, p.error("',' is missing")
+		}
+		if p.peek(']') {
+			p.pos++
+			n.End = comments
+			return n, nil
+		}
+	}
+}
+
+func (p *tomlParser) inlineTable() (*nanotree.Node, error) {
+	p.pos++
+	n := nanotree.NewEntity()
+	p.inline[n] = true
+	p.skipSpace()
+	if p.peek('}') {
+		p.pos++
+		return n, nil
+	}
+	for {
+		if err := p.parseKeyValue(n); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.peek('}') {
+			p.pos++
+			return n, nil
+		} else if !p.peek(',') {
+			return nil, p.error("',' is missing")
+		}
+		p.pos++
+		p.skipSpace()
+	}
+}
+
+func (p *tomlParser) basicString() (string, error) {
+	start := p.pos
+	for p.pos++; p.pos < len(p.data) && p.data[p.pos] != '\n'; p.pos++ {
+		if p.data[p.pos] == '\\' {
+			p.pos++
+		} else if p.data[p.pos] == '"' {
+			p.pos++
+			return p.unescape(p.data[start+1 : p.pos-1])
+		}
+	}
+	return "", p.error("'\"' is missing")
+}
+
+func (p *tomlParser) literalString() (string, error) {
+	end := strings.IndexAny(p.data[p.pos+1:], "'\n")
+	if end < 0 || p.data[p.pos+1+end] != '\'' {
+		return "", p.error("\"'\" is missing")
+	}
+	s := p.data[p.pos+1 : p.pos+1+end]
+	p.pos += end + 2
+	return s, nil
+}
+
+func (p *tomlParser) multilineString(delim string) (string, error) {
+	p.pos += 3
+	// a new line after the delimiter is trimmed
+	if p.peek('\n') {
+		p.newLine()
+	}
+	start := p.pos
+	for ; p.pos < len(p.data); p.pos++ {
+		c := p.data[p.pos]
+		if c == '\n' {
+			p.line++
+			p.lineBeg = p.pos + 1
+		} else if c == '\\' && delim[0] == '"' && p.pos+1 < len(p.data) && p.data[p.pos+1] != '\n' {
+			p.pos++
+		} else if strings.HasPrefix(p.data[p.pos:], delim) {
+			// up to two quotes can precede the delimiter
+			end := p.pos
+			for end+3 < len(p.data) && p.data[end+3] == delim[0] && end-p.pos < 2 {
+				end++
+			}
+			s := p.data[start:end]
+			p.pos = end + 3
+			if delim[0] == '\'' {
+				return s, nil
+			}
+			return p.unescape(s)
+		}
+	}
+	return "", p.error("%s is missing", delim)
+}
+
+// unescape returns the value of a basic string, a backslash at the end of a line trims the following spaces.
+func (p *tomlParser) unescape(s string) (string, error) {
+	if !strings.Contains(s, "\\") {
+		return s, nil
+	}
+	out := strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			out.WriteByte(s[i])
+			continue
+		}
+		i++
+		if i == len(s) {
+			return "", p.error("invalid escape sequence")
+		}
+		switch s[i] {
+		case 'b':
+			out.WriteByte('\b')
+		case 't':
+			out.WriteByte('\t')
+		case 'n':
+			out.WriteByte('\n')
+		case 'f':
+			out.WriteByte('\f')
+		case 'r':
+			out.WriteByte('\r')
+		case 'e':
+			out.WriteByte(0x1b)
+		case '"', '\\':
+			out.WriteByte(s[i])
+		case 'u', 'U':
+			size := 4
+			if s[i] == 'U' {
+				size = 8
+			}
+			if i+size >= len(s) {
+				return "", p.error("invalid escape sequence")
+			}
+			r, err := strconv.ParseUint(s[i+1:i+1+size], 16, 32)
+			if err != nil {
+				return "", p.error("invalid escape sequence")
+			}
+			out.WriteRune(rune(r))
+			i += size
+		case ' ', '\t', '\n':
+			// trim the spaces and new lines after the line ending backslash
+			rest := strings.TrimLeft(s[i:], " \t")
+			if !strings.HasPrefix(rest, "\n") {
+				return "", p.error("invalid escape sequence")
+			}
+			i = len(s) - len(strings.TrimLeft(rest, " \t\n")) - 1
+		default:
+			return "", p.error("invalid escape sequence: \\%c", s[i])
+		}
+	}
+	return out.String(), nil
+}
+
+// comment reads the comment up to the end of the line and returns its text.
+func (p *tomlParser) comment() string {
+	end := strings.IndexByte(p.data[p.pos:], '\n')
+	if end < 0 {
+		end = len(p.data) - p.pos
+	}
+	s := p.data[p.pos+1 : p.pos+end]
+	p.pos += end
+	return strings.TrimRight(s, " \t")
+}
+
+// endLine checks that the line contains no more data.
+func (p *tomlParser) endLine() error {
+	p.skipSpace()
+	if p.peek('#') {
+		p.comments.Add(p.comment(), false)
+	}
+	if p.pos < len(p.data) && !p.peek('\n') {
+		return p.error("unexpected data: %s", strings.SplitN(p.data[p.pos:], "\n", 2)[0])
+	}
+	return nil
+}
+
+func (p *tomlParser) skipSpace() {
+	for p.pos < len(p.data) && (p.data[p.pos] == ' ' || p.data[p.pos] == '\t' || p.data[p.pos] == '\r') {
+		p.pos++
+	}
+}
+
+// skipBlank skips spaces, new lines and comments inside an array.
+func (p *tomlParser) skipBlank(comments *nanocomment.Comments) {
+	for p.skipSpace(); p.pos < len(p.data); p.skipSpace() {
+		if p.peek('\n') {
+			p.newLine()
+		} else if p.peek('#') {
+			comments.Add(p.comment(), false)
+		} else {
+			return
+		}
+	}
+}
+
+func (p *tomlParser) newLine() {
+	p.pos++
+	p.line++
+	p.lineBeg = p.pos
+}
+
+func (p *tomlParser) peek(c byte) bool {
+	return p.pos < len(p.data) && p.data[p.pos] == c
+}
+
+func (p *tomlParser) error(format string, a ...any) error {
+	return syntaxError(tomlContext, p.line, p.pos-p.lineBeg+1, format, a...)
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || isDigit(c) || c == '_' || c == '-'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isTOMLScalar reports whether the value is a boolean, a number or a date-time.
+func isTOMLScalar(s string) bool {
+	for _, re := range tomlScalars {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// appendTOML appends the keys of the table followed by the nested tables and arrays of tables.
+func appendTOML(dst []byte, n *nanotree.Node, path string) []byte {
+	for i, k := range n.Keys {
+		it := n.Items[i]
+		if isTable(it) || isTableArray(it) {
+			continue
+		}
+		dst = appendComments(dst, it.Comments, "#")
+		dst = appendTOMLKey(dst, k)
+		dst = append(dst, " = "...)
+		dst = appendTOMLValue(dst, it)
+		dst = append(dst, '\n')
+	}
+	dst = appendComments(dst, n.End, "#")
+	for i, k := range n.Keys {
+		it := n.Items[i]
+		if !isTable(it) && !isTableArray(it) {
+			continue
+		}
+		name := string(appendTOMLKey([]byte{}, k))
+		if path != "" {
+			name = path + "." + name
+		}
+		if isTable(it) {
+			if len(dst) > 0 {
+				dst = append(dst, '\n')
+			}
+			dst = appendComments(dst, it.Comments, "#")
+			dst = append(dst, '[')
+			dst = append(dst, name...)
+			dst = append(dst, "]\n"...)
+			dst = appendTOML(dst, it, name)
+			continue
+		}
+		for j, table := range it.Items {
+			if len(dst) > 0 {
+				dst = append(dst, '\n')
+			}
+			if j == 0 {
+				dst = appendComments(dst, it.Comments, "#")
+			}
+			dst = appendComments(dst, table.Comments, "#")
+			dst = append(dst, "[["...)
+			dst = append(dst, name...)
+			dst = append(dst, "]]\n"...)
+			dst = appendTOML(dst, table, name)
+		}
+		dst = appendComments(dst, it.End, "#")
+	}
+	return dst
+}
+
+// appendTOMLValue appends a scalar or an inline array or table.
+func appendTOMLValue(dst []byte, n *nanotree.Node) []byte {
+	switch n.Kind {
+	case '{':
+		if len(n.Items) == 0 {
+			return append(dst, "{}"...)
+		}
+		dst = append(dst, "{ "...)
+		for i, k := range n.Keys {
+			if i > 0 {
+				dst = append(dst, ", "...)
+			}
+			dst = appendTOMLKey(dst, k)
+			dst = append(dst, " = "...)
+			dst = appendTOMLValue(dst, n.Items[i])
+		}
+		return append(dst, " }"...)
+	case '[':
+		dst = append(dst, '[')
+		for i, it := range n.Items {
+			if i > 0 {
+				dst = append(dst, ", "...)
+			}
+			dst = appendTOMLValue(dst, it)
+		}
+		return append(dst, ']')
+	}
+	if isTOMLScalar(n.Value) {
+		return append(dst, n.Value...)
+	} else if strings.Contains(n.Value, "\n") {
+		dst = append(dst, "\"\"\"\n"...)
+		dst = appendTOMLString(dst, n.Value, true)
+		return append(dst, "\"\"\""...)
+	}
+	dst = append(dst, '"')
+	dst = appendTOMLString(dst, n.Value, false)
+	return append(dst, '"')
+}
+
+func appendTOMLKey(dst []byte, key string) []byte {
+	if tomlBareKey.MatchString(key) {
+		return append(dst, key...)
+	}
+	dst = append(dst, '"')
+	dst = appendTOMLString(dst, key, false)
+	return append(dst, '"')
+}
+
+// appendTOMLString appends the content of a basic string escaping the special characters.
+func appendTOMLString(dst []byte, s string, multiline bool) []byte {
+	for i, r := range s {
+		switch {
+		case r == '\\':
+			dst = append(dst, `\\`...)
+		case r == '"':
+			// only the quotes which can close a multi-line string are escaped
+			if !multiline || strings.HasPrefix(s[i:], `"""`) || i == len(s)-1 {
+				dst = append(dst, '\\')
+			}
+			dst = append(dst, '"')
+		case r == '\n' && multiline, r == '\t':
+			dst = utf8.AppendRune(dst, r)
+		case r == '\n':
+			dst = append(dst, `\n`...)
+		case r == '\r':
+			dst = append(dst, `\r`...)
+		case r < 0x20 || r == 0x7f:
+			dst = fmt.Appendf(dst, `\u%04X`, r)
+		default:
+			dst = utf8.AppendRune(dst, r)
+		}
+	}
+	return dst
+}
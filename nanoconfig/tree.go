@@ -0,0 +1,86 @@
+package nanoconfig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	nanomarkup "github.com/nanomarkup/nanomarkup.go"
+	"github.com/nanomarkup/nanomarkup.go/internal/nanotree"
+	"github.com/nanomarkup/nanomarkup.go/nanocomment"
+	"github.com/nanomarkup/nanomarkup.go/nanoerror"
+)
+
+// isTable reports whether the value is a non-empty entity.
+func isTable(n *nanotree.Node) bool {
+	return n.Kind == '{' && len(n.Items) > 0
+}
+
+// isTableArray reports whether the value is a non-empty array of entities.
+func isTableArray(n *nanotree.Node) bool {
+	if n.Kind != '[' || len(n.Items) == 0 {
+		return false
+	}
+	for _, it := range n.Items {
+		if it.Kind != '{' {
+			return false
+		}
+	}
+	return true
+}
+
+// readDocument reads the nano-encoded data which must be an entity.
+func readDocument(data []byte, context string) (*nanotree.Node, error) {
+	if errs := nanomarkup.Validate(data); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	n, _, err := nanotree.Parse(data)
+	if err != nil {
+		return nil, err
+	} else if n == nil {
+		return nanotree.NewEntity(), nil
+	} else if n.Kind != '{' {
+		return nil, &nanoerror.InvalidEntityError{Context: context, Entity: "", Err: fmt.Errorf("the data must be an entity")}
+	}
+	return n, nil
+}
+
+// writeDocument returns the nano encoding of the value.
+func writeDocument(n *nanotree.Node) ([]byte, error) {
+	out := bytes.Buffer{}
+	enc := nanomarkup.NewEncoder(&out)
+	if err := n.Write(enc); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// appendComments appends the comments starting every line by the marker.
+func appendComments(dst []byte, comments nanocomment.Comments, marker string) []byte {
+	for _, c := range comments {
+		text := strings.TrimSuffix(string(nanocomment.Marshal(nanocomment.Comments{c})), "\n")
+		if strings.HasPrefix(text, nanocomment.MultilineCommentBegOpCode) {
+			text = strings.TrimSuffix(text[2:], nanocomment.MultilineCommentEndOpCode)
+		} else if strings.HasPrefix(text, nanocomment.SingleCommentOpCode) {
+			text = text[2:]
+		} else {
+			// skip a blank line
+			continue
+		}
+		for _, l := range strings.Split(text, "\n") {
+			dst = append(dst, marker...)
+			dst = append(dst, strings.TrimRight(l, " \t")...)
+			dst = append(dst, '\n')
+		}
+	}
+	return dst
+}
+
+// syntaxError returns an error of the line of a configuration file.
+func syntaxError(context string, line, column int, format string, a ...any) error {
+	return &nanoerror.SyntaxError{Context: context, Line: line, Column: column, Err: fmt.Errorf(format, a...)}
+}
@@ -0,0 +1,132 @@
+package nanocsv
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	nanomarkup "github.com/nanomarkup/nanomarkup.go"
+	"github.com/nanomarkup/nanomarkup.go/nanoerror"
+)
+
+type options struct {
+	comma  rune
+	prefix string
+	indent string
+}
+
+func getOptions(opts []Option) options {
+	o := options{comma: ','}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func export(cw *csv.Writer, dec *nanomarkup.Decoder) error {
+	t, err := dec.Token()
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	} else if t != nanomarkup.Delim('[') {
+		return csvError(fmt.Errorf("the data must be an array of entities"))
+	}
+	var header []string = nil
+	index := map[string]int{}
+	for dec.More() {
+		if t, err = dec.Token(); err != nil {
+			return err
+		} else if t != nanomarkup.Delim('{') {
+			return csvError(fmt.Errorf("an item of the array must be an entity"))
+		}
+		keys := []string{}
+		values := []string{}
+		for dec.More() {
+			if t, err = dec.Token(); err != nil {
+				return err
+			}
+			keys = append(keys, string(t.(nanomarkup.Key)))
+			if t, err = dec.Token(); err != nil {
+				return err
+			} else if _, ok := t.(string); !ok {
+				return csvError(fmt.Errorf("a value of the %s key must be a scalar", keys[len(keys)-1]))
+			}
+			values = append(values, t.(string))
+		}
+		if _, err = dec.Token(); err != nil {
+			return err
+		}
+		if header == nil {
+			// the keys of the first entity are the header
+			if len(keys) == 0 {
+				return csvError(fmt.Errorf("the first entity must contain keys"))
+			}
+			header = keys
+			for i, k := range keys {
+				if _, ok := index[k]; ok {
+					return csvError(fmt.Errorf("the %s key is duplicated", k))
+				}
+				index[k] = i
+			}
+			if err = cw.Write(header); err != nil {
+				return err
+			}
+		}
+		row := make([]string, len(header))
+		for i, k := range keys {
+			pos, ok := index[k]
+			if !ok {
+				return csvError(fmt.Errorf("the %s key is missing in the header", k))
+			}
+			row[pos] = values[i]
+		}
+		if err = cw.Write(row); err != nil {
+			return err
+		}
+	}
+	if _, err = dec.Token(); err != nil {
+		return err
+	}
+	if _, err = dec.Token(); err != io.EOF {
+		return csvError(fmt.Errorf("unexpected data after the array"))
+	}
+	return nil
+}
+
+func importRows(enc *nanomarkup.Encoder, cr *csv.Reader) error {
+	record, err := cr.Read()
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	header := append([]string{}, record...)
+	if err = enc.BeginArray(); err != nil {
+		return err
+	}
+	for record, err = cr.Read(); err == nil; record, err = cr.Read() {
+		if err = enc.BeginEntity(); err != nil {
+			return err
+		}
+		for i, k := range header {
+			if err = enc.Key(k); err != nil {
+				return err
+			}
+			if err = enc.Encode(record[i], nil); err != nil {
+				return err
+			}
+		}
+		if err = enc.EndEntity(); err != nil {
+			return err
+		}
+	}
+	if err != io.EOF {
+		return err
+	}
+	return enc.EndArray()
+}
+
+func csvError(err error) error {
+	return &nanoerror.InvalidEntityError{Context: "CSV", Entity: "", Err: err}
+}
@@ -0,0 +1,79 @@
+package nanocsv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+
+	nanomarkup "github.com/nanomarkup/nanomarkup.go"
+)
+
+// Option configures the conversion.
+type Option func(*options)
+
+// ToCSV converts the nano-encoded array of flat entities to CSV.
+//
+// The header row contains the keys of the first entity, the keys of other entities
+// must be a subset of the header, a missing key is written as an empty field.
+// Comments are skipped.
+func ToCSV(data []byte, opts ...Option) ([]byte, error) {
+	out := bytes.Buffer{}
+	if err := Export(&out, bytes.NewReader(data), opts...); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// FromCSV converts CSV with a header row to a nano-encoded array of flat entities.
+func FromCSV(data []byte, opts ...Option) ([]byte, error) {
+	out := bytes.Buffer{}
+	if err := Import(&out, bytes.NewReader(data), opts...); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// Export reads the nano-encoded array of flat entities from r and writes it as CSV to w.
+// The entities are read and written one by one.
+//
+// See the documentation for ToCSV for details about the conversion.
+func Export(w io.Writer, r io.Reader, opts ...Option) error {
+	o := getOptions(opts)
+	cw := csv.NewWriter(w)
+	cw.Comma = o.comma
+	if err := export(cw, nanomarkup.NewDecoder(r)); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Import reads CSV with a header row from r and writes it as a nano-encoded array of flat entities to w.
+// The rows are read and written one by one.
+func Import(w io.Writer, r io.Reader, opts ...Option) error {
+	o := getOptions(opts)
+	cr := csv.NewReader(r)
+	cr.Comma = o.comma
+	cr.ReuseRecord = true
+	enc := nanomarkup.NewEncoder(w)
+	enc.SetIndent(o.prefix, o.indent)
+	if err := importRows(enc, cr); err != nil {
+		return err
+	}
+	return enc.Flush()
+}
+
+// WithComma sets the field delimiter, the default delimiter is a comma.
+func WithComma(comma rune) Option {
+	return func(o *options) {
+		o.comma = comma
+	}
+}
+
+// WithIndent instructs Import and FromCSV to indent the nano output like the Indent function of the nanomarkup package.
+func WithIndent(prefix, indent string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+		o.indent = indent
+	}
+}
@@ -0,0 +1,88 @@
+package nanocsv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const testNano = "[\n{\nid 1\nname Widget, large\nnote `\nfirst line\nsecond \"line\"\n`\n}\n{\nid 2\nname Gadget\nnote \n}\n]\n"
+
+const testCSV = "id,name,note\n1,\"Widget, large\",\"first line\nsecond \"\"line\"\"\"\n2,Gadget,\n"
+
+func TestToCSV(t *testing.T) {
+	testCases := []struct {
+		v    string
+		opts []Option
+		want string
+	}{
+		{v: "", want: ""},
+		{v: "[\n]\n", want: ""},
+		{v: testNano, want: testCSV},
+		{v: "// items\n[\n{\na 1\nb 2\n}\n// the second item\n{\nb 3\n}\n]\n", opts: []Option{WithComma(';')}, want: "a;b\n1;2\n;3\n"},
+	}
+
+	for _, item := range testCases {
+		out, err := ToCSV([]byte(item.v), item.opts...)
+		if err != nil {
+			t.Errorf("[ToCSV] in: %s; error: %s", item.v, err)
+		} else if string(out) != item.want {
+			t.Errorf("[ToCSV] in: %s; out: %q; want: %q", item.v, out, item.want)
+		}
+	}
+
+	invalid := []string{"{\n}\n", "[\n1\n]\n", "[\n{\na {\n}\n}\n]\n", "[\n{\na 1\n}\n{\nb 2\n}\n]\n", "[\n{\n}\n]\n", "[\n{\na 1\na 2\n}\n]\n", "[\n]\n[\n]\n"}
+	for _, item := range invalid {
+		if _, err := ToCSV([]byte(item)); err == nil {
+			t.Errorf("[ToCSV] in: %s; want: error", item)
+		}
+	}
+}
+
+func TestFromCSV(t *testing.T) {
+	testCases := []struct {
+		v    string
+		opts []Option
+		want string
+	}{
+		{v: "", want: ""},
+		{v: "a,b\n", want: "[\n]\n"},
+		{v: testCSV, want: testNano},
+		{v: "a;b\n1;2\n", opts: []Option{WithComma(';'), WithIndent("", "\t")}, want: "[\n\t{\n\t\ta 1\n\t\tb 2\n\t}\n]\n"},
+	}
+
+	for _, item := range testCases {
+		out, err := FromCSV([]byte(item.v), item.opts...)
+		if err != nil {
+			t.Errorf("[FromCSV] in: %s; error: %s", item.v, err)
+		} else if string(out) != item.want {
+			t.Errorf("[FromCSV] in: %s; out: %q; want: %q", item.v, out, item.want)
+		}
+	}
+
+	invalid := []string{"a,b\n1\n", "a b\n1\n", "a\n\"1\n"}
+	for _, item := range invalid {
+		if _, err := FromCSV([]byte(item)); err == nil {
+			t.Errorf("[FromCSV] in: %s; want: error", item)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	in := strings.Builder{}
+	in.WriteString("id,value\n")
+	for i := 0; i < 1000; i++ {
+		in.WriteString("1,text\n")
+	}
+	nano := bytes.Buffer{}
+	if err := Import(&nano, strings.NewReader(in.String())); err != nil {
+		t.Fatalf("[Import] %s", err)
+	}
+	out := bytes.Buffer{}
+	if err := Export(&out, &nano); err != nil {
+		t.Fatalf("[Export] %s", err)
+	}
+	if out.String() != in.String() {
+		t.Errorf("[Stream] in: %d bytes; out: %d bytes", in.Len(), out.Len())
+	}
+}
@@ -1,8 +1,30 @@
 package nanodecoder
 
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
 func (d *Decoder) Init(data [][]byte) {
 	d.data = data
 	d.index = -1
+	d.reader = nil
+	d.err = nil
+}
+
+// InitReader initializes the decoder to read lines from r on demand.
+// Only the current and the previous lines are kept in memory.
+func (d *Decoder) InitReader(r io.Reader) {
+	d.data = [][]byte{}
+	d.index = -1
+	d.reader = bufio.NewReader(r)
+	d.err = nil
+}
+
+// Err returns the first error that was encountered while reading lines.
+func (d *Decoder) Err() error {
+	return d.err
 }
 
 func (d *Decoder) Curr() ([]byte, bool) {
@@ -23,10 +45,33 @@ func (d *Decoder) Prev() ([]byte, bool) {
 }
 
 func (d *Decoder) Next() ([]byte, bool) {
-	if d.index+1 >= len(d.data) {
+	if d.index+1 >= len(d.data) && !d.read() {
 		return nil, false
 	} else {
 		d.index++
 		return d.data[d.index], true
 	}
 }
+
+func (d *Decoder) read() bool {
+	if d.reader == nil {
+		return false
+	}
+	line, err := d.reader.ReadBytes(10) // new line
+	if err != nil {
+		d.reader = nil
+		if err != io.EOF {
+			d.err = err
+			return false
+		} else if len(line) == 0 {
+			return false
+		}
+	}
+	if len(d.data) > 1 {
+		// keep the current line to be able to go back
+		d.data = append(d.data[:0], d.data[len(d.data)-1])
+		d.index = 0
+	}
+	d.data = append(d.data, bytes.TrimSuffix(line, []byte("\n")))
+	return true
+}
@@ -1,6 +1,10 @@
 package nanodecoder
 
+import "bufio"
+
 type Decoder struct {
-	data  [][]byte
-	index int
+	data   [][]byte
+	index  int
+	reader *bufio.Reader
+	err    error
 }
@@ -0,0 +1,171 @@
+package nanodiff
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	nanomarkup "github.com/nanomarkup/nanomarkup.go"
+	"github.com/nanomarkup/nanomarkup.go/nanopath"
+)
+
+// compare appends the changes of the values.
+func (d *Diff) compare(path string, before, after *nanopath.Node) {
+	switch {
+	case before == nil && after == nil:
+	case before == nil:
+		d.Changes = append(d.Changes, Change{Op: Added, Path: path, New: after})
+	case after == nil:
+		d.Changes = append(d.Changes, Change{Op: Removed, Path: path, Old: before})
+	case before.Kind != after.Kind:
+		d.Changes = append(d.Changes, Change{Op: Changed, Path: path, Old: before, New: after})
+	case before.Kind == nanopath.Entity:
+		for _, it := range before.Items {
+			d.compare(joinKey(path, it.Key), it, find(after, it.Key))
+		}
+		for _, it := range after.Items {
+			if find(before, it.Key) == nil {
+				d.compare(joinKey(path, it.Key), nil, it)
+			}
+		}
+	case before.Kind == nanopath.Array:
+		for i := 0; i < max(len(before.Items), len(after.Items)); i++ {
+			p := path + "[" + strconv.Itoa(i) + "]"
+			if i >= len(after.Items) {
+				d.compare(p, before.Items[i], nil)
+			} else if i >= len(before.Items) {
+				d.compare(p, nil, after.Items[i])
+			} else {
+				d.compare(p, before.Items[i], after.Items[i])
+			}
+		}
+	case before.Value != after.Value:
+		d.Changes = append(d.Changes, Change{Op: Changed, Path: path, Old: before, New: after})
+	}
+}
+
+// find returns the first item of the entity which has the key.
+func find(n *nanopath.Node, key string) *nanopath.Node {
+	for _, it := range n.Items {
+		if it.Key == key {
+			return it
+		}
+	}
+	return nil
+}
+
+// equal reports whether the values are equal ignoring the order of keys.
+func equal(a, b *nanopath.Node) bool {
+	d := Diff{}
+	d.compare("", a, b)
+	return len(d.Changes) == 0
+}
+
+// joinKey returns the path of the item of an entity in the nanopath syntax.
+func joinKey(path, key string) string {
+	switch {
+	case key == "" || strings.ContainsAny(key, ".[]'\"*?$"):
+		return path + "['" + key + "']"
+	case path == "":
+		return key
+	default:
+		return path + "." + key
+	}
+}
+
+func (d *Diff) report() []byte {
+	out := []byte{}
+	for _, c := range d.Changes {
+		path := c.Path
+		if path == "" {
+			path = "$"
+		}
+		out = append(out, c.Op.String()...)
+		out = append(out, ' ')
+		out = append(out, path...)
+		out = append(out, ':')
+		switch c.Op {
+		case Added:
+			out = appendValue(out, c.New)
+		case Removed:
+			out = appendValue(out, c.Old)
+		default:
+			out = appendValue(out, c.Old)
+			out = append(out, " ->"...)
+			out = appendValue(out, c.New)
+		}
+		out = append(out, '\n')
+	}
+	return out
+}
+
+// appendValue appends a scalar or an empty value on the same line
+// and the other values on the following lines indented by four spaces.
+func appendValue(dst []byte, n *nanopath.Node) []byte {
+	switch {
+	case n.Kind == nanopath.Scalar:
+		s := n.Value
+		if s == "" || s == "{}" || s == "[]" || strings.ContainsAny(s, "\n\r\t\"") || strings.TrimSpace(s) != s {
+			s = strconv.Quote(s)
+		}
+		return append(append(dst, ' '), s...)
+	case len(n.Items) == 0 && n.Kind == nanopath.Entity:
+		return append(dst, " {}"...)
+	case len(n.Items) == 0:
+		return append(dst, " []"...)
+	}
+	data, err := nanomarkup.Marshal(n, nil)
+	if err == nil {
+		data, err = nanomarkup.Format(data, nanomarkup.WithTabs(false))
+	}
+	if err != nil {
+		// the value is parsed from valid data, so it cannot happen
+		return append(dst, " ?"...)
+	}
+	for _, line := range bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n")) {
+		dst = append(dst, "\n    "...)
+		dst = append(dst, line...)
+	}
+	return dst
+}
+
+func (d *Diff) patch() ([]byte, error) {
+	var p *nanopath.Node
+	switch {
+	case d.before != nil && d.after != nil && d.before.Kind == nanopath.Entity && d.after.Kind == nanopath.Entity:
+		p = patchEntity(d.before, d.after)
+	case d.after == nil && d.before != nil:
+		p = &nanopath.Node{Value: nanomarkup.DeleteMarker}
+	case d.after == nil || equal(d.before, d.after):
+		return []byte{}, nil
+	default:
+		p = d.after
+	}
+	return nanomarkup.Marshal(p, nil)
+}
+
+// patchEntity returns the entity which contains the changed items,
+// the removed items are marked by nanomarkup.DeleteMarker.
+func patchEntity(before, after *nanopath.Node) *nanopath.Node {
+	p := &nanopath.Node{Kind: nanopath.Entity}
+	for _, it := range before.Items {
+		n := find(after, it.Key)
+		switch {
+		case n == nil:
+			p.Items = append(p.Items, &nanopath.Node{Key: it.Key, Value: nanomarkup.DeleteMarker})
+		case it.Kind == nanopath.Entity && n.Kind == nanopath.Entity:
+			if sub := patchEntity(it, n); len(sub.Items) > 0 {
+				sub.Key = it.Key
+				p.Items = append(p.Items, sub)
+			}
+		case !equal(it, n):
+			p.Items = append(p.Items, n)
+		}
+	}
+	for _, it := range after.Items {
+		if find(before, it.Key) == nil {
+			p.Items = append(p.Items, it)
+		}
+	}
+	return p
+}
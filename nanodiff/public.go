@@ -0,0 +1,88 @@
+// Package nanodiff compares nano documents semantically.
+//
+// The documents are compared by values: the indentation, the comments and the order of keys
+// of entities are ignored, items of arrays are compared by their indexes.
+package nanodiff
+
+import (
+	nanomarkup "github.com/nanomarkup/nanomarkup.go"
+	"github.com/nanomarkup/nanomarkup.go/nanopath"
+)
+
+// Op is a kind of a change.
+type Op int
+
+const (
+	Added Op = iota
+	Removed
+	Changed
+)
+
+// Change is a difference of a value.
+type Change struct {
+	Op Op
+	// Path is the location of the value like "servers[1].port", it is empty for the whole document.
+	Path string
+	// Old is nil if the value is added, New is nil if the value is removed.
+	Old *nanopath.Node
+	New *nanopath.Node
+}
+
+// Diff is the result of comparing two documents.
+type Diff struct {
+	Changes []Change
+	before  *nanopath.Node
+	after   *nanopath.Node
+}
+
+// Compare compares the nano-encoded documents and returns the changes which turn the first into the second.
+func Compare(a, b []byte) (*Diff, error) {
+	before, err := nanopath.Parse(a)
+	if err != nil {
+		return nil, err
+	}
+	after, err := nanopath.Parse(b)
+	if err != nil {
+		return nil, err
+	}
+	d := &Diff{before: before, after: after}
+	d.compare("", before, after)
+	return d, nil
+}
+
+// Equal reports whether the documents are equal.
+func (d *Diff) Equal() bool {
+	return len(d.Changes) == 0
+}
+
+// Report returns a human-readable report of the changes. Every change is written on a line
+// which starts with "+" for added, "-" for removed and "~" for changed values followed
+// by the path and the values like "~ servers[0].port: 8080 -> 9090".
+// Entities and arrays are written in the nano format on the following lines.
+func (d *Diff) Report() []byte {
+	return d.report()
+}
+
+// Patch returns a patch document which turns the first document into the second
+// when it is applied by Apply.
+func (d *Diff) Patch() ([]byte, error) {
+	return d.patch()
+}
+
+// Apply applies the patch document to the data.
+// It is the same as nanomarkup.Merge with the default options.
+func Apply(data, patch []byte) ([]byte, error) {
+	return nanomarkup.Merge(data, patch)
+}
+
+// String returns the symbol of the change used by Report.
+func (op Op) String() string {
+	switch op {
+	case Added:
+		return "+"
+	case Removed:
+		return "-"
+	default:
+		return "~"
+	}
+}
@@ -0,0 +1,110 @@
+package nanodiff
+
+import (
+	"testing"
+)
+
+const testOld = `// the service
+{
+	name api
+	server {
+		host localhost
+		port 8080
+	}
+	hosts [
+		a
+		b
+	]
+	debug true
+}
+`
+
+const testNew = `{
+server {
+port 9090
+host localhost
+tls {
+cert a.pem
+}
+}
+name api
+hosts [
+a
+]
+users [
+{
+name admin
+}
+]
+}
+`
+
+func TestCompare(t *testing.T) {
+	d, err := Compare([]byte(testOld), []byte(testNew))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "~ server.port: 8080 -> 9090\n+ server.tls:\n    {\n        cert a.pem\n    }\n- hosts[1]: b\n- debug: true\n+ users:\n    [\n        {\n            name admin\n        }\n    ]\n"
+	if out := d.Report(); string(out) != want {
+		t.Errorf("[Report] out: %q; want: %q", out, want)
+	}
+	if d.Equal() {
+		t.Errorf("[Equal] out: true; want: false")
+	}
+
+	// the formatting, the comments and the order of keys are ignored
+	if d, err = Compare([]byte(testOld), []byte("{\ndebug true\nhosts [\na\nb\n]\nserver {\nport 8080\nhost localhost\n}\nname api\n}\n")); err != nil || !d.Equal() {
+		t.Errorf("[Compare] changes: %v; error: %v", d.Changes, err)
+	}
+	testCases := []struct {
+		a, b string
+		want string
+	}{
+		{"a\n", "b\n", "~ $: a -> b\n"},
+		{"", "a\n", "+ $: a\n"},
+		{"{\nx `\nl1\nl2\n`\n}\n", "{\nx [\n]\n}\n", "~ x: \"l1\\nl2\" -> []\n"},
+		{"{\na.b 1\n}\n", "{\n}\n", "- ['a.b']: 1\n"},
+	}
+	for _, item := range testCases {
+		if d, err = Compare([]byte(item.a), []byte(item.b)); err != nil || string(d.Report()) != item.want {
+			t.Errorf("[Compare] a: %q; b: %q; out: %q; want: %q; error: %v", item.a, item.b, d.Report(), item.want, err)
+		}
+	}
+	if _, err = Compare([]byte("{\n"), []byte("")); err == nil {
+		t.Errorf("[Compare] want: error")
+	}
+}
+
+func TestPatch(t *testing.T) {
+	testCases := []struct {
+		a, b string
+		want string
+	}{
+		{testOld, testNew, "{\nserver {\nport 9090\ntls {\ncert a.pem\n}\n}\nhosts [\na\n]\ndebug ~delete\nusers [\n{\nname admin\n}\n]\n}\n"},
+		{testOld, testOld, "{\n}\n"},
+		{"a\n", "[\nb\n]\n", "[\nb\n]\n"},
+		{"a\n", "", "~delete\n"},
+		{"", "", ""},
+	}
+	for _, item := range testCases {
+		d, err := Compare([]byte(item.a), []byte(item.b))
+		if err != nil {
+			t.Errorf("[Compare] error: %v", err)
+			continue
+		}
+		patch, err := d.Patch()
+		if err != nil || string(patch) != item.want {
+			t.Errorf("[Patch] out: %q; want: %q; error: %v", patch, item.want, err)
+			continue
+		}
+		// the patch turns the first document into the second
+		out, err := Apply([]byte(item.a), patch)
+		if err != nil {
+			t.Errorf("[Apply] error: %v", err)
+			continue
+		}
+		if d, err = Compare(out, []byte(item.b)); err != nil || !d.Equal() {
+			t.Errorf("[Apply] out: %q; changes: %v; error: %v", out, d.Changes, err)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package nanoenv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	nanomarkup "github.com/nanomarkup/nanomarkup.go"
+	"github.com/nanomarkup/nanomarkup.go/internal/nanotag"
+	"github.com/nanomarkup/nanomarkup.go/nanoerror"
+)
+
+type options struct {
+	prefix string
+	sep    string
+}
+
+// tree is a nano value built from the names of variables.
+type tree struct {
+	keys     []string
+	children map[string]*tree
+	value    string
+	leaf     bool
+	// name is the name of the variable of the value
+	name string
+}
+
+func getOptions(opts []Option) options {
+	o := options{sep: DefaultSeparator}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.sep == "" {
+		o.sep = DefaultSeparator
+	}
+	return o
+}
+
+func flatten(dec *nanomarkup.Decoder, o options) ([]string, error) {
+	env := []string{}
+	t, err := dec.Token()
+	if err == io.EOF {
+		return env, nil
+	} else if err != nil {
+		return nil, err
+	}
+	path := []string{}
+	if o.prefix != "" {
+		path = append(path, strings.ToUpper(o.prefix))
+	}
+	return flattenValue(dec, t, path, env, o)
+}
+
+func flattenValue(dec *nanomarkup.Decoder, t nanomarkup.Token, path, env []string, o options) ([]string, error) {
+	if v, ok := t.(string); ok {
+		if len(path) == 0 {
+			return nil, envError(fmt.Errorf("a name of the variable is missing, use a prefix"))
+		}
+		return append(env, strings.Join(path, o.sep)+"="+v), nil
+	}
+	array := t == nanomarkup.Delim('[')
+	for i := 0; dec.More(); i++ {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		name := strconv.Itoa(i)
+		if !array {
+			name = envName(string(t.(nanomarkup.Key)))
+			if t, err = dec.Token(); err != nil {
+				return nil, err
+			}
+		}
+		if env, err = flattenValue(dec, t, append(path[:len(path):len(path)], name), env, o); err != nil {
+			return nil, err
+		}
+	}
+	_, err := dec.Token()
+	return env, err
+}
+
+// envName returns the upper-cased key, the characters which are not letters, digits or underscores are replaced by underscores.
+func envName(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		} else if r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, key)
+}
+
+func unflatten(env []string, typ reflect.Type, o options) ([]byte, error) {
+	prefix := ""
+	if o.prefix != "" {
+		prefix = strings.ToUpper(o.prefix) + o.sep
+	}
+	root := &tree{}
+	for _, it := range env {
+		name, value, ok := strings.Cut(it, "=")
+		if !ok || !strings.HasPrefix(name, prefix) || len(name) == len(prefix) {
+			continue
+		}
+		keys := resolve(typ, strings.Split(name[len(prefix):], o.sep), o.sep)
+		if err := root.insert(keys, name, value); err != nil {
+			return nil, envError(fmt.Errorf("the %s variable conflicts with another variable: %w", name, err))
+		}
+	}
+	if len(root.keys) == 0 {
+		return []byte{}, nil
+	}
+	out := bytes.Buffer{}
+	enc := nanomarkup.NewEncoder(&out)
+	if err := root.write(enc); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// resolve returns the keys of the parts of a name, the parts are matched with the fields of a struct type.
+func resolve(typ reflect.Type, parts []string, sep string) []string {
+	for typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if len(parts) == 0 {
+		return []string{}
+	}
+	var elem reflect.Type = nil
+	key := strings.ToLower(parts[0])
+	if typ != nil {
+		switch typ.Kind() {
+		case reflect.Struct:
+			// a key of a field can contain the separator
+			for n := len(parts); n > 0; n-- {
+				if name, ft, ok := fieldByKey(typ, strings.Join(parts[:n], sep), sep); ok {
+					return append([]string{name}, resolve(ft, parts[n:], sep)...)
+				}
+			}
+		case reflect.Map, reflect.Slice, reflect.Array:
+			elem = typ.Elem()
+		}
+	}
+	return append([]string{key}, resolve(elem, parts[1:], sep)...)
+}
+
+// fieldByKey returns the nano key and the type of the field which matches the name ignoring case and separators.
+func fieldByKey(typ reflect.Type, name, sep string) (string, reflect.Type, bool) {
+	name = strings.ReplaceAll(name, sep, "")
+	for _, f := range reflect.VisibleFields(typ) {
+		if !f.IsExported() || len(f.Index) > 1 {
+			continue
+		}
+		tag := nanotag.Parse(f)
+		if tag.Ignore || tag.Remain {
+			continue
+		}
+		key := tag.Name
+		if strings.EqualFold(strings.ReplaceAll(key, "_", ""), name) {
+			return key, f.Type, true
+		}
+	}
+	return "", nil, false
+}
+
+// insert adds the value of the variable by the keys, the variables which have different names
+// and the same keys conflict.
+func (t *tree) insert(keys []string, name, value string) error {
+	if len(keys) == 0 {
+		if len(t.keys) > 0 {
+			return fmt.Errorf("the value has keys")
+		} else if t.leaf && t.name != name {
+			return fmt.Errorf("the value is set by the %s variable", t.name)
+		}
+		t.value = value
+		t.leaf = true
+		t.name = name
+		return nil
+	} else if t.leaf {
+		return fmt.Errorf("the value is not an entity")
+	}
+	if t.children == nil {
+		t.children = map[string]*tree{}
+	}
+	child, ok := t.children[keys[0]]
+	if !ok {
+		child = &tree{}
+		t.children[keys[0]] = child
+		t.keys = append(t.keys, keys[0])
+	}
+	return child.insert(keys[1:], name, value)
+}
+
+func (t *tree) write(enc *nanomarkup.Encoder) error {
+	if t.leaf {
+		return enc.Encode(t.value, nil)
+	}
+	if indexes := t.indexes(); indexes != nil {
+		if err := enc.BeginArray(); err != nil {
+			return err
+		}
+		for _, i := range indexes {
+			if err := t.children[t.keys[i]].write(enc); err != nil {
+				return err
+			}
+		}
+		return enc.EndArray()
+	}
+	if err := enc.BeginEntity(); err != nil {
+		return err
+	}
+	for _, k := range t.keys {
+		if err := enc.Key(k); err != nil {
+			return err
+		}
+		if err := t.children[k].write(enc); err != nil {
+			return err
+		}
+	}
+	return enc.EndEntity()
+}
+
+// indexes returns the positions of keys ordered by the indexes if all keys are indexes.
+func (t *tree) indexes() []int {
+	values := make([]int, len(t.keys))
+	for i, k := range t.keys {
+		n, err := strconv.Atoi(k)
+		if err != nil || n < 0 {
+			return nil
+		}
+		values[i] = n
+	}
+	pos := make([]int, len(t.keys))
+	for i := range pos {
+		pos[i] = i
+	}
+	sort.SliceStable(pos, func(a, b int) bool {
+		return values[pos[a]] < values[pos[b]]
+	})
+	return pos
+}
+
+// appendValue appends the value of a dotenv variable, the value is double-quoted if required.
+func appendValue(dst []byte, value string) []byte {
+	if !strings.ContainsAny(value, " \t\r\n\"'#\\$`=") {
+		return append(dst, value...)
+	}
+	dst = append(dst, '"')
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\n':
+			dst = append(dst, `\n`...)
+		case '\r':
+			dst = append(dst, `\r`...)
+		case '\t':
+			dst = append(dst, `\t`...)
+		case '"', '\\':
+			dst = append(dst, '\\', c)
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return append(dst, '"')
+}
+
+// parseDotenv returns the variables of the dotenv data in the form "NAME=value".
+func parseDotenv(data []byte) ([]string, error) {
+	env := []string{}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, &nanoerror.SyntaxError{Context: "Env", Line: i + 1, Column: 1, Err: fmt.Errorf("invalid variable: %s", line)}
+		}
+		value, err := parseValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, &nanoerror.SyntaxError{Context: "Env", Line: i + 1, Column: len(name) + 2, Err: err}
+		}
+		env = append(env, name+"="+value)
+	}
+	return env, nil
+}
+
+func parseValue(value string) (string, error) {
+	if value == "" {
+		return value, nil
+	}
+	rest := ""
+	switch value[0] {
+	case '\'':
+		end := strings.IndexByte(value[1:], '\'')
+		if end < 0 {
+			return "", fmt.Errorf("' is missing")
+		}
+		rest = value[end+2:]
+		value = value[1 : end+1]
+	case '"':
+		out := []byte{}
+		end := -1
+		for i := 1; i < len(value) && end < 0; i++ {
+			c := value[i]
+			if c == '"' {
+				end = i
+			} else if c == '\\' && i+1 < len(value) {
+				i++
+				switch value[i] {
+				case 'n':
+					out = append(out, '\n')
+				case 'r':
+					out = append(out, '\r')
+				case 't':
+					out = append(out, '\t')
+				default:
+					out = append(out, value[i])
+				}
+			} else {
+				out = append(out, c)
+			}
+		}
+		if end < 0 {
+			return "", fmt.Errorf("'\"' is missing")
+		}
+		rest = value[end+1:]
+		value = string(out)
+	default:
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = value[:i]
+		}
+		return strings.TrimSpace(value), nil
+	}
+	if rest = strings.TrimSpace(rest); rest != "" && rest[0] != '#' {
+		return "", fmt.Errorf("unexpected data after the value: %s", rest)
+	}
+	return value, nil
+}
+
+func envError(err error) error {
+	return &nanoerror.InvalidEntityError{Context: "Env", Entity: "", Err: err}
+}
@@ -0,0 +1,115 @@
+package nanoenv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	nanomarkup "github.com/nanomarkup/nanomarkup.go"
+	"github.com/nanomarkup/nanomarkup.go/nanoerror"
+)
+
+// Option configures the conversion.
+type Option func(*options)
+
+// DefaultSeparator is the separator of keys and indexes in the names of variables.
+const DefaultSeparator string = "_"
+
+// Flatten converts the nano-encoded data to environment variables in the form "NAME=value".
+//
+// The name of a variable is the prefix followed by the upper-cased keys and indexes
+// of the value joined by the separator, for example APP_SERVERS_0_PORT. The characters
+// of keys which are not letters, digits or underscores are replaced by underscores.
+// Empty entities and arrays do not produce variables, comments are skipped.
+func Flatten(data []byte, opts ...Option) ([]string, error) {
+	o := getOptions(opts)
+	if errs := nanomarkup.Validate(data); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return flatten(nanomarkup.NewDecoder(bytes.NewReader(data)), o)
+}
+
+// Unflatten converts the environment variables in the form "NAME=value" to nano.
+//
+// Only the variables which start with the prefix are converted. The parts of a name
+// are converted to lower-cased keys of nested entities, an entity which keys are indexes
+// is converted to an array ordered by the indexes. The order of keys follows the order of variables.
+// Different variables which resolve to the same key are reported as an error.
+func Unflatten(env []string, opts ...Option) ([]byte, error) {
+	return unflatten(env, nil, getOptions(opts))
+}
+
+// ToDotenv converts the nano-encoded data to the dotenv format, one variable per line.
+// The values which contain spaces, quotes or special characters are double-quoted.
+//
+// See the documentation for Flatten for details about the names of variables.
+func ToDotenv(data []byte, opts ...Option) ([]byte, error) {
+	env, err := Flatten(data, opts...)
+	if err != nil {
+		return nil, err
+	}
+	out := []byte{}
+	for _, it := range env {
+		name, value, _ := strings.Cut(it, "=")
+		out = append(out, name...)
+		out = append(out, '=')
+		out = appendValue(out, value)
+		out = append(out, '\n')
+	}
+	return out, nil
+}
+
+// FromDotenv converts the data in the dotenv format to nano.
+//
+// Empty lines, comments and the "export" keyword are skipped, the values can be
+// unquoted, single-quoted or double-quoted with escape sequences.
+//
+// See the documentation for Unflatten for details about the conversion.
+func FromDotenv(data []byte, opts ...Option) ([]byte, error) {
+	env, err := parseDotenv(data)
+	if err != nil {
+		return nil, err
+	}
+	return Unflatten(env, opts...)
+}
+
+// Unmarshal decodes the environment variables in the form "NAME=value" into the value pointed to by v.
+//
+// The parts of a name are matched with the keys of struct fields ignoring case, a key can consist
+// of several parts joined by the separator. The variables are converted to nano and decoded by
+// the Unmarshal function of the nanomarkup package.
+func Unmarshal(env []string, v any, opts ...Option) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return &nanoerror.InvalidArgumentError{Context: "Env", Err: fmt.Errorf("the second argument is not a non-nil Pointer")}
+	}
+	data, err := unflatten(env, rv.Type().Elem(), getOptions(opts))
+	if err != nil {
+		return err
+	}
+	return nanomarkup.Unmarshal(data, v, nil)
+}
+
+// Decode decodes the variables of the current process into the value pointed to by v.
+//
+// See the documentation for Unmarshal for details about the conversion.
+func Decode(v any, opts ...Option) error {
+	return Unmarshal(os.Environ(), v, opts...)
+}
+
+// WithPrefix sets the prefix of the names of variables, the prefix is joined with the name by the separator.
+func WithPrefix(prefix string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+// WithSeparator sets the separator of keys and indexes in the names of variables, the default separator is DefaultSeparator.
+func WithSeparator(sep string) Option {
+	return func(o *options) {
+		o.sep = sep
+	}
+}
@@ -0,0 +1,131 @@
+package nanoenv
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testNano = "{\nserver {\nhost localhost\nport 8080\n}\nmax_conns 10\nlabels [\na\nb c\n]\nmotd `\nhello\n\"world\"\n`\n}\n"
+
+func TestFlatten(t *testing.T) {
+	want := []string{"APP_SERVER_HOST=localhost", "APP_SERVER_PORT=8080", "APP_MAX_CONNS=10", "APP_LABELS_0=a", "APP_LABELS_1=b c", "APP_MOTD=hello\n\"world\""}
+	out, err := Flatten([]byte(testNano), WithPrefix("app"))
+	if err != nil || !reflect.DeepEqual(out, want) {
+		t.Errorf("[Flatten] out: %q; want: %q; error: %v", out, want, err)
+	}
+	want = []string{"SERVER__HOST=localhost", "SERVER__PORT=8080", "MAX_CONNS=10", "LABELS__0=a", "LABELS__1=b c", "MOTD=hello\n\"world\""}
+	if out, err = Flatten([]byte(testNano), WithSeparator("__")); err != nil || !reflect.DeepEqual(out, want) {
+		t.Errorf("[Flatten] out: %q; want: %q; error: %v", out, want, err)
+	}
+	if out, err = Flatten([]byte("{\nkey-name.x 1\n}\n")); err != nil || !reflect.DeepEqual(out, []string{"KEY_NAME_X=1"}) {
+		t.Errorf("[Flatten] out: %q; error: %v", out, err)
+	}
+	if _, err = Flatten([]byte("value")); err == nil {
+		t.Errorf("[Flatten] in: value; want: error")
+	}
+}
+
+func TestUnflatten(t *testing.T) {
+	testCases := []struct {
+		v    []string
+		opts []Option
+		want string
+	}{
+		{v: []string{"PATH=/bin"}, opts: []Option{WithPrefix("app")}, want: ""},
+		{v: []string{"APP_SERVER_HOST=localhost", "PATH=/bin", "APP_LIST_1=b", "APP_SERVER_PORT=8080", "APP_LIST_0=a"}, opts: []Option{WithPrefix("APP")}, want: "{\nserver {\nhost localhost\nport 8080\n}\nlist [\na\nb\n]\n}\n"},
+		{v: []string{"A__B_C=1", "A__D="}, opts: []Option{WithSeparator("__")}, want: "{\na {\nb_c 1\nd \n}\n}\n"},
+	}
+
+	for _, item := range testCases {
+		out, err := Unflatten(item.v, item.opts...)
+		if err != nil {
+			t.Errorf("[Unflatten] in: %q; error: %s", item.v, err)
+		} else if string(out) != item.want {
+			t.Errorf("[Unflatten] in: %q; out: %q; want: %q", item.v, out, item.want)
+		}
+	}
+
+	if _, err := Unflatten([]string{"A=1", "A_B=2"}); err == nil {
+		t.Errorf("[Unflatten] in: conflicting variables; want: error")
+	}
+}
+
+func TestDotenv(t *testing.T) {
+	want := "APP_SERVER_HOST=localhost\nAPP_SERVER_PORT=8080\nAPP_MAX_CONNS=10\nAPP_LABELS_0=a\nAPP_LABELS_1=\"b c\"\nAPP_MOTD=\"hello\\n\\\"world\\\"\"\n"
+	out, err := ToDotenv([]byte(testNano), WithPrefix("app"))
+	if err != nil || string(out) != want {
+		t.Errorf("[ToDotenv] out: %q; want: %q; error: %v", out, want, err)
+	}
+	in := "# comment\n\nexport APP_NAME='a # b' # name\nAPP_PORT = 80 # port\nAPP_EMPTY=\n" + string(out)
+	want = "{\nname a # b\nport 80\nempty \nserver {\nhost localhost\nport 8080\n}\nmax {\nconns 10\n}\nlabels [\na\nb c\n]\nmotd `\nhello\n\"world\"\n`\n}\n"
+	if out, err = FromDotenv([]byte(in), WithPrefix("app")); err != nil || string(out) != want {
+		t.Errorf("[FromDotenv] out: %q; want: %q; error: %v", out, want, err)
+	}
+	invalid := []string{"NAME\n", "NAME=\"value\n", "NAME='value\n", "NAME=\"a\" b\n"}
+	for _, item := range invalid {
+		if _, err = FromDotenv([]byte(item)); err == nil {
+			t.Errorf("[FromDotenv] in: %s; want: error", item)
+		}
+	}
+}
+
+type testServer struct {
+	Host string `nano:"host"`
+	Port int
+}
+
+type testConfig struct {
+	Server   testServer            `nano:"server"`
+	MaxConns int                   `nano:"max_conns,omitempty"`
+	Replicas []*testServer         `nano:"replicas"`
+	Labels   map[string]string     `nano:"labels"`
+	Limits   map[string]testServer `nano:"limits"`
+	Secret   string                `nano:"-"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	env := []string{
+		"APP_SERVER_HOST=localhost",
+		"APP_SERVER_PORT=8080",
+		"APP_MAX_CONNS=10",
+		"APP_REPLICAS_1_HOST=b",
+		"APP_REPLICAS_0_HOST=a",
+		"APP_LABELS_TEAM=core",
+		"APP_LIMITS_CPU_PORT=1",
+		"APP_SECRET=x",
+	}
+	v := testConfig{}
+	if err := Unmarshal(env, &v, WithPrefix("app")); err != nil {
+		t.Fatalf("[Unmarshal] %s", err)
+	}
+	want := testConfig{
+		Server:   testServer{Host: "localhost", Port: 8080},
+		MaxConns: 10,
+		Replicas: []*testServer{{Host: "a"}, {Host: "b"}},
+		Labels:   map[string]string{"team": "core"},
+		Limits:   map[string]testServer{"cpu": {Port: 1}},
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("[Unmarshal] out: %+v; want: %+v", v, want)
+	}
+	// both variables resolve to the MaxConns field
+	err := Unmarshal([]string{"APP_MAXCONNS=1", "APP_MAX_CONNS=2"}, &v, WithPrefix("app"))
+	if err == nil || !strings.Contains(err.Error(), "APP_MAX_CONNS") || !strings.Contains(err.Error(), "APP_MAXCONNS") {
+		t.Errorf("[Unmarshal] in: conflicting variables; error: %v", err)
+	}
+	if _, err = Unflatten([]string{"A=1", "A=2"}); err != nil {
+		t.Errorf("[Unflatten] in: the same variable twice; error: %v", err)
+	}
+
+	os.Setenv("NANOENV_TEST_SERVER_PORT", "9090")
+	defer os.Unsetenv("NANOENV_TEST_SERVER_PORT")
+	v = testConfig{}
+	if err := Decode(&v, WithPrefix("nanoenv_test")); err != nil || v.Server.Port != 9090 {
+		t.Errorf("[Decode] out: %+v; error: %v", v, err)
+	}
+	if err := Decode(v); err == nil {
+		t.Errorf("[Decode] in: not a pointer; want: error")
+	}
+}
@@ -18,6 +18,23 @@ type InvalidEntityError struct {
 	Err     error
 }
 
+// SyntaxError describes a syntax error of nano data at the specified position.
+// Line and Column start from 1.
+type SyntaxError struct {
+	Context string
+	Line    int
+	Column  int
+	Err     error
+}
+
+// SchemaError describes an error that occurs when a value does not match a schema.
+// Path is the location of the value in the document like "servers[1].port".
+type SchemaError struct {
+	Context string
+	Path    string
+	Err     error
+}
+
 const (
 	ErrorFmt string = "[%s] %s"
 )
@@ -46,3 +63,29 @@ func (e *InvalidEntityError) Error() string {
 		return s
 	}
 }
+
+// Error returns a string representation of the SyntaxError.
+func (e *SyntaxError) Error() string {
+	s := fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Err.Error())
+	if len(strings.TrimSpace(e.Context)) > 0 {
+		return fmt.Sprintf(ErrorFmt, e.Context, s)
+	} else {
+		return s
+	}
+}
+
+// Error returns a string representation of the SchemaError.
+func (e *SchemaError) Error() string {
+	var s string
+	if len(strings.TrimSpace(e.Path)) > 0 {
+		s = fmt.Sprintf("%s: %s", e.Path, e.Err.Error())
+	} else {
+		s = e.Err.Error()
+	}
+
+	if len(strings.TrimSpace(e.Context)) > 0 {
+		return fmt.Sprintf(ErrorFmt, e.Context, s)
+	} else {
+		return s
+	}
+}
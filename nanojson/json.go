@@ -0,0 +1,305 @@
+package nanojson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	nanomarkup "github.com/nanomarkup/nanomarkup.go"
+	"github.com/nanomarkup/nanomarkup.go/nanocomment"
+	"github.com/nanomarkup/nanomarkup.go/nanoerror"
+	"github.com/nanomarkup/nanomarkup.go/nanostr"
+)
+
+type options struct {
+	inference bool
+	comments  bool
+	prefix    string
+	indent    string
+}
+
+// converter writes JSON reading nano tokens.
+type converter struct {
+	opts   options
+	dec    *nanomarkup.Decoder
+	out    []byte
+	frames []frame
+}
+
+// frame is an object or an array which is being written.
+type frame struct {
+	array bool
+	first bool
+}
+
+// node is a JSON value which keeps the order of object keys.
+type node struct {
+	kind   json.Delim
+	keys   []string
+	items  []*node
+	value  string
+	str    bool
+	isNull bool
+}
+
+// multiline is a value which is written as a multi-line value to keep its leading whitespaces.
+type multiline string
+
+var numberRegexp = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+func getOptions(opts []Option) options {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func (c *converter) toJSON() error {
+	t, err := c.dec.Token()
+	if err == io.EOF {
+		c.out = append(c.out, "null"...)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	// the comments of the document are stored at the beginning of the root object
+	comments := c.dec.Comments()
+	if err = c.token(t); err != nil {
+		return err
+	}
+	if _, ok := t.(nanomarkup.Delim); ok {
+		c.appendComments(comments)
+	}
+	for len(c.frames) > 0 {
+		if t, err = c.dec.Token(); err != nil {
+			return err
+		}
+		if err = c.token(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *converter) token(t nanomarkup.Token) error {
+	switch t := t.(type) {
+	case nanomarkup.Delim:
+		switch t {
+		case '{', '[':
+			c.appendComments(c.dec.Comments())
+			c.appendComma()
+			c.out = append(c.out, byte(t))
+			c.frames = append(c.frames, frame{array: t == '[', first: true})
+		case '}', ']':
+			c.appendComments(c.dec.Comments())
+			c.out = append(c.out, byte(t))
+			c.frames = c.frames[:len(c.frames)-1]
+		}
+	case nanomarkup.Key:
+		c.appendComments(c.dec.Comments())
+		c.appendComma()
+		c.out = appendString(c.out, string(t))
+		c.out = append(c.out, ':')
+		// the next token is the value of the key
+		if t, err := c.dec.Token(); err != nil {
+			return err
+		} else if d, ok := t.(nanomarkup.Delim); ok {
+			c.out = append(c.out, byte(d))
+			c.frames = append(c.frames, frame{array: d == '[', first: true})
+		} else {
+			c.appendValue(t.(string))
+			c.appendTrailing()
+		}
+	case string:
+		c.appendComments(c.dec.Comments())
+		c.appendComma()
+		c.appendValue(t)
+		c.appendTrailing()
+	}
+	return nil
+}
+
+// appendTrailing appends the trailing comment of the last value as a comment item after it.
+func (c *converter) appendTrailing() {
+	if trailing := c.dec.Trailing(); trailing != "" {
+		comments := nanocomment.Comments{}
+		comments.Add(trailing, false)
+		c.appendComments(comments)
+	}
+}
+
+// appendComma appends a comma before every item of an object or an array except the first one.
+func (c *converter) appendComma() {
+	if len(c.frames) == 0 {
+		return
+	}
+	if f := &c.frames[len(c.frames)-1]; f.first {
+		f.first = false
+	} else {
+		c.out = append(c.out, ',')
+	}
+}
+
+func (c *converter) appendValue(v string) {
+	if c.opts.inference && (v == "true" || v == "false" || numberRegexp.MatchString(v)) {
+		c.out = append(c.out, v...)
+	} else {
+		c.out = appendString(c.out, v)
+	}
+}
+
+// appendComments appends the comments as CommentKey items of the current object or array.
+func (c *converter) appendComments(comments nanocomment.Comments) {
+	if !c.opts.comments || len(c.frames) == 0 {
+		return
+	}
+	array := c.frames[len(c.frames)-1].array
+	for _, it := range comments {
+		text := strings.TrimSuffix(string(nanocomment.Marshal(nanocomment.Comments{it})), "\n")
+		if text == "" {
+			// skip a blank line
+			continue
+		}
+		c.appendComma()
+		if array {
+			c.out = append(c.out, '{')
+		}
+		c.out = appendString(c.out, CommentKey)
+		c.out = append(c.out, ':')
+		c.out = appendString(c.out, text)
+		if array {
+			c.out = append(c.out, '}')
+		}
+	}
+}
+
+func appendString(dst []byte, s string) []byte {
+	buf := bytes.Buffer{}
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return append(dst, bytes.TrimSuffix(buf.Bytes(), []byte("\n"))...)
+}
+
+// readNode reads the next JSON value.
+func readNode(dec *json.Decoder) (*node, error) {
+	t, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := t.(type) {
+	case json.Delim:
+		n := node{kind: t}
+		for dec.More() {
+			if t == '{' {
+				k, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				n.keys = append(n.keys, k.(string))
+			}
+			it, err := readNode(dec)
+			if err == io.EOF {
+				return nil, io.ErrUnexpectedEOF
+			} else if err != nil {
+				return nil, err
+			}
+			n.items = append(n.items, it)
+		}
+		// read the end of the object or the array
+		if _, err = dec.Token(); err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		} else if err != nil {
+			return nil, err
+		}
+		return &n, nil
+	case string:
+		return &node{value: t, str: true}, nil
+	case json.Number:
+		return &node{value: t.String()}, nil
+	case bool:
+		return &node{value: fmt.Sprint(t)}, nil
+	default:
+		return &node{isNull: true}, nil
+	}
+}
+
+// writeNode writes the JSON value as nano, the root CommentKey items of an object are written before the entity.
+func writeNode(enc *nanomarkup.Encoder, n *node, root bool) error {
+	switch n.kind {
+	case '{':
+		i := 0
+		if root {
+			for ; i < len(n.keys) && n.keys[i] == CommentKey; i++ {
+				if err := writeComment(enc, n.items[i]); err != nil {
+					return err
+				}
+			}
+		}
+		if err := enc.BeginEntity(); err != nil {
+			return err
+		}
+		for ; i < len(n.keys); i++ {
+			var err error
+			if n.keys[i] == CommentKey {
+				err = writeComment(enc, n.items[i])
+			} else if k := n.keys[i]; k == "" || strings.ContainsAny(k, " \t\n") {
+				err = &nanoerror.InvalidEntityError{Context: "JSON", Entity: k, Err: fmt.Errorf("a key cannot be empty or contain whitespaces")}
+			} else if err = enc.Key(k); err == nil {
+				err = writeNode(enc, n.items[i], false)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return enc.EndEntity()
+	case '[':
+		if err := enc.BeginArray(); err != nil {
+			return err
+		}
+		for _, it := range n.items {
+			var err error
+			if it.kind == '{' && len(it.keys) == 1 && it.keys[0] == CommentKey {
+				err = writeComment(enc, it.items[0])
+			} else {
+				err = writeNode(enc, it, false)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return enc.EndArray()
+	default:
+		if strings.HasPrefix(n.value, " ") || strings.HasPrefix(n.value, "\t") {
+			return enc.Encode(multiline(n.value), nil)
+		}
+		return enc.Encode(n.value, nil)
+	}
+}
+
+// writeComment writes the value of a CommentKey item as a comment.
+func writeComment(enc *nanomarkup.Encoder, n *node) error {
+	if !n.str {
+		return &nanoerror.InvalidEntityError{Context: "JSON", Entity: CommentKey, Err: fmt.Errorf("a comment must be a string")}
+	}
+	c := nanocomment.Comments{}
+	text := n.value
+	if strings.HasPrefix(text, nanocomment.MultilineCommentBegOpCode) && strings.HasSuffix(text, nanocomment.MultilineCommentEndOpCode) && len(text) > 3 {
+		c.Add(text[2:len(text)-2], true)
+	} else if strings.HasPrefix(text, nanocomment.SingleCommentOpCode) && !strings.Contains(text, "\n") {
+		c.Add(text[2:], false)
+	} else if strings.Contains(text, "\n") {
+		c.Add(" "+text+" ", true)
+	} else {
+		c.Add(" "+text, false)
+	}
+	return enc.Comments(c)
+}
+
+func (m multiline) AppendNano(dst []byte) ([]byte, error) {
+	return nanostr.AppendMultiline(dst, string(m)), nil
+}
@@ -0,0 +1,100 @@
+package nanojson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	nanomarkup "github.com/nanomarkup/nanomarkup.go"
+	"github.com/nanomarkup/nanomarkup.go/nanoerror"
+)
+
+// Option configures the conversion.
+type Option func(*options)
+
+// CommentKey is the key of an object which holds a nano comment in JSON.
+// In an array a comment is stored as an object with the only CommentKey key.
+const CommentKey string = "//"
+
+// ToJSON converts the nano-encoded data to JSON.
+//
+// Entities are converted to objects keeping the order of keys, arrays to arrays
+// and values to strings. An empty document is converted to null.
+func ToJSON(data []byte, opts ...Option) ([]byte, error) {
+	o := getOptions(opts)
+	if errs := nanomarkup.Validate(data); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	c := converter{opts: o, dec: nanomarkup.NewDecoder(bytes.NewReader(data))}
+	if err := c.toJSON(); err != nil {
+		return nil, err
+	}
+	if o.indent == "" && o.prefix == "" {
+		return c.out, nil
+	}
+	out := bytes.Buffer{}
+	if err := json.Indent(&out, c.out, o.prefix, o.indent); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// FromJSON converts the JSON-encoded data to nano.
+//
+// Objects are converted to entities keeping the order of keys, arrays to arrays
+// and other values to scalars, null is converted to an empty value.
+// Strings which begin with whitespaces are written as multi-line values to keep them.
+// A nano key cannot be empty or contain whitespaces, such keys are reported as an error.
+// The CommentKey items are converted to comments, the items at the beginning
+// of the root object are written before the entity.
+func FromJSON(data []byte, opts ...Option) ([]byte, error) {
+	o := getOptions(opts)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	n, err := readNode(dec)
+	if err == io.EOF {
+		return []byte{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if _, err = dec.Token(); err != io.EOF {
+		return nil, &nanoerror.InvalidEntityError{Context: "JSON", Entity: "", Err: fmt.Errorf("unexpected data after the top-level value")}
+	}
+	out := bytes.Buffer{}
+	enc := nanomarkup.NewEncoder(&out)
+	enc.SetIndent(o.prefix, o.indent)
+	if err = writeNode(enc, n, true); err != nil {
+		return nil, err
+	}
+	if err = enc.Flush(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// WithInference instructs ToJSON to convert values which look like numbers
+// or booleans to JSON numbers and booleans instead of strings.
+func WithInference() Option {
+	return func(o *options) {
+		o.inference = true
+	}
+}
+
+// WithComments instructs ToJSON to keep nano comments as CommentKey items.
+// The comments before the root value are stored at the beginning of the root object or array.
+func WithComments() Option {
+	return func(o *options) {
+		o.comments = true
+	}
+}
+
+// WithIndent instructs the conversion to indent the output
+// like the Indent function of the json or nanomarkup package.
+func WithIndent(prefix, indent string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+		o.indent = indent
+	}
+}
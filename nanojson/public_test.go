@@ -0,0 +1,96 @@
+package nanojson
+
+import (
+	"testing"
+)
+
+const testNano = "// Service configuration\n{\nname api\n// A port of the service\nport 8080\ndebug false\nratio 0.5\nversion 1.10\nmotd `\nhello\n  world\n`\nservers [\n// the main server\nlocalhost\n{\nhost example.com\n}\n]\nlabels {\n}\nempty \n}\n"
+
+func TestToJSON(t *testing.T) {
+	testCases := []struct {
+		v    string
+		opts []Option
+		want string
+	}{
+		{v: "", want: "null"},
+		{v: "hello world", want: `"hello world"`},
+		{v: testNano, want: `{"name":"api","port":"8080","debug":"false","ratio":"0.5","version":"1.10","motd":"hello\n  world","servers":["localhost",{"host":"example.com"}],"labels":{},"empty":""}`},
+		{v: testNano, opts: []Option{WithInference()}, want: `{"name":"api","port":8080,"debug":false,"ratio":0.5,"version":1.10,"motd":"hello\n  world","servers":["localhost",{"host":"example.com"}],"labels":{},"empty":""}`},
+		{v: testNano, opts: []Option{WithComments()}, want: `{"//":"// Service configuration","name":"api","//":"// A port of the service","port":"8080","debug":"false","ratio":"0.5","version":"1.10","motd":"hello\n  world","servers":[{"//":"// the main server"},"localhost",{"host":"example.com"}],"labels":{},"empty":""}`},
+		{v: "[\n1\n/* last\nitem */\n]\n", opts: []Option{WithComments(), WithInference()}, want: `[1,{"//":"/* last\nitem */"}]`},
+		{v: "{\nport 8080 // the port\nhosts [\na // the first\n]\n}\n", opts: []Option{WithComments()}, want: `{"port":"8080","//":"// the port","hosts":["a",{"//":"// the first"}]}`},
+		{v: "{\nport 8080 // the port\n}\n", want: `{"port":"8080"}`},
+		{v: "{\nname // the name\nport 8080\n}\n", opts: []Option{WithComments()}, want: `{"name":"","//":"// the name","port":"8080"}`},
+		{v: "{\nkey <a&b>\n}\n", opts: []Option{WithIndent("", "  ")}, want: "{\n  \"key\": \"<a&b>\"\n}"},
+	}
+
+	for _, item := range testCases {
+		out, err := ToJSON([]byte(item.v), item.opts...)
+		if err != nil {
+			t.Errorf("[ToJSON] in: %s; error: %s", item.v, err)
+		} else if string(out) != item.want {
+			t.Errorf("[ToJSON] in: %s; out: %s; want: %s", item.v, out, item.want)
+		}
+	}
+
+	if _, err := ToJSON([]byte("{\nkey value\n")); err == nil {
+		t.Errorf("[ToJSON] in: invalid data; want: error")
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	testCases := []struct {
+		v    string
+		want string
+	}{
+		{v: "", want: ""},
+		{v: `"hello world"`, want: "hello world\n"},
+		{v: `{"b":1,"a":[true,null,{"x":"1\n2"}],"c":{},"d":[]}`, want: "{\nb 1\na [\ntrue\n\n{\nx `\n1\n2\n`\n}\n]\nc {\n}\nd [\n]\n}\n"},
+		{v: `{"//":"head","//":"// name","name":"[x]","list":[{"//":"/* item */"},1],"//":"tail\ntext"}`, want: "// head\n// name\n{\nname `\n[x]\n`\nlist [\n/* item */\n1\n]\n/* tail\ntext */\n}\n"},
+		{v: `{"a":" b"}`, want: "{\na `\n b\n`\n}\n"},
+	}
+
+	for _, item := range testCases {
+		out, err := FromJSON([]byte(item.v))
+		if err != nil {
+			t.Errorf("[FromJSON] in: %s; error: %s", item.v, err)
+		} else if string(out) != item.want {
+			t.Errorf("[FromJSON] in: %s; out: %q; want: %q", item.v, out, item.want)
+		}
+	}
+
+	invalid := []string{`{"a":1} 2`, `{"a b":1}`, `{"":1}`, `{"a\tb":1}`, `{"//":1}`, `{"a":`}
+	for _, item := range invalid {
+		if _, err := FromJSON([]byte(item)); err == nil {
+			t.Errorf("[FromJSON] in: %s; want: error", item)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, item := range []string{`{"x":"` + "`" + `"}`, `{"x":"` + "`x" + `"}`, `{"x":"a\n` + "`" + `\nb"}`, `{"x":"  lead","y":["\tb","c  "]}`} {
+		in, err := FromJSON([]byte(item))
+		if err != nil {
+			t.Fatalf("[FromJSON] in: %s; error: %s", item, err)
+		}
+		out, err := ToJSON(in)
+		if err != nil {
+			t.Fatalf("[ToJSON] in: %q; error: %s", in, err)
+		}
+		if string(out) != item {
+			t.Errorf("[RoundTrip] in: %s; out: %s", item, out)
+		}
+	}
+
+	js, err := ToJSON([]byte(testNano), WithComments())
+	if err != nil {
+		t.Fatalf("[ToJSON] %s", err)
+	}
+	out, err := FromJSON(js)
+	if err != nil {
+		t.Fatalf("[FromJSON] %s", err)
+	}
+	if string(out) != testNano {
+		t.Errorf("[RoundTrip] in: %s; out: %s", testNano, out)
+	}
+}
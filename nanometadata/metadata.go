@@ -1,9 +1,18 @@
 package nanometadata
 
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
 func (m *Metadata) AddField(name string, data *Metadata) {
 	if m.fields == nil {
 		m.fields = map[string]*Metadata{}
 	}
+	if _, ok := m.fields[name]; !ok {
+		m.order = append(m.order, name)
+	}
 	m.fields[name] = data
 }
 
@@ -14,3 +23,140 @@ func (m *Metadata) GetField(name string) *Metadata {
 		return m.fields[name]
 	}
 }
+
+// SetKey records the key of the field in the encoded data if it differs from the name,
+// such as the name of a nano tag, so Lookup finds the field by both of them.
+func (m *Metadata) SetKey(name, key string) {
+	if name == key {
+		return
+	}
+	if m.keys == nil {
+		m.keys = map[string]string{}
+	}
+	m.keys[key] = name
+}
+
+func (m *Metadata) AddItem(index int, data *Metadata) {
+	if m.items == nil {
+		m.items = map[int]*Metadata{}
+	}
+	m.items[index] = data
+}
+
+func (m *Metadata) GetItem(index int) *Metadata {
+	if m.items == nil {
+		return nil
+	} else {
+		return m.items[index]
+	}
+}
+
+// SetElem sets the metadata which is shared by the items of an array, a slice or a map,
+// for example the comments of the fields of the element type.
+func (m *Metadata) SetElem(data *Metadata) {
+	m.elem = data
+}
+
+// Elem returns the metadata set by SetElem or nil if it is not set.
+func (m *Metadata) Elem() *Metadata {
+	return m.elem
+}
+
+// RemoveField removes the metadata of the field.
+func (m *Metadata) RemoveField(name string) {
+	if _, ok := m.fields[name]; !ok {
+		return
+	}
+	delete(m.fields, name)
+	for k, v := range m.keys {
+		if v == name {
+			delete(m.keys, k)
+		}
+	}
+	for i, v := range m.order {
+		if v == name {
+			m.order = append(m.order[:i], m.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// RemoveItem removes the metadata of the item.
+func (m *Metadata) RemoveItem(index int) {
+	delete(m.items, index)
+}
+
+// Fields returns the names of fields in the order they were added,
+// which is the document order for the metadata filled by Unmarshal.
+func (m *Metadata) Fields() []string {
+	return append([]string{}, m.order...)
+}
+
+// Items returns the indexes of items in ascending order.
+func (m *Metadata) Items() []int {
+	out := make([]int, 0, len(m.items))
+	for i := range m.items {
+		out = append(out, i)
+	}
+	sort.Ints(out)
+	return out
+}
+
+// Lookup returns the metadata at the path or nil if it is not found.
+// The path consists of field names or keys set by SetKey separated by dots, an item is addressed by an index
+// such as "servers[1].host" or "servers.1.host", and a field with special characters is
+// enclosed in brackets and quotes such as "labels['app.name']".
+func (m *Metadata) Lookup(path string) *Metadata {
+	curr := m
+	for path != "" && curr != nil {
+		var name string
+		quoted := false
+		switch {
+		case path[0] == 46: // .
+			path = path[1:]
+			continue
+		case strings.HasPrefix(path, "['"):
+			end := strings.Index(path[2:], "']")
+			if end < 0 {
+				return nil
+			}
+			name = path[2 : end+2]
+			path = path[end+4:]
+			quoted = true
+		case path[0] == 91: // [
+			end := strings.IndexByte(path, 93) // ]
+			if end < 0 {
+				return nil
+			}
+			name = path[1:end]
+			path = path[end+1:]
+			if _, err := strconv.Atoi(name); err != nil {
+				return nil
+			}
+		default:
+			end := strings.IndexAny(path, ".[")
+			if end < 0 {
+				end = len(path)
+			}
+			name = path[:end]
+			path = path[end:]
+		}
+		curr = curr.child(name, quoted)
+	}
+	return curr
+}
+
+// child returns the metadata of the field or the item by the name.
+func (m *Metadata) child(name string, quoted bool) *Metadata {
+	if f := m.GetField(name); f != nil {
+		return f
+	} else if field, ok := m.keys[name]; ok {
+		return m.GetField(field)
+	} else if quoted {
+		return nil
+	}
+	if i, err := strconv.Atoi(name); err == nil {
+		return m.GetItem(i)
+	}
+	return nil
+}
@@ -2,9 +2,21 @@ package nanometadata
 
 import "github.com/nanomarkup/nanomarkup.go/nanocomment"
 
+// Metadata holds the comments of a value and the metadata of its items.
+// The items of a struct are addressed by the names of fields, the items of a map
+// by the encoded keys and the items of an array or a slice by the indexes.
+// Lookup also finds the fields by the keys of nano tags recorded by SetKey.
+// The metadata set by SetElem is used by Marshal for the items of an array,
+// a slice or a map which do not have their own metadata.
+// Trailing is the text of a comment after a single-line value, it is empty if there is no comment.
 type Metadata struct {
 	fields   map[string]*Metadata
+	keys     map[string]string
+	elem     *Metadata
+	order    []string
+	items    map[int]*Metadata
 	Comments nanocomment.Comments
+	Trailing string
 }
 
 func CreateMetadata(comment string, multiline bool) *Metadata {
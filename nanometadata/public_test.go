@@ -0,0 +1,90 @@
+package nanometadata
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFields(t *testing.T) {
+	m := Metadata{}
+	m.AddField("b", CreateMetadata(" b", false))
+	m.AddField("a", CreateMetadata(" a", false))
+	m.AddField("c", CreateMetadata(" c", false))
+	m.AddField("b", CreateMetadata(" b2", false))
+	if out, want := m.Fields(), []string{"b", "a", "c"}; !reflect.DeepEqual(out, want) {
+		t.Errorf("[Fields] out: %v, want: %v", out, want)
+	}
+	if out := m.GetField("b").Comments.String(); out != " b2" {
+		t.Errorf("[GetField] out: %q, want: %q", out, " b2")
+	}
+	m.RemoveField("a")
+	if out, want := m.Fields(), []string{"b", "c"}; !reflect.DeepEqual(out, want) {
+		t.Errorf("[RemoveField] out: %v, want: %v", out, want)
+	}
+	m.AddItem(2, CreateMetadata(" 2", false))
+	m.AddItem(0, CreateMetadata(" 0", false))
+	if out, want := m.Items(), []int{0, 2}; !reflect.DeepEqual(out, want) {
+		t.Errorf("[Items] out: %v, want: %v", out, want)
+	}
+	m.RemoveItem(0)
+	if out, want := m.Items(), []int{2}; !reflect.DeepEqual(out, want) {
+		t.Errorf("[RemoveItem] out: %v, want: %v", out, want)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	tls := CreateMetadata(" tls", false)
+	host := CreateMetadata(" host", false)
+	second := CreateMetadata(" second", false)
+	second.AddField("host", host)
+	servers := Metadata{}
+	servers.AddItem(1, second)
+	server := Metadata{}
+	server.AddField("tls", tls)
+	labels := Metadata{}
+	labels.AddField("app.name", CreateMetadata(" app", false))
+	labels.AddField("1", CreateMetadata(" one", false))
+	m := Metadata{}
+	m.AddField("server", &server)
+	m.AddField("servers", &servers)
+	m.AddField("labels", &labels)
+	m.AddField("Backup", CreateMetadata(" backup", false))
+	m.SetKey("Backup", "backup")
+	tests := []struct {
+		path string
+		want *Metadata
+	}{
+		{"", &m},
+		{"server.tls", tls},
+		{"servers[1]", second},
+		{"servers[1].host", host},
+		{"servers.1.host", host},
+		{"labels['app.name']", labels.GetField("app.name")},
+		{"labels.1", labels.GetField("1")},
+		{"labels[1]", labels.GetField("1")},
+		{"backup", m.GetField("Backup")},
+		{"Backup", m.GetField("Backup")},
+		{"['backup']", m.GetField("Backup")},
+		{"server.port", nil},
+		{"servers[0]", nil},
+		{"servers[x]", nil},
+		{"labels['app", nil},
+	}
+	for _, tt := range tests {
+		if out := m.Lookup(tt.path); out != tt.want {
+			t.Errorf("[Lookup] %q out: %v, want: %v", tt.path, out, tt.want)
+		}
+	}
+}
+
+func TestElem(t *testing.T) {
+	m := Metadata{}
+	if m.Elem() != nil {
+		t.Errorf("[Elem] out: %v, want: nil", m.Elem())
+	}
+	elem := CreateMetadata(" item", false)
+	m.SetElem(elem)
+	if m.Elem() != elem || m.GetItem(0) != nil {
+		t.Errorf("[Elem] out: %v, want: %v", m.Elem(), elem)
+	}
+}
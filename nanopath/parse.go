@@ -0,0 +1,178 @@
+package nanopath
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+
+	nanomarkup "github.com/nanomarkup/nanomarkup.go"
+	"github.com/nanomarkup/nanomarkup.go/nanodecoder"
+	"github.com/nanomarkup/nanomarkup.go/nanostr"
+)
+
+// parser reads the values of nano data line by line keeping their positions.
+type parser struct {
+	lines [][]byte
+	index int
+}
+
+func parse(data []byte) (*Node, error) {
+	if errs := nanomarkup.Validate(data); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	p := parser{lines: bytes.Split(data, []byte("\n")), index: -1}
+	item, col, ok := p.next()
+	if !ok {
+		return nil, nil
+	}
+	return p.value(item, col)
+}
+
+// next returns the next item skipping blank lines and comments, col is the column of the item.
+func (p *parser) next() ([]byte, int, bool) {
+	for p.index++; p.index < len(p.lines); p.index++ {
+		line := p.lines[p.index]
+		item := bytes.TrimLeft(line, " \t")
+		if len(item) == 0 || p.skipComment(item) {
+			continue
+		}
+		return item, len(line) - len(item) + 1, true
+	}
+	return nil, 0, false
+}
+
+// skipComment skips a single or multi-line comment.
+// It returns false if the item is not a comment.
+func (p *parser) skipComment(item []byte) bool {
+	if len(item) < 2 || item[0] != 47 || (item[1] != 47 && item[1] != 42) { // /, *
+		return false
+	}
+	if item[1] == 47 {
+		return true
+	}
+	// check MultilineCommentEndOpCode
+	if b := bytes.TrimRight(item[2:], " "); len(b) > 1 && bytes.HasSuffix(b, []byte("*/")) {
+		return true
+	}
+	for p.index++; p.index < len(p.lines); p.index++ {
+		if bytes.HasSuffix(bytes.TrimRight(p.lines[p.index], " "), []byte("*/")) {
+			break
+		}
+	}
+	return true
+}
+
+// value reads the value which begins with the item.
+func (p *parser) value(item []byte, col int) (*Node, error) {
+	n := &Node{Line: p.index + 1, Column: col}
+	val := bytes.TrimRight(item, " \t")
+	if len(val) == 1 {
+		switch val[0] {
+		case 91: // [
+			n.Kind = Array
+			return n, p.items(n)
+		case 123: // {
+			n.Kind = Entity
+			return n, p.items(n)
+		case 96: // `
+			start := p.index
+			for p.index++; p.index < len(p.lines); p.index++ {
+				if line := p.lines[p.index]; len(line) == 1 && line[0] == 96 { // `
+					break
+				}
+			}
+			// decode the content in the same way as the decoder
+			d := nanodecoder.Decoder{}
+			d.Init(p.lines[start+1 : p.index+1])
+			s, err := nanostr.Unmarshal(&d, item)
+			if err != nil {
+				return nil, err
+			}
+			n.Value = string(s)
+			return n, nil
+		}
+	}
+	n.Value = string(nanostr.Value(item))
+	return n, nil
+}
+
+// items reads the items of the entity or the array up to the closing bracket.
+func (p *parser) items(n *Node) error {
+	for {
+		item, col, ok := p.next()
+		if !ok {
+			// the data is validated, so it cannot happen
+			return errors.New("the closing bracket is missing")
+		}
+		val := bytes.TrimRight(item, " \t")
+		if len(val) == 1 && (val[0] == 93 || val[0] == 125) { // ], }
+			return nil
+		}
+		key := []byte{}
+		if n.Kind == Entity {
+			// the position of an entity item is the position of its key
+			key, item = item, []byte{}
+			if space := bytes.IndexByte(key, 32); space > 0 { // space
+				key, item = key[:space], bytes.TrimLeft(key[space+1:], " \t")
+			}
+		}
+		it, err := p.value(item, col)
+		if err != nil {
+			return err
+		}
+		it.Key = string(key)
+		n.Items = append(n.Items, it)
+	}
+}
+
+// get returns the item of an entity by the key or the item of an array by the index.
+func (n *Node) get(key string) *Node {
+	switch n.Kind {
+	case Entity:
+		for _, it := range n.Items {
+			if it.Key == key {
+				return it
+			}
+		}
+	case Array:
+		if i, err := strconv.Atoi(key); err == nil {
+			if i < 0 {
+				i += len(n.Items)
+			}
+			if i >= 0 && i < len(n.Items) {
+				return n.Items[i]
+			}
+		}
+	}
+	return nil
+}
+
+// write writes the value using the encoder.
+func (n *Node) write(enc *nanomarkup.Encoder) error {
+	var err error
+	switch n.Kind {
+	case Entity:
+		err = enc.BeginEntity()
+	case Array:
+		err = enc.BeginArray()
+	default:
+		return enc.Encode(n.Value, nil)
+	}
+	if err != nil {
+		return err
+	}
+	for _, it := range n.Items {
+		if n.Kind == Entity {
+			if err = enc.Key(it.Key); err != nil {
+				return err
+			}
+		}
+		if err = it.write(enc); err != nil {
+			return err
+		}
+	}
+	if n.Kind == Entity {
+		return enc.EndEntity()
+	}
+	return enc.EndArray()
+}
@@ -0,0 +1,322 @@
+package nanopath
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nanomarkup/nanomarkup.go/nanoerror"
+)
+
+type segmentKind int
+
+const (
+	keySegment segmentKind = iota
+	indexSegment
+	wildcardSegment
+	filterSegment
+)
+
+// segment is a step of a path.
+type segment struct {
+	kind  segmentKind
+	key   string
+	index int
+	// the segment is applied to the value and all nested values
+	recursive bool
+	// the nested value of a filter, the operator is empty if the value must exist
+	filter *Path
+	op     string
+	value  string
+}
+
+// compiler parses the source text of a path.
+type compiler struct {
+	expr string
+	pos  int
+}
+
+func compile(expr string) (*Path, error) {
+	c := compiler{expr: expr}
+	p := &Path{expr: expr}
+	if strings.HasPrefix(expr, "$") {
+		c.pos = 1
+	}
+	for c.pos < len(c.expr) {
+		s := segment{}
+		dot := false
+		if strings.HasPrefix(c.expr[c.pos:], "..") {
+			c.pos += 2
+			s.recursive = true
+		} else if c.expr[c.pos] == '.' {
+			c.pos++
+			dot = true
+		}
+		var err error
+		if c.pos < len(c.expr) && c.expr[c.pos] == '[' {
+			err = c.bracket(&s)
+		} else if s.recursive || dot || len(p.segments) == 0 {
+			err = c.name(&s)
+		} else {
+			err = c.errorf("unexpected %q", c.expr[c.pos])
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.segments = append(p.segments, s)
+	}
+	return p, nil
+}
+
+// name parses a key or a wildcard.
+func (c *compiler) name(s *segment) error {
+	end := strings.IndexAny(c.expr[c.pos:], ".[]")
+	if end < 0 {
+		end = len(c.expr) - c.pos
+	}
+	name := c.expr[c.pos : c.pos+end]
+	if name == "" {
+		return c.errorf("a key is missing")
+	}
+	c.pos += end
+	if name == "*" {
+		s.kind = wildcardSegment
+	} else {
+		s.kind = keySegment
+		s.key = name
+	}
+	return nil
+}
+
+// bracket parses a quoted key, an index, a wildcard or a filter in brackets.
+func (c *compiler) bracket(s *segment) error {
+	c.pos++ // [
+	rest := c.expr[c.pos:]
+	switch {
+	case strings.HasPrefix(rest, "*]"):
+		s.kind = wildcardSegment
+		c.pos += 2
+		return nil
+	case strings.HasPrefix(rest, "'") || strings.HasPrefix(rest, "\""):
+		key, err := c.quoted()
+		if err != nil {
+			return err
+		}
+		s.kind = keySegment
+		s.key = key
+		return c.close()
+	case strings.HasPrefix(rest, "?"):
+		c.pos++
+		return c.filter(s)
+	}
+	end := strings.IndexByte(rest, ']')
+	if end < 0 {
+		return c.errorf("']' is missing")
+	}
+	i, err := strconv.Atoi(strings.TrimSpace(rest[:end]))
+	if err != nil {
+		return c.errorf("invalid index %q", rest[:end])
+	}
+	s.kind = indexSegment
+	s.index = i
+	c.pos += end + 1
+	return nil
+}
+
+// filter parses a filter like "?name==api]" after the question mark.
+func (c *compiler) filter(s *segment) error {
+	rest := c.expr[c.pos:]
+	end := strings.IndexAny(rest, "=!]")
+	if end < 0 {
+		return c.errorf("']' is missing")
+	}
+	sub, err := compile(strings.TrimSpace(rest[:end]))
+	if err != nil {
+		return err
+	} else if len(sub.segments) == 0 {
+		return c.errorf("a path of the filter is missing")
+	}
+	s.kind = filterSegment
+	s.filter = sub
+	c.pos += end
+	if c.expr[c.pos] == ']' {
+		c.pos++
+		return nil
+	}
+	if op := c.expr[c.pos:min(c.pos+2, len(c.expr))]; op == "==" || op == "!=" {
+		s.op = op
+		c.pos += 2
+	} else {
+		return c.errorf("unknown operator %q", op)
+	}
+	for c.pos < len(c.expr) && c.expr[c.pos] == ' ' {
+		c.pos++
+	}
+	if c.pos < len(c.expr) && (c.expr[c.pos] == '\'' || c.expr[c.pos] == '"') {
+		if s.value, err = c.quoted(); err != nil {
+			return err
+		}
+		return c.close()
+	}
+	end = strings.IndexByte(c.expr[c.pos:], ']')
+	if end < 0 {
+		return c.errorf("']' is missing")
+	}
+	s.value = strings.TrimSpace(c.expr[c.pos : c.pos+end])
+	c.pos += end + 1
+	return nil
+}
+
+// quoted parses a string in single or double quotes.
+func (c *compiler) quoted() (string, error) {
+	quote := c.expr[c.pos]
+	end := strings.IndexByte(c.expr[c.pos+1:], quote)
+	if end < 0 {
+		return "", c.errorf("%q is missing", quote)
+	}
+	s := c.expr[c.pos+1 : c.pos+1+end]
+	c.pos += end + 2
+	return s, nil
+}
+
+// close skips the closing bracket.
+func (c *compiler) close() error {
+	for c.pos < len(c.expr) && c.expr[c.pos] == ' ' {
+		c.pos++
+	}
+	if c.pos >= len(c.expr) || c.expr[c.pos] != ']' {
+		return c.errorf("']' is missing")
+	}
+	c.pos++
+	return nil
+}
+
+func (c *compiler) errorf(format string, a ...any) error {
+	return &nanoerror.InvalidArgumentError{Context: "Compile", Err: fmt.Errorf("invalid path %q at %d: %s", c.expr, c.pos+1, fmt.Sprintf(format, a...))}
+}
+
+func (p *Path) keys() ([]string, bool) {
+	res := make([]string, 0, len(p.segments))
+	for _, s := range p.segments {
+		switch {
+		case s.recursive:
+			return nil, false
+		case s.kind == keySegment:
+			res = append(res, s.key)
+		case s.kind == indexSegment:
+			res = append(res, strconv.Itoa(s.index))
+		default:
+			return nil, false
+		}
+	}
+	return res, true
+}
+
+// eval applies the segments of the path to the values.
+func (p *Path) eval(values []Match) []Match {
+	for _, s := range p.segments {
+		next := []Match{}
+		for _, m := range values {
+			if s.recursive {
+				next = s.descend(next, m)
+			} else {
+				next = s.apply(next, m)
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+// apply appends the values which are selected by the segment from the value.
+func (s *segment) apply(dst []Match, m Match) []Match {
+	n := m.Node
+	switch s.kind {
+	case keySegment:
+		if n.Kind == Array {
+			if i, err := strconv.Atoi(s.key); err == nil {
+				dst = appendIndex(dst, m, i)
+			}
+			return dst
+		}
+		for i, it := range n.Items {
+			if n.Kind == Entity && it.Key == s.key {
+				dst = append(dst, child(m, i))
+			}
+		}
+	case indexSegment:
+		if n.Kind == Array {
+			dst = appendIndex(dst, m, s.index)
+		}
+	case wildcardSegment:
+		for i := range n.Items {
+			dst = append(dst, child(m, i))
+		}
+	case filterSegment:
+		for i := range n.Items {
+			if c := child(m, i); s.match(c.Node) {
+				dst = append(dst, c)
+			}
+		}
+	}
+	return dst
+}
+
+// appendIndex appends the item of the array by the index, a negative index counts from the end.
+func appendIndex(dst []Match, m Match, i int) []Match {
+	if i < 0 {
+		i += len(m.Node.Items)
+	}
+	if i >= 0 && i < len(m.Node.Items) {
+		dst = append(dst, child(m, i))
+	}
+	return dst
+}
+
+// match reports whether the value passes the filter.
+func (s *segment) match(n *Node) bool {
+	values := s.filter.eval([]Match{{Node: n}})
+	if s.op == "" {
+		return len(values) > 0
+	}
+	equal := false
+	for _, v := range values {
+		if v.Node.Kind == Scalar && v.Node.Value == s.value {
+			equal = true
+			break
+		}
+	}
+	return equal == (s.op == "==")
+}
+
+// child returns the item of the value with its path.
+func child(m Match, i int) Match {
+	it := m.Node.Items[i]
+	path := m.Path
+	switch {
+	case m.Node.Kind == Array:
+		path += "[" + strconv.Itoa(i) + "]"
+	case it.Key == "" || strings.ContainsAny(it.Key, ".[]'\"*?$"):
+		path += "['" + it.Key + "']"
+	case path == "":
+		path = it.Key
+	default:
+		path += "." + it.Key
+	}
+	return Match{Path: path, Node: it}
+}
+
+// descend appends the items which are selected by the segment from the value and all nested values
+// in the document order, so the selected items of a value go before the items of their nested values.
+func (s *segment) descend(dst []Match, m Match) []Match {
+	// the segments select the items of a value in the order of the items
+	selected := s.apply(nil, m)
+	for i, it := range m.Node.Items {
+		if len(selected) > 0 && selected[0].Node == it {
+			dst = append(dst, selected[0])
+			selected = selected[1:]
+		}
+		dst = s.descend(dst, child(m, i))
+	}
+	return dst
+}
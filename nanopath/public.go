@@ -0,0 +1,137 @@
+// Package nanopath selects values of nano documents by paths like "servers[1].tls.cert".
+//
+// A path consists of the following segments:
+//
+//	key, .key     an item of an entity, a number also selects an item of an array
+//	['key']       an item of an entity which key contains special characters
+//	[2], [-1]     an item of an array, a negative index counts from the end
+//	*, [*]        all items of an entity or an array
+//	..key, ..*    the items of the value and all nested values (recursive descent)
+//	[?name==api]  the items which have the nested value equal to the value,
+//	              != selects the other items and [?name] the items where the value exists
+//
+// An empty path or "$" selects the whole document.
+package nanopath
+
+import (
+	"fmt"
+
+	nanomarkup "github.com/nanomarkup/nanomarkup.go"
+	"github.com/nanomarkup/nanomarkup.go/nanoerror"
+)
+
+// Kind is a kind of a value.
+type Kind int
+
+const (
+	Scalar Kind = iota
+	Entity
+	Array
+)
+
+// Node is a decoded value of a nano document with its position.
+type Node struct {
+	Kind Kind
+	// Key is the key of an entity item, it is empty for array items and the document.
+	Key string
+	// Value is the value of a scalar or a multi-line value.
+	Value string
+	// Items are the items of an entity or an array in the document order.
+	Items []*Node
+	// Line and Column are the position of the key of an entity item or the position of a value, they start from 1.
+	Line   int
+	Column int
+}
+
+// Path is a compiled path.
+type Path struct {
+	expr     string
+	segments []segment
+}
+
+// Match is a value which matches a path.
+type Match struct {
+	// Path is the location of the value like "servers[1].tls.cert".
+	Path string
+	Node *Node
+}
+
+// Parse parses the nano-encoded data into a tree of nodes.
+// It returns nil if the data does not contain a value.
+func Parse(data []byte) (*Node, error) {
+	return parse(data)
+}
+
+// Compile parses the path.
+func Compile(expr string) (*Path, error) {
+	return compile(expr)
+}
+
+// MustCompile is like Compile but panics if the path cannot be parsed.
+func MustCompile(expr string) *Path {
+	p, err := compile(expr)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// String returns the source text of the path.
+func (p *Path) String() string {
+	return p.expr
+}
+
+// Keys returns the keys of a path which consists of keys and indexes only, the indexes are
+// returned as numbers like "-1". It returns false if the path contains wildcards, filters
+// or recursive descents.
+func (p *Path) Keys() ([]string, bool) {
+	return p.keys()
+}
+
+// Select returns the values of the tree which match the path in the document order.
+func (p *Path) Select(root *Node) []Match {
+	if root == nil {
+		return nil
+	}
+	return p.eval([]Match{{Path: "", Node: root}})
+}
+
+// Select returns the values of the nano-encoded data which match the path in the document order.
+func Select(data []byte, expr string) ([]Match, error) {
+	p, err := compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	root, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return p.Select(root), nil
+}
+
+// DecodePath decodes the first value of the nano-encoded data which matches the path
+// and stores the result in v. See nanomarkup.Unmarshal for details about the conversion.
+func DecodePath(data []byte, expr string, v any) error {
+	matches, err := Select(data, expr)
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		return &nanoerror.InvalidEntityError{Context: "DecodePath", Entity: expr, Err: fmt.Errorf("no value matches the path")}
+	}
+	sub, err := nanomarkup.Marshal(matches[0].Node, nil)
+	if err != nil {
+		return err
+	}
+	return nanomarkup.Unmarshal(sub, v, nil)
+}
+
+// Get returns the item of an entity by the key or the item of an array by the index, nil if it is not found.
+func (n *Node) Get(key string) *Node {
+	return n.get(key)
+}
+
+// MarshalNanoTo writes the value without its key.
+func (n *Node) MarshalNanoTo(enc *nanomarkup.Encoder) error {
+	return n.write(enc)
+}
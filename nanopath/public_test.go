@@ -0,0 +1,157 @@
+package nanopath
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testDoc = `// services
+{
+	name demo
+	servers [
+		{
+			name api
+			port 8080 // the default port
+		}
+		{
+			name web
+			port 80
+			tls {
+				cert web.pem
+			}
+		}
+	]
+	notes ` + "`" + `
+first
+second
+` + "`" + `
+	a.b dotted
+}
+`
+
+func TestParse(t *testing.T) {
+	root, err := Parse([]byte(testDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Kind != Entity || root.Line != 2 || root.Column != 1 || len(root.Items) != 4 {
+		t.Errorf("[Parse] root: %+v", root)
+	}
+	if n := root.Get("servers").Get("1").Get("tls"); n == nil || n.Kind != Entity || n.Line != 12 || n.Column != 4 {
+		t.Errorf("[Parse] tls: %+v", n)
+	}
+	if n := root.Get("notes"); n == nil || n.Value != "first\nsecond" || n.Line != 17 || n.Column != 2 {
+		t.Errorf("[Parse] notes: %+v", n)
+	}
+	if n := root.Get("servers").Get("0").Get("port"); n == nil || n.Value != "8080" {
+		t.Errorf("[Parse] port: %+v", n)
+	}
+	if n := root.Get("servers").Get("-1"); n == nil || n.Line != 9 {
+		t.Errorf("[Parse] servers[-1]: %+v", n)
+	}
+	if root, err = Parse([]byte("// comment\n")); err != nil || root != nil {
+		t.Errorf("[Parse] root: %+v; error: %v", root, err)
+	}
+	if _, err = Parse([]byte("{\nkey value\n")); err == nil {
+		t.Errorf("[Parse] want: error")
+	}
+}
+
+func TestSelect(t *testing.T) {
+	testCases := []struct {
+		path  string
+		paths []string
+		value string
+	}{
+		{"", []string{""}, ""},
+		{"$.name", []string{"name"}, "demo"},
+		{"servers[1].tls.cert", []string{"servers[1].tls.cert"}, "web.pem"},
+		{"servers.1.name", []string{"servers[1].name"}, "web"},
+		{"servers[-1].port", []string{"servers[1].port"}, "80"},
+		{"servers[*].name", []string{"servers[0].name", "servers[1].name"}, "api"},
+		{"servers.*.port", []string{"servers[0].port", "servers[1].port"}, "8080"},
+		{"..name", []string{"name", "servers[0].name", "servers[1].name"}, "demo"},
+		{"$..cert", []string{"servers[1].tls.cert"}, "web.pem"},
+		{"servers..*", []string{"servers[0]", "servers[0].name", "servers[0].port", "servers[1]", "servers[1].name", "servers[1].port", "servers[1].tls", "servers[1].tls.cert"}, ""},
+		{"servers..[-1]", []string{"servers[1]"}, ""},
+		{"servers[?name==api].port", []string{"servers[0].port"}, "8080"},
+		{"servers[?name != 'api'].port", []string{"servers[1].port"}, "80"},
+		{"servers[?tls].name", []string{"servers[1].name"}, "web"},
+		{"servers[?tls.cert==\"web.pem\"]", []string{"servers[1]"}, ""},
+		{"['a.b']", []string{"['a.b']"}, "dotted"},
+		{"notes", []string{"notes"}, "first\nsecond"},
+		{"servers[2]", []string{}, ""},
+		{"name.x", []string{}, ""},
+		{"servers[?name==db]", []string{}, ""},
+	}
+	for _, item := range testCases {
+		matches, err := Select([]byte(testDoc), item.path)
+		if err != nil {
+			t.Errorf("[Select] path: %s; error: %v", item.path, err)
+			continue
+		}
+		paths := []string{}
+		for _, m := range matches {
+			paths = append(paths, m.Path)
+		}
+		if !reflect.DeepEqual(paths, item.paths) {
+			t.Errorf("[Select] path: %s; out: %v; want: %v", item.path, paths, item.paths)
+		} else if len(matches) > 0 && matches[0].Node.Value != item.value {
+			t.Errorf("[Select] path: %s; value: %q; want: %q", item.path, matches[0].Node.Value, item.value)
+		}
+	}
+
+	invalid := []string{"a.", "a..", "[", "[1", "[x]", "['a]", "[?]", "[?a=b]", "[?a==b", "a]"}
+	for _, item := range invalid {
+		if _, err := Compile(item); err == nil {
+			t.Errorf("[Compile] path: %s; want: error", item)
+		}
+	}
+}
+
+func TestKeys(t *testing.T) {
+	testCases := []struct {
+		path string
+		keys []string
+		ok   bool
+	}{
+		{"", []string{}, true},
+		{"$.servers[1].tls", []string{"servers", "1", "tls"}, true},
+		{"servers.0.['a.b'][-1]", []string{"servers", "0", "a.b", "-1"}, true},
+		{"servers[*]", nil, false},
+		{"..name", nil, false},
+		{"servers[?tls]", nil, false},
+	}
+	for _, item := range testCases {
+		keys, ok := MustCompile(item.path).Keys()
+		if ok != item.ok || !reflect.DeepEqual(keys, item.keys) {
+			t.Errorf("[Keys] path: %s; out: %v, %t; want: %v, %t", item.path, keys, ok, item.keys, item.ok)
+		}
+	}
+}
+
+func TestDecodePath(t *testing.T) {
+	type server struct {
+		Name string `nano:"name"`
+		Port int    `nano:"port"`
+	}
+	s := server{}
+	if err := DecodePath([]byte(testDoc), "servers[?name==web]", &s); err != nil || s != (server{"web", 80}) {
+		t.Errorf("[DecodePath] out: %+v; error: %v", s, err)
+	}
+	ports := []int{}
+	if err := DecodePath([]byte(testDoc), "servers", &ports); err == nil {
+		t.Errorf("[DecodePath] out: %v; want: error", ports)
+	}
+	names := []string{}
+	if err := DecodePath([]byte("{\nlist [\na\nb\n]\n}\n"), "list", &names); err != nil || !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Errorf("[DecodePath] out: %v; error: %v", names, err)
+	}
+	port := 0
+	if err := DecodePath([]byte(testDoc), "servers[0].port", &port); err != nil || port != 8080 {
+		t.Errorf("[DecodePath] out: %d; error: %v", port, err)
+	}
+	if err := DecodePath([]byte(testDoc), "servers[5]", &port); err == nil {
+		t.Errorf("[DecodePath] want: error")
+	}
+}
@@ -0,0 +1,138 @@
+package nanoschema
+
+import (
+	"encoding"
+	"reflect"
+	"strings"
+
+	nanomarkup "github.com/nanomarkup/nanomarkup.go"
+	"github.com/nanomarkup/nanomarkup.go/internal/nanotag"
+	"github.com/nanomarkup/nanomarkup.go/nanometadata"
+)
+
+var (
+	marshalerType     = reflect.TypeOf((*nanomarkup.Marshaler)(nil)).Elem()
+	appenderType      = reflect.TypeOf((*nanomarkup.AppenderNano)(nil)).Elem()
+	marshalerToType   = reflect.TypeOf((*nanomarkup.MarshalerTo)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// generator keeps the types which are being generated to handle recursive types.
+type generator struct {
+	visiting map[reflect.Type]bool
+}
+
+func (g *generator) schema(typ reflect.Type, meta *nanometadata.Metadata) *Schema {
+	s := &Schema{Description: description(meta)}
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	ptr := reflect.PointerTo(typ)
+	if typ.Implements(marshalerToType) || ptr.Implements(marshalerToType) ||
+		typ.Implements(appenderType) || ptr.Implements(appenderType) ||
+		typ.Implements(marshalerType) || ptr.Implements(marshalerType) {
+		// the encoding is defined by the type itself
+		s.Type = TypeAny
+		return s
+	} else if typ.Implements(textMarshalerType) || ptr.Implements(textMarshalerType) {
+		s.Type = TypeString
+		return s
+	}
+	switch typ.Kind() {
+	case reflect.Bool:
+		s.Type = TypeBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s.Type = TypeInt
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		s.Type = TypeInt
+		s.Min = new(float64)
+	case reflect.Float32, reflect.Float64:
+		s.Type = TypeFloat
+	case reflect.String:
+		s.Type = TypeString
+	case reflect.Slice, reflect.Array:
+		s.Type = TypeArray
+		s.Items = g.schema(typ.Elem(), elemMetadata(meta))
+		if typ.Kind() == reflect.Array {
+			n := float64(typ.Len())
+			s.Max = &n
+		}
+	case reflect.Map:
+		s.Type = TypeEntity
+		s.Values = g.schema(typ.Elem(), elemMetadata(meta))
+	case reflect.Struct:
+		if g.visiting[typ] {
+			// a recursive type is not described twice
+			s.Type = TypeAny
+			return s
+		}
+		g.visiting[typ] = true
+		s.Type = TypeEntity
+		s.Fields, s.Values = g.fields(typ, meta)
+		delete(g.visiting, typ)
+	default:
+		s.Type = TypeAny
+	}
+	return s
+}
+
+// fields returns the schemas of the struct fields and the schema of the values
+// of unknown keys if the struct has a field with the remain option.
+func (g *generator) fields(typ reflect.Type, meta *nanometadata.Metadata) (Fields, *Schema) {
+	fields := Fields{}
+	var values *Schema
+	for _, f := range reflect.VisibleFields(typ) {
+		if !f.IsExported() || len(f.Index) > 1 {
+			continue
+		}
+		tag := nanotag.Parse(f)
+		if tag.Remain {
+			// the remain field collects the unknown keys
+			if f.Type.Kind() == reflect.Map {
+				values = g.schema(f.Type.Elem(), nil)
+			}
+			continue
+		} else if tag.Ignore {
+			continue
+		}
+		name := tag.Name
+		// handle a metadata
+		var fmeta *nanometadata.Metadata = nil
+		if meta != nil {
+			if fmeta = meta.GetField(f.Name); fmeta == nil {
+				fmeta = meta.GetField(name)
+			}
+		}
+		s := g.schema(f.Type, fmeta)
+		// Marshal skips nil values, so they cannot be required
+		switch f.Type.Kind() {
+		case reflect.Slice, reflect.Map, reflect.Pointer, reflect.Interface:
+		default:
+			s.Required = !tag.OmitEmpty
+		}
+		fields = append(fields, Field{name, s})
+	}
+	return fields, values
+}
+
+// elemMetadata returns the metadata of the items of an array or a map.
+func elemMetadata(meta *nanometadata.Metadata) *nanometadata.Metadata {
+	if meta == nil {
+		return nil
+	}
+	return meta.Elem()
+}
+
+// description returns the text of the comments without the comment markers.
+func description(meta *nanometadata.Metadata) string {
+	if meta == nil {
+		return ""
+	}
+	lines := []string{}
+	for _, line := range strings.Split(meta.Comments.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,104 @@
+package nanoschema
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+
+	nanomarkup "github.com/nanomarkup/nanomarkup.go"
+	"github.com/nanomarkup/nanomarkup.go/nanoerror"
+	"github.com/nanomarkup/nanomarkup.go/nanometadata"
+)
+
+// Schema describes the expected structure of a nano value.
+// A schema is written in nano itself, for example:
+//
+//	{
+//	type entity
+//	fields {
+//	port {
+//	type int
+//	required true
+//	min 1
+//	max 65535
+//	}
+//	}
+//	}
+type Schema struct {
+	// Type is one of the type constants; an empty type is the same as TypeAny.
+	Type        string   `nano:"type,omitempty"`
+	Description string   `nano:"description,omitempty"`
+	Required    bool     `nano:"required,omitempty"`
+	Enum        []string `nano:"enum,omitempty"`
+	// Min and Max limit a number, a length of a string or a number of items of an array.
+	Min     *float64 `nano:"min,omitempty"`
+	Max     *float64 `nano:"max,omitempty"`
+	Pattern string   `nano:"pattern,omitempty"`
+	// Items describes items of an array.
+	Items *Schema `nano:"items,omitempty"`
+	// Fields describes known keys of an entity in the document order.
+	Fields Fields `nano:"fields,omitempty"`
+	// Values describes values of keys which are not listed in Fields.
+	Values *Schema `nano:"values,omitempty"`
+	// Additional allows keys which are not listed in Fields if Values is not specified.
+	Additional bool `nano:"additional,omitempty"`
+	pattern    *regexp.Regexp
+}
+
+// Field is a named schema of an entity key.
+type Field struct {
+	Name   string
+	Schema *Schema
+}
+
+// Fields is a list of entity keys which keeps the document order.
+type Fields []Field
+
+const (
+	TypeAny    string = "any"
+	TypeString string = "string"
+	TypeInt    string = "int"
+	TypeFloat  string = "float"
+	TypeBool   string = "bool"
+	TypeEntity string = "entity"
+	TypeArray  string = "array"
+)
+
+// Parse parses the nano-encoded schema.
+func Parse(data []byte) (*Schema, error) {
+	s := Schema{}
+	if err := nanomarkup.Unmarshal(data, &s, nil); err != nil {
+		return nil, err
+	}
+	if err := s.compile(""); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Marshal returns the nano encoding of the schema.
+func Marshal(s *Schema) ([]byte, error) {
+	if s == nil {
+		return nil, &nanoerror.InvalidArgumentError{Context: "Schema", Err: fmt.Errorf("the schema is Nil")}
+	}
+	return nanomarkup.Marshal(s, nil)
+}
+
+// Generate returns the schema of the Go value v, v can be a nil pointer of the required type.
+//
+// The keys of entities follow the encoding of Marshal: the "nano" tag renames
+// or skips a field and a field without the "omitempty" option is required unless it is
+// a slice, a map, a pointer or an interface, which is not written if it is nil.
+// A field with the "remain" option is not a key, it describes the values of unknown keys.
+// The comments of the metadata fields are used as descriptions of the keys.
+func Generate(v any, meta *nanometadata.Metadata) (*Schema, error) {
+	if v == nil {
+		return nil, &nanoerror.InvalidArgumentError{Context: "Schema", Err: fmt.Errorf("the first argument is Nil")}
+	}
+	g := generator{visiting: map[reflect.Type]bool{}}
+	s := g.schema(reflect.TypeOf(v), meta)
+	if err := s.compile(""); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
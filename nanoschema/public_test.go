@@ -0,0 +1,238 @@
+package nanoschema
+
+import (
+	"testing"
+	"time"
+
+	nanomarkup "github.com/nanomarkup/nanomarkup.go"
+	"github.com/nanomarkup/nanomarkup.go/nanometadata"
+)
+
+const testSchema = `{
+type entity
+fields {
+name {
+type string
+required true
+min 1
+pattern ` + "`" + `
+[a-z]+
+` + "`" + `
+}
+level {
+type string
+enum [
+debug
+info
+]
+}
+servers {
+type array
+max 2
+items {
+fields {
+host {
+type string
+required true
+}
+port {
+type int
+min 1
+max 65535
+}
+tls {
+type bool
+}
+}
+}
+}
+labels {
+type entity
+values {
+type string
+}
+}
+}
+}
+`
+
+func TestValidate(t *testing.T) {
+	s, err := Parse([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("[Parse] %s", err)
+	}
+	testCases := []struct {
+		v    string
+		want []string
+	}{
+		{v: "{\nname api\nlevel info\nservers [\n{\nhost localhost\nport 8080\ntls true\n}\n]\nlabels {\nteam core\n}\n}\n", want: []string{}},
+		{v: "", want: []string{"[Schema] the document is empty"}},
+		{v: "[\n]\n", want: []string{"[Schema] the value must be entity, not an array"}},
+		{v: "{\nlevel warn\n}\n", want: []string{
+			"[Schema] level: the value must be one of debug, info: warn",
+			"[Schema] the required key name is missing",
+		}},
+		{v: "{\nname API\nname api\nunknown 1\n}\n", want: []string{
+			"[Schema] name: the value does not match the pattern [a-z]+: API",
+			"[Schema] name: the key is duplicated",
+			"[Schema] unknown: the key is unknown",
+		}},
+		{v: "{\nname api\nservers [\n{\nhost a\n}\n{\nport 0\ntls yes\n}\n{\nhost c\nport http\n}\n]\n}\n", want: []string{
+			"[Schema] servers[1].port: the value must be greater than or equal to 1",
+			"[Schema] servers[1].tls: the value must be a boolean: yes",
+			"[Schema] servers[1]: the required key host is missing",
+			"[Schema] servers[2].port: the value must be an integer: http",
+			"[Schema] servers: the number of items must be less than or equal to 2",
+		}},
+		{v: "{\nname api\nlabels {\nteam {\n}\n}\n}\n", want: []string{"[Schema] labels.team: the value must be string, not an entity"}},
+		{v: "{\nname api\n", want: []string{"[Validate] line 1, column 1: '}' is missing"}},
+	}
+
+	for _, item := range testCases {
+		errs := s.Validate([]byte(item.v))
+		if len(errs) != len(item.want) {
+			t.Errorf("[Validate] in: %s; out: %v; want: %v", item.v, errs, item.want)
+			continue
+		}
+		for i, err := range errs {
+			if err.Error() != item.want[i] {
+				t.Errorf("[Validate] in: %s; out: %s; want: %s", item.v, err.Error(), item.want[i])
+			}
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		v    string
+		want string
+	}{
+		{v: "{\ntype number\n}\n", want: "[Schema] unknown type: number"},
+		{v: "{\nfields {\nname {\npattern (a\n}\n}\n}\n", want: "[Schema] name: error parsing regexp: missing closing ): `^(?:(a)$`"},
+	}
+
+	for _, item := range testCases {
+		if _, err := Parse([]byte(item.v)); err == nil || err.Error() != item.want {
+			t.Errorf("[Parse] in: %s; out: %v; want: %s", item.v, err, item.want)
+		}
+	}
+
+	s, err := Parse([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("[Parse] %s", err)
+	}
+	out, err := Marshal(s)
+	if err != nil {
+		t.Fatalf("[Marshal] %s", err)
+	}
+	r, err := Parse(out)
+	if err != nil {
+		t.Fatalf("[Parse] in: %s; %s", out, err)
+	}
+	if len(r.Fields) != 4 || r.Fields[2].Name != "servers" || r.Fields.Get("servers").Items.Fields.Get("port").Max == nil {
+		t.Errorf("[Marshal] in: %s; out: %s", testSchema, out)
+	}
+}
+
+func TestUnmarshalWithValidator(t *testing.T) {
+	s, err := Parse([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("[Parse] %s", err)
+	}
+	v := struct {
+		Name  string `nano:"name"`
+		Level string `nano:"level"`
+	}{}
+	if err = nanomarkup.Unmarshal([]byte("{\nname api\nlevel info\n}\n"), &v, nil, nanomarkup.WithValidator(s)); err != nil || v.Name != "api" || v.Level != "info" {
+		t.Errorf("[Unmarshal] out: %v; error: %v", v, err)
+	}
+	want := "[Schema] level: the value must be one of debug, info: warn\n[Schema] the required key name is missing"
+	if err = nanomarkup.Unmarshal([]byte("{\nlevel warn\n}\n"), &v, nil, nanomarkup.WithValidator(s)); err == nil || err.Error() != want {
+		t.Errorf("[Unmarshal] out: %v; want: %s", err, want)
+	}
+}
+
+type testServer struct {
+	Host string `nano:"host"`
+	Port uint16 `nano:"port,omitempty"`
+}
+
+type testConfig struct {
+	Name    string            `nano:"name"`
+	Debug   bool              `nano:"omitempty"`
+	Ratio   float64           `nano:"ratio,omitempty"`
+	Servers []testServer      `nano:"servers"`
+	Labels  map[string]string `nano:"labels,omitempty"`
+	Started time.Time         `nano:"started,omitempty"`
+	Parent  *testConfig       `nano:"parent,omitempty"`
+	Secret  string            `nano:"-"`
+	secret  string
+}
+
+func TestGenerate(t *testing.T) {
+	meta := nanometadata.CreateMetadata(" Service configuration", false)
+	meta.AddField("Name", nanometadata.CreateMetadata(" A name of the service", false))
+	servers := nanometadata.CreateMetadata(" Listening\n addresses ", true)
+	server := &nanometadata.Metadata{}
+	server.AddField("host", nanometadata.CreateMetadata(" A host name", false))
+	servers.SetElem(server)
+	meta.AddField("servers", servers)
+	s, err := Generate((*testConfig)(nil), meta)
+	if err != nil {
+		t.Fatalf("[Generate] %s", err)
+	}
+	out, err := Marshal(s)
+	if err != nil {
+		t.Fatalf("[Marshal] %s", err)
+	}
+	want := "{\ntype entity\ndescription Service configuration\nfields {\n" +
+		"name {\ntype string\ndescription A name of the service\nrequired true\n}\n" +
+		"Debug {\ntype bool\n}\n" +
+		"ratio {\ntype float\n}\n" +
+		"servers {\ntype array\ndescription `\nListening\naddresses\n`\nitems {\ntype entity\nfields {\n" +
+		"host {\ntype string\ndescription A host name\nrequired true\n}\n" +
+		"port {\ntype int\nmin 0\n}\n}\n}\n}\n" +
+		"labels {\ntype entity\nvalues {\ntype string\n}\n}\n" +
+		"started {\ntype string\n}\n" +
+		"parent {\ntype any\n}\n}\n}\n"
+	if string(out) != want {
+		t.Errorf("[Generate] out: %s; want: %s", out, want)
+	}
+
+	data, err := nanomarkup.Marshal(testConfig{Name: "api", Servers: []testServer{{Host: "localhost", Port: 8080}}}, nil)
+	if err != nil {
+		t.Fatalf("[Marshal] %s", err)
+	}
+	if errs := s.Validate(data); len(errs) > 0 {
+		t.Errorf("[Validate] in: %s; out: %v", data, errs)
+	}
+	// the nil values are not written, so they are not required
+	if data, err = nanomarkup.Marshal(testConfig{Name: "api"}, nil); err != nil {
+		t.Fatalf("[Marshal] %s", err)
+	}
+	if errs := s.Validate(data); len(errs) > 0 {
+		t.Errorf("[Validate] in: %s; out: %v", data, errs)
+	}
+	if errs := s.Validate([]byte("{\nname api\nservers [\n{\nport -1\n}\n]\n}\n")); len(errs) != 2 {
+		t.Errorf("[Validate] out: %v; want: 2 errors", errs)
+	}
+
+	// the unknown keys are collected by the remain field
+	type remainConfig struct {
+		Name  string                           `nano:"name"`
+		Extra map[string]nanomarkup.RawMessage `nano:"extra,remain"`
+	}
+	if s, err = Generate(remainConfig{}, nil); err != nil {
+		t.Fatalf("[Generate] %s", err)
+	}
+	if len(s.Fields) != 1 || s.Values == nil || s.Values.Type != TypeAny {
+		t.Errorf("[Generate] remain: %+v", s)
+	}
+	if errs := s.Validate([]byte("{\nname api\nzone eu\nlimits {\nrate 5\n}\n}\n")); len(errs) > 0 {
+		t.Errorf("[Validate] remain: %v", errs)
+	}
+
+	if _, err = Generate(nil, nil); err == nil {
+		t.Errorf("[Generate] in: nil; want: error")
+	}
+}
@@ -0,0 +1,280 @@
+package nanoschema
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	nanomarkup "github.com/nanomarkup/nanomarkup.go"
+	"github.com/nanomarkup/nanomarkup.go/nanoerror"
+)
+
+// validator collects errors of a document.
+type validator struct {
+	errors []error
+}
+
+// Validate checks the nano-encoded data against the schema and returns all found errors.
+// Every error is a *nanoerror.SchemaError that holds the path of the value
+// or a *nanoerror.SyntaxError if the data is not a valid nano encoding.
+func (s *Schema) Validate(data []byte) []error {
+	if errs := nanomarkup.Validate(data); len(errs) > 0 {
+		return errs
+	}
+	v := validator{}
+	dec := nanomarkup.NewDecoder(bytes.NewReader(data))
+	if err := v.value(dec, s, ""); err == io.EOF {
+		v.add("", "the document is empty")
+	} else if err != nil {
+		v.errors = append(v.errors, err)
+	}
+	return v.errors
+}
+
+// Get returns the schema of the key or nil if the key is unknown.
+func (f Fields) Get(name string) *Schema {
+	for _, it := range f {
+		if it.Name == name {
+			return it.Schema
+		}
+	}
+	return nil
+}
+
+// MarshalNanoTo writes the fields as an entity of the schemas by the names in the order of the fields.
+func (f Fields) MarshalNanoTo(enc *nanomarkup.Encoder) error {
+	if err := enc.BeginEntity(); err != nil {
+		return err
+	}
+	for _, it := range f {
+		if err := enc.Key(it.Name); err != nil {
+			return err
+		}
+		if err := enc.Encode(it.Schema, nil); err != nil {
+			return err
+		}
+	}
+	return enc.EndEntity()
+}
+
+// UnmarshalNanoFrom appends the items of an entity to the fields keeping the order of the keys.
+func (f *Fields) UnmarshalNanoFrom(dec *nanomarkup.Decoder) error {
+	if t, err := dec.Token(); err != nil {
+		return err
+	} else if t != nanomarkup.Delim('{') {
+		return &nanoerror.InvalidEntityError{Context: "Schema", Entity: fmt.Sprint(t), Err: fmt.Errorf("fields must be an entity")}
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		s := Schema{}
+		if err = dec.Decode(&s, nil); err != nil {
+			return err
+		}
+		*f = append(*f, Field{string(t.(nanomarkup.Key)), &s})
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// compile checks the schema and prepares it for validation.
+func (s *Schema) compile(path string) error {
+	switch s.Type {
+	case "":
+		if len(s.Fields) > 0 || s.Values != nil {
+			s.Type = TypeEntity
+		} else if s.Items != nil {
+			s.Type = TypeArray
+		} else {
+			s.Type = TypeAny
+		}
+	case TypeAny, TypeString, TypeInt, TypeFloat, TypeBool, TypeEntity, TypeArray:
+	default:
+		return &nanoerror.SchemaError{Context: "Schema", Path: path, Err: fmt.Errorf("unknown type: %s", s.Type)}
+	}
+	if s.Pattern != "" {
+		p, err := regexp.Compile("^(?:" + s.Pattern + ")$")
+		if err != nil {
+			return &nanoerror.SchemaError{Context: "Schema", Path: path, Err: err}
+		}
+		s.pattern = p
+	}
+	if s.Items != nil {
+		if err := s.Items.compile(path + "[]"); err != nil {
+			return err
+		}
+	}
+	if s.Values != nil {
+		if err := s.Values.compile(joinKey(path, "*")); err != nil {
+			return err
+		}
+	}
+	for _, f := range s.Fields {
+		if f.Schema == nil {
+			return &nanoerror.SchemaError{Context: "Schema", Path: joinKey(path, f.Name), Err: fmt.Errorf("the schema is missing")}
+		}
+		if err := f.Schema.compile(joinKey(path, f.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// value checks the next value of the decoder, the schema can be nil to skip the value.
+func (v *validator) value(dec *nanomarkup.Decoder, s *Schema, path string) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch t := t.(type) {
+	case nanomarkup.Delim:
+		if t == '{' {
+			return v.entity(dec, s, path)
+		} else {
+			return v.array(dec, s, path)
+		}
+	case string:
+		v.scalar(t, s, path)
+	}
+	return nil
+}
+
+func (v *validator) entity(dec *nanomarkup.Decoder, s *Schema, path string) error {
+	if s != nil && s.Type != TypeAny && s.Type != TypeEntity {
+		v.add(path, "the value must be %s, not an entity", s.Type)
+		s = nil
+	}
+	seen := map[string]bool{}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key := string(t.(nanomarkup.Key))
+		var fs *Schema = nil
+		if s != nil {
+			if seen[key] {
+				v.add(joinKey(path, key), "the key is duplicated")
+			}
+			if fs = s.Fields.Get(key); fs == nil {
+				if s.Values != nil {
+					fs = s.Values
+				} else if !s.Additional && s.Type == TypeEntity {
+					v.add(joinKey(path, key), "the key is unknown")
+				}
+			}
+		}
+		seen[key] = true
+		if err = v.value(dec, fs, joinKey(path, key)); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	if s != nil {
+		for _, f := range s.Fields {
+			if f.Schema.Required && !seen[f.Name] {
+				v.add(path, "the required key %s is missing", f.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func (v *validator) array(dec *nanomarkup.Decoder, s *Schema, path string) error {
+	if s != nil && s.Type != TypeAny && s.Type != TypeArray {
+		v.add(path, "the value must be %s, not an array", s.Type)
+		s = nil
+	}
+	var items *Schema = nil
+	if s != nil {
+		items = s.Items
+	}
+	count := 0
+	for ; dec.More(); count++ {
+		if err := v.value(dec, items, path+"["+strconv.Itoa(count)+"]"); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	if s != nil {
+		v.limits(float64(count), s, path, "the number of items")
+	}
+	return nil
+}
+
+func (v *validator) scalar(val string, s *Schema, path string) {
+	if s == nil {
+		return
+	}
+	switch s.Type {
+	case TypeEntity, TypeArray:
+		v.add(path, "the value must be %s, not a scalar", s.Type)
+		return
+	case TypeInt:
+		n, err := strconv.ParseInt(val, 0, 64)
+		if err != nil {
+			v.add(path, "the value must be an integer: %s", val)
+			return
+		}
+		v.limits(float64(n), s, path, "the value")
+	case TypeFloat:
+		n, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			v.add(path, "the value must be a number: %s", val)
+			return
+		}
+		v.limits(n, s, path, "the value")
+	case TypeBool:
+		if _, err := strconv.ParseBool(val); err != nil {
+			v.add(path, "the value must be a boolean: %s", val)
+			return
+		}
+	case TypeString:
+		v.limits(float64(utf8.RuneCountInString(val)), s, path, "the length")
+	}
+	if len(s.Enum) > 0 {
+		found := false
+		for _, it := range s.Enum {
+			if it == val {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.add(path, "the value must be one of %s: %s", strings.Join(s.Enum, ", "), val)
+		}
+	}
+	if s.pattern != nil && !s.pattern.MatchString(val) {
+		v.add(path, "the value does not match the pattern %s: %s", s.Pattern, val)
+	}
+}
+
+func (v *validator) limits(n float64, s *Schema, path, name string) {
+	if s.Min != nil && n < *s.Min {
+		v.add(path, "%s must be greater than or equal to %s", name, strconv.FormatFloat(*s.Min, 'g', -1, 64))
+	}
+	if s.Max != nil && n > *s.Max {
+		v.add(path, "%s must be less than or equal to %s", name, strconv.FormatFloat(*s.Max, 'g', -1, 64))
+	}
+}
+
+func (v *validator) add(path, format string, a ...any) {
+	v.errors = append(v.errors, &nanoerror.SchemaError{Context: "Schema", Path: path, Err: fmt.Errorf(format, a...)})
+}
+
+func joinKey(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
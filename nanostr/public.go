@@ -1,6 +1,7 @@
 package nanostr
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
 
@@ -8,17 +9,58 @@ import (
 	"github.com/nanomarkup/nanomarkup.go/nanoerror"
 )
 
+// EscapedCommentOpCode is written instead of "//" after a whitespace in a single-line value,
+// so the value is not cut by a trailing comment.
+const EscapedCommentOpCode string = "\\//"
+
 func Marshal(value string) []byte {
-	lines := strings.Split(value, "\n")
-	if len(lines) == 1 {
-		return []byte(strings.TrimLeft(value, " \t"))
-	} else {
-		res := "`\n"
-		for _, it := range lines {
-			res += it + "\n"
+	return Append([]byte{}, value)
+}
+
+func MarshalMultiline(value string) []byte {
+	return AppendMultiline([]byte{}, value)
+}
+
+func Append(dst []byte, value string) []byte {
+	if strings.Contains(value, "\n") {
+		return AppendMultiline(dst, value)
+	}
+	value = strings.TrimLeft(value, " \t")
+	// a value which looks like a delimiter or a comment is written as a multi-line value
+	if len(value) > 0 && strings.ContainsRune("[]{}`", rune(value[0])) || strings.HasPrefix(value, "//") || strings.HasPrefix(value, "/*") {
+		return AppendMultiline(dst, value)
+	}
+	// an escaped comment delimiter cannot be written as is
+	if strings.Contains(value, " "+EscapedCommentOpCode) || strings.Contains(value, "\t"+EscapedCommentOpCode) {
+		return AppendMultiline(dst, value)
+	}
+	// a comment delimiter after a whitespace begins a trailing comment and must be escaped
+	for i := 1; i+1 < len(value); i++ {
+		if value[i] == 47 && value[i+1] == 47 && (value[i-1] == 32 || value[i-1] == 9) { // /, space, tab
+			dst = append(dst, value[:i]...)
+			dst = append(dst, 92) // \
+			value = value[i:]
+			i = 0
 		}
-		return []byte(res + "`\n")
 	}
+	return append(dst, value...)
+}
+
+// AppendMultiline appends the value as a multi-line value.
+// A line of the value which consists of backslashes followed by "`" is written with
+// one more backslash, so it is not read as the end of the multi-line value.
+func AppendMultiline(dst []byte, value string) []byte {
+	dst = append(dst, "`\n"...)
+	for i, line := range strings.Split(value, "\n") {
+		if i > 0 {
+			dst = append(dst, 10) // \n
+		}
+		if isEscapedEnd(line, 0) {
+			dst = append(dst, 92) // \
+		}
+		dst = append(dst, line...)
+	}
+	return append(dst, "\n`\n"...)
 }
 
 func Unmarshal(d *nanodecoder.Decoder, item []byte) ([]byte, error) {
@@ -26,7 +68,7 @@ func Unmarshal(d *nanodecoder.Decoder, item []byte) ([]byte, error) {
 		// update the item variable by a multi-line value
 		val := item[1:]
 		if len(val) > 0 && len(strings.TrimSpace(string(val))) > 0 {
-			return item, &nanoerror.InvalidEntityError{"Parse", string(item), fmt.Errorf("the data of a multi-line value must be started from a new line")}
+			return item, &nanoerror.InvalidEntityError{Context: "Parse", Entity: string(item), Err: fmt.Errorf("the data of a multi-line value must be started from a new line")}
 		}
 		mval := []byte{}
 		first := true
@@ -42,6 +84,9 @@ func Unmarshal(d *nanodecoder.Decoder, item []byte) ([]byte, error) {
 				if !first {
 					mval = append(mval, "\n"...)
 				}
+				if isEscapedEnd(string(item), 1) {
+					item = item[1:]
+				}
 				mval = append(mval, item...)
 			}
 			if first {
@@ -51,9 +96,60 @@ func Unmarshal(d *nanodecoder.Decoder, item []byte) ([]byte, error) {
 		if completed {
 			return mval, nil
 		} else {
-			return item, &nanoerror.InvalidEntityError{"Parse", "", fmt.Errorf("'`' is missing")}
+			return item, &nanoerror.InvalidEntityError{Context: "Parse", Entity: "", Err: fmt.Errorf("'`' is missing")}
 		}
 	} else {
-		return item, nil
+		return Value(item), nil
 	}
 }
+
+// Value returns the decoded single-line value without a trailing comment.
+func Value(item []byte) []byte {
+	val, _, _ := CutComment(item)
+	return unescape(val)
+}
+
+// CutComment slices the single-line value around a trailing comment, which begins with
+// "//" after a space or a tab, and returns the text of the value before the comment
+// without the trailing whitespaces and the text of the comment after the "//".
+// The found result reports whether the comment is in the value.
+// The returned value keeps the escaped comment delimiters ("\\//") as is.
+// An item which begins with "//" is an empty value with a trailing comment
+// because the whitespaces before the comment are trimmed with the key.
+// Multi-line values, entities and arrays have no trailing comments.
+func CutComment(item []byte) (value []byte, comment string, found bool) {
+	if len(item) == 0 || item[0] == 96 || item[0] == 91 || item[0] == 123 { // `, [, {
+		return item, "", false
+	}
+	if len(item) > 1 && item[0] == 47 && item[1] == 47 { // the value is empty
+		return item[:0], string(item[2:]), true
+	}
+	for i := 1; i+1 < len(item); i++ {
+		if item[i] == 47 && item[i+1] == 47 && (item[i-1] == 32 || item[i-1] == 9) { // /, space, tab
+			return bytes.TrimRight(item[:i], " \t"), string(item[i+2:]), true
+		}
+	}
+	return item, "", false
+}
+
+// unescape replaces the escaped comment delimiters after whitespaces by "//".
+func unescape(item []byte) []byte {
+	if !bytes.Contains(item, []byte(EscapedCommentOpCode)) {
+		return item
+	}
+	out := make([]byte, 0, len(item))
+	for i := 0; i < len(item); i++ {
+		if i > 0 && item[i] == 92 && bytes.HasPrefix(item[i+1:], []byte("//")) && (item[i-1] == 32 || item[i-1] == 9) { // \, space, tab
+			continue
+		}
+		out = append(out, item[i])
+	}
+	return out
+}
+
+// isEscapedEnd reports whether the line of a multi-line value consists of at least min
+// backslashes followed by "`".
+func isEscapedEnd(line string, min int) bool {
+	n := strings.TrimLeft(line, "\\")
+	return n == "`" && len(line)-len(n) >= min
+}
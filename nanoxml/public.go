@@ -0,0 +1,118 @@
+package nanoxml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+
+	nanomarkup "github.com/nanomarkup/nanomarkup.go"
+	"github.com/nanomarkup/nanomarkup.go/internal/nanotree"
+	"github.com/nanomarkup/nanomarkup.go/nanoerror"
+)
+
+// Option configures the conversion.
+type Option func(*options)
+
+const (
+	// DefaultAttributePrefix is the prefix of keys which hold attributes of an element.
+	DefaultAttributePrefix string = "@"
+	// DefaultTextKey is the key which holds the text of an element with attributes or child elements.
+	DefaultTextKey string = "#text"
+)
+
+// ToXML converts the nano-encoded data to XML.
+//
+// The data must be an entity with the only key which is the name of the root element.
+// Entities are converted to elements, the keys with the attribute prefix to attributes
+// and the text key to the text of the element. Every item of an array is converted
+// to an element with the name of the key of the array. Comments are converted to XML comments.
+func ToXML(data []byte, opts ...Option) ([]byte, error) {
+	o, err := getOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if errs := nanomarkup.Validate(data); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	n, footer, err := nanotree.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if n == nil || n.Kind != '{' || len(n.Keys) != 1 {
+		return nil, &nanoerror.InvalidEntityError{Context: "XML", Entity: "", Err: fmt.Errorf("the data must be an entity with the only root element")}
+	}
+	out := bytes.Buffer{}
+	enc := xml.NewEncoder(&out)
+	w := writer{opts: o, enc: enc, first: true}
+	if err = w.writeComments(n.Comments); err != nil {
+		return nil, err
+	}
+	if err = w.writeComments(n.Items[0].Comments); err != nil {
+		return nil, err
+	}
+	if err = w.writeElement(n.Keys[0], n.Items[0]); err != nil {
+		return nil, err
+	}
+	if err = w.writeComments(n.End); err != nil {
+		return nil, err
+	}
+	if err = w.writeComments(footer); err != nil {
+		return nil, err
+	}
+	if err = enc.Flush(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// FromXML converts the XML-encoded data to nano.
+//
+// The root element is converted to an entity with the only key.
+// An element without attributes and child elements is converted to a value,
+// other elements are converted to entities. Repeated child elements are converted
+// to an array at the position of the first element. The text of an element is trimmed.
+// XML comments are converted to comments, processing instructions and directives are skipped.
+func FromXML(data []byte, opts ...Option) ([]byte, error) {
+	o, err := getOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	root, err := readElement(xml.NewDecoder(bytes.NewReader(data)))
+	if err != nil {
+		return nil, err
+	}
+	out := bytes.Buffer{}
+	enc := nanomarkup.NewEncoder(&out)
+	enc.SetIndent(o.prefix, o.indent)
+	if err = writeNode(enc, root, o); err != nil {
+		return nil, err
+	}
+	if err = enc.Flush(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// WithAttributePrefix sets the prefix of keys which hold attributes, the default prefix is DefaultAttributePrefix.
+func WithAttributePrefix(prefix string) Option {
+	return func(o *options) {
+		o.attrPrefix = prefix
+	}
+}
+
+// WithTextKey sets the key which holds the text of an element, the default key is DefaultTextKey.
+func WithTextKey(key string) Option {
+	return func(o *options) {
+		o.textKey = key
+	}
+}
+
+// WithIndent instructs the conversion to indent the output
+// like the Indent method of xml.Encoder or the Indent function of the nanomarkup package.
+func WithIndent(prefix, indent string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+		o.indent = indent
+	}
+}
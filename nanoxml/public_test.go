@@ -0,0 +1,100 @@
+package nanoxml
+
+import (
+	"testing"
+)
+
+const testXML = `<?xml version="1.0"?>
+<!-- Service configuration -->
+<config version="2" xmlns:x="urn:x">
+  <name>api</name>
+  <!-- Listening addresses -->
+  <server tls="true">localhost</server>
+  <empty/>
+  <server>
+    <host>example.com</host>
+  </server>
+  <x:motd>
+hello &amp; welcome
+  </x:motd>
+</config>
+`
+
+const testNano = "{\n// Service configuration\nconfig {\n@version 2\n@xmlns:x urn:x\nname api\n// Listening addresses\nserver [\n{\n@tls true\n#text localhost\n}\n{\nhost example.com\n}\n]\nempty \nx:motd hello & welcome\n}\n}\n"
+
+func TestFromXML(t *testing.T) {
+	testCases := []struct {
+		v    string
+		opts []Option
+		want string
+	}{
+		{v: "<a>text</a>", want: "{\na text\n}\n"},
+		{v: "<a><b>1</b><c>2</c><b>3</b></a>", want: "{\na {\nb [\n1\n3\n]\nc 2\n}\n}\n"},
+		{v: "<a id=\"1\">text<b/></a>", opts: []Option{WithAttributePrefix("-"), WithTextKey("_text")}, want: "{\na {\n-id 1\n_text text\nb \n}\n}\n"},
+		{v: "<a>\n<!-- multi\nline -->\n</a>", want: "{\na {\n/* multi\nline */\n}\n}\n"},
+		{v: testXML, want: testNano},
+	}
+
+	for _, item := range testCases {
+		out, err := FromXML([]byte(item.v), item.opts...)
+		if err != nil {
+			t.Errorf("[FromXML] in: %s; error: %s", item.v, err)
+		} else if string(out) != item.want {
+			t.Errorf("[FromXML] in: %s; out: %q; want: %q", item.v, out, item.want)
+		}
+	}
+
+	invalid := []string{"", "<a>", "<a></b>", "<a/><b/>", "text<a/>"}
+	for _, item := range invalid {
+		if _, err := FromXML([]byte(item)); err == nil {
+			t.Errorf("[FromXML] in: %s; want: error", item)
+		}
+	}
+	if _, err := FromXML([]byte("<a/>"), WithTextKey("")); err == nil {
+		t.Errorf("[FromXML] in: empty text key; want: error")
+	}
+}
+
+func TestToXML(t *testing.T) {
+	testCases := []struct {
+		v    string
+		opts []Option
+		want string
+	}{
+		{v: "{\na text\n}\n", want: "<a>text</a>"},
+		{v: "{\na text\n}\n// the end\n", want: "<a>text</a><!-- the end -->"},
+		{v: "{\na {\nb 1 // the first\n#text t // the text\n}\n}\n", want: "<a><b>1</b><!-- the first -->t<!-- the text --></a>"},
+		{v: "{\na {\nb [\n1\n// second\n3\n]\nc <2>\n}\n}\n", want: "<a><b>1</b><!-- second --><b>3</b><c>&lt;2&gt;</c></a>"},
+		{v: "{\na {\n-id 1\n_text text\nb \n}\n}\n", opts: []Option{WithAttributePrefix("-"), WithTextKey("_text")}, want: "<a id=\"1\">text<b></b></a>"},
+		{v: testNano, opts: []Option{WithIndent("", "  ")}, want: "<!-- Service configuration -->\n<config version=\"2\" xmlns:x=\"urn:x\">\n  <name>api</name>\n  <!-- Listening addresses -->\n  <server tls=\"true\">localhost</server>\n  <server>\n    <host>example.com</host>\n  </server>\n  <empty></empty>\n  <x:motd>hello &amp; welcome</x:motd>\n</config>"},
+	}
+
+	for _, item := range testCases {
+		out, err := ToXML([]byte(item.v), item.opts...)
+		if err != nil {
+			t.Errorf("[ToXML] in: %s; error: %s", item.v, err)
+		} else if string(out) != item.want {
+			t.Errorf("[ToXML] in: %s; out: %q; want: %q", item.v, out, item.want)
+		}
+	}
+
+	invalid := []string{"", "text", "{\na 1\nb 2\n}\n", "{\na [\n[\n1\n]\n]\n}\n", "{\na {\n@id {\n}\n}\n}\n", "{\na 1\n"}
+	for _, item := range invalid {
+		if _, err := ToXML([]byte(item)); err == nil {
+			t.Errorf("[ToXML] in: %s; want: error", item)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	out, err := ToXML([]byte(testNano))
+	if err != nil {
+		t.Fatalf("[ToXML] %s", err)
+	}
+	if out, err = FromXML(out); err != nil {
+		t.Fatalf("[FromXML] %s", err)
+	}
+	if string(out) != testNano {
+		t.Errorf("[RoundTrip] in: %s; out: %s", testNano, out)
+	}
+}
@@ -0,0 +1,332 @@
+package nanoxml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	nanomarkup "github.com/nanomarkup/nanomarkup.go"
+	"github.com/nanomarkup/nanomarkup.go/internal/nanotree"
+	"github.com/nanomarkup/nanomarkup.go/nanocomment"
+	"github.com/nanomarkup/nanomarkup.go/nanoerror"
+)
+
+type options struct {
+	attrPrefix string
+	textKey    string
+	prefix     string
+	indent     string
+}
+
+// element is an XML element which keeps the order of child elements and comments.
+type element struct {
+	name     string
+	attrs    []xml.Attr
+	children []child
+	text     strings.Builder
+}
+
+// child is an element or a comment.
+type child struct {
+	elem    *element
+	comment string
+}
+
+// writer writes XML elements of nano values.
+type writer struct {
+	opts  options
+	enc   *xml.Encoder
+	depth int
+	first bool
+}
+
+func getOptions(opts []Option) (options, error) {
+	o := options{attrPrefix: DefaultAttributePrefix, textKey: DefaultTextKey}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if !isKey(o.attrPrefix) || !isKey(o.textKey) || strings.HasPrefix(o.textKey, o.attrPrefix) {
+		return o, &nanoerror.InvalidArgumentError{Context: "XML", Err: fmt.Errorf("invalid attribute prefix or text key: %q, %q", o.attrPrefix, o.textKey)}
+	}
+	return o, nil
+}
+
+func isKey(s string) bool {
+	return s != "" && !strings.ContainsAny(s, " \t\n") && !strings.HasPrefix(s, "/")
+}
+
+// readElement reads the document and returns an element which contains the root element and the comments around it.
+func readElement(dec *xml.Decoder) (*element, error) {
+	doc := &element{}
+	stack := []*element{doc}
+	for {
+		t, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		curr := stack[len(stack)-1]
+		switch t := t.(type) {
+		case xml.StartElement:
+			if len(stack) == 1 && hasElement(doc) {
+				return nil, xmlError(fmt.Errorf("unexpected element after the root element: %s", name(t.Name)))
+			}
+			e := &element{name: name(t.Name)}
+			for _, a := range t.Attr {
+				e.attrs = append(e.attrs, xml.Attr{Name: xml.Name{Local: name(a.Name)}, Value: a.Value})
+			}
+			curr.children = append(curr.children, child{elem: e})
+			stack = append(stack, e)
+		case xml.EndElement:
+			if len(stack) == 1 || curr.name != name(t.Name) {
+				return nil, xmlError(fmt.Errorf("unexpected end element: %s", name(t.Name)))
+			}
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) == 1 {
+				if len(strings.TrimSpace(string(t))) > 0 {
+					return nil, xmlError(fmt.Errorf("unexpected text outside the root element"))
+				}
+				continue
+			}
+			curr.text.Write(t)
+		case xml.Comment:
+			curr.children = append(curr.children, child{comment: string(t)})
+		}
+	}
+	if len(stack) > 1 {
+		return nil, xmlError(fmt.Errorf("the end of the %s element is missing", stack[len(stack)-1].name))
+	} else if !hasElement(doc) {
+		return nil, xmlError(fmt.Errorf("the root element is missing"))
+	}
+	return doc, nil
+}
+
+func hasElement(e *element) bool {
+	for _, c := range e.children {
+		if c.elem != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func name(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
+
+// writeNode writes the content of the element as nano, the document is written as an entity.
+func writeNode(enc *nanomarkup.Encoder, e *element, o options) error {
+	text := strings.TrimSpace(e.text.String())
+	if e.name != "" && len(e.attrs) == 0 && len(e.children) == 0 {
+		return enc.Encode(text, nil)
+	}
+	if err := enc.BeginEntity(); err != nil {
+		return err
+	}
+	for _, a := range e.attrs {
+		if err := enc.Key(o.attrPrefix + a.Name.Local); err != nil {
+			return err
+		}
+		if err := enc.Encode(a.Value, nil); err != nil {
+			return err
+		}
+	}
+	if text != "" {
+		if err := enc.Key(o.textKey); err != nil {
+			return err
+		}
+		if err := enc.Encode(text, nil); err != nil {
+			return err
+		}
+	}
+	written := map[string]bool{}
+	for _, c := range e.children {
+		if c.elem == nil {
+			if err := enc.Comments(commentOf(c.comment)); err != nil {
+				return err
+			}
+			continue
+		} else if written[c.elem.name] {
+			continue
+		}
+		written[c.elem.name] = true
+		if err := enc.Key(c.elem.name); err != nil {
+			return err
+		}
+		same := []*element{}
+		for _, it := range e.children {
+			if it.elem != nil && it.elem.name == c.elem.name {
+				same = append(same, it.elem)
+			}
+		}
+		if len(same) == 1 {
+			if err := writeNode(enc, c.elem, o); err != nil {
+				return err
+			}
+			continue
+		}
+		// repeated elements are written as an array
+		if err := enc.BeginArray(); err != nil {
+			return err
+		}
+		for _, it := range same {
+			if err := writeNode(enc, it, o); err != nil {
+				return err
+			}
+		}
+		if err := enc.EndArray(); err != nil {
+			return err
+		}
+	}
+	return enc.EndEntity()
+}
+
+func commentOf(text string) nanocomment.Comments {
+	c := nanocomment.Comments{}
+	if strings.Contains(text, "\n") {
+		c.Add(text, true)
+	} else {
+		c.Add(" "+strings.TrimSpace(text), false)
+	}
+	return c
+}
+
+// writeElement writes the nano value as the element, an array is written as repeated elements.
+func (w *writer) writeElement(key string, n *nanotree.Node) error {
+	switch n.Kind {
+	case '[':
+		for i, it := range n.Items {
+			if it.Kind == '[' {
+				return xmlError(fmt.Errorf("an array of arrays cannot be converted: %s", key))
+			}
+			if i > 0 {
+				if err := w.writeComments(it.Comments); err != nil {
+					return err
+				}
+			}
+			if err := w.writeElement(key, it); err != nil {
+				return err
+			}
+		}
+		return w.writeComments(n.End)
+	case '{':
+		start := xml.StartElement{Name: xml.Name{Local: key}}
+		for i, k := range n.Keys {
+			if !strings.HasPrefix(k, w.opts.attrPrefix) {
+				continue
+			} else if n.Items[i].Kind != 0 {
+				return xmlError(fmt.Errorf("an attribute must be a value: %s", k))
+			}
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: k[len(w.opts.attrPrefix):]}, Value: n.Items[i].Value})
+		}
+		if err := w.writeIndent(); err != nil {
+			return err
+		}
+		if err := w.enc.EncodeToken(start); err != nil {
+			return err
+		}
+		w.depth++
+		nested := false
+		for i, k := range n.Keys {
+			it := n.Items[i]
+			if strings.HasPrefix(k, w.opts.attrPrefix) {
+				continue
+			}
+			if err := w.writeComments(it.Comments); err != nil {
+				return err
+			}
+			var err error
+			if k == w.opts.textKey {
+				if it.Kind != 0 {
+					return xmlError(fmt.Errorf("a text must be a value: %s", k))
+				}
+				if err = w.enc.EncodeToken(xml.CharData(it.Value)); err == nil {
+					err = w.writeTrailing(it)
+				}
+			} else {
+				nested = true
+				err = w.writeElement(k, it)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if err := w.writeComments(n.End); err != nil {
+			return err
+		}
+		w.depth--
+		if nested || len(n.End) > 0 {
+			if err := w.writeIndent(); err != nil {
+				return err
+			}
+		}
+		return w.enc.EncodeToken(start.End())
+	default:
+		start := xml.StartElement{Name: xml.Name{Local: key}}
+		if err := w.writeIndent(); err != nil {
+			return err
+		}
+		if err := w.enc.EncodeToken(start); err != nil {
+			return err
+		}
+		if n.Value != "" {
+			if err := w.enc.EncodeToken(xml.CharData(n.Value)); err != nil {
+				return err
+			}
+		}
+		if err := w.enc.EncodeToken(start.End()); err != nil {
+			return err
+		}
+		return w.writeTrailing(n)
+	}
+}
+
+// writeTrailing writes the trailing comment of the value at the same line.
+func (w *writer) writeTrailing(n *nanotree.Node) error {
+	if n.Trailing == "" {
+		return nil
+	}
+	return w.enc.EncodeToken(xml.Comment(" " + strings.TrimSpace(n.Trailing) + " "))
+}
+
+func (w *writer) writeComments(comments nanocomment.Comments) error {
+	for _, c := range comments {
+		text := strings.TrimSuffix(string(nanocomment.Marshal(nanocomment.Comments{c})), "\n")
+		if strings.HasPrefix(text, nanocomment.MultilineCommentBegOpCode) {
+			text = strings.TrimSuffix(text[2:], nanocomment.MultilineCommentEndOpCode)
+		} else if strings.HasPrefix(text, nanocomment.SingleCommentOpCode) {
+			text = " " + strings.TrimSpace(text[2:]) + " "
+		} else {
+			// skip a blank line
+			continue
+		}
+		if err := w.writeIndent(); err != nil {
+			return err
+		}
+		if err := w.enc.EncodeToken(xml.Comment(text)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeIndent starts a new indented line unless it is the beginning of the document.
+func (w *writer) writeIndent() error {
+	if w.first {
+		w.first = false
+		return nil
+	} else if w.opts.prefix == "" && w.opts.indent == "" {
+		return nil
+	}
+	return w.enc.EncodeToken(xml.CharData("\n" + w.opts.prefix + strings.Repeat(w.opts.indent, w.depth)))
+}
+
+func xmlError(err error) error {
+	return &nanoerror.InvalidEntityError{Context: "XML", Entity: "", Err: err}
+}
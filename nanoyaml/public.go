@@ -0,0 +1,48 @@
+package nanoyaml
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	nanomarkup "github.com/nanomarkup/nanomarkup.go"
+	"github.com/nanomarkup/nanomarkup.go/internal/nanotree"
+)
+
+// ToYAML converts the nano-encoded data to block-style YAML.
+//
+// Entities are converted to mappings keeping the order of keys, arrays to sequences,
+// multi-line values to literal blocks and other values to plain or double-quoted scalars.
+// The comments are converted to YAML comments.
+func ToYAML(data []byte) ([]byte, error) {
+	if errs := nanomarkup.Validate(data); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	n, footer, err := nanotree.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return appendComments(writeDocument([]byte{}, n), footer, 0), nil
+}
+
+// FromYAML converts the block-style YAML data to nano.
+//
+// The supported subset of YAML contains mappings, sequences, plain and quoted scalars,
+// literal blocks, empty flow collections ({} and []) and comments.
+// Scalars are kept as they are written, for example null is not converted to an empty value.
+// A comment at the end of a line is moved before the item of the line.
+func FromYAML(data []byte) ([]byte, error) {
+	p := parser{}
+	if err := p.init(strings.Split(string(data), "\n")); err != nil {
+		return nil, err
+	}
+	out := bytes.Buffer{}
+	p.enc = nanomarkup.NewEncoder(&out)
+	if err := p.parseDocument(); err != nil {
+		return nil, err
+	}
+	if err := p.enc.Flush(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
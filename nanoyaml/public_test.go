@@ -0,0 +1,92 @@
+package nanoyaml
+
+import (
+	"testing"
+)
+
+const testYAML = `# Service configuration
+name: api
+port: 8080
+# Listening addresses
+servers:
+  - host: localhost
+    tls: false
+  - host: example.com
+  # the last server
+  - - a
+    - b
+labels: {}
+hooks: []
+empty: ""
+quoted: "a: b"
+script: |
+  echo hello
+    echo world
+motd: |-
+  line 1
+
+  line 2
+`
+
+const testNano = "// Service configuration\n{\nname api\nport 8080\n// Listening addresses\nservers [\n{\nhost localhost\ntls false\n}\n{\nhost example.com\n}\n// the last server\n[\na\nb\n]\n]\nlabels {\n}\nhooks [\n]\nempty \nquoted a: b\nscript `\necho hello\n  echo world\n\n`\nmotd `\nline 1\n\nline 2\n`\n}\n"
+
+func TestFromYAML(t *testing.T) {
+	testCases := []struct {
+		v    string
+		want string
+	}{
+		{v: "", want: ""},
+		{v: "hello world\n", want: "hello world\n"},
+		{v: "---\n- 1\n- '2'' '\n- \"3\\t\" # three\n-\n  - 4\n- key: 5\n", want: "[\n1\n2' \n// three\n3\t\n[\n4\n]\n{\nkey 5\n}\n]\n"},
+		{v: "list:\n- a\n- b\nnext: c # comment\n", want: "{\nlist [\na\nb\n]\n// comment\nnext c\n}\n"},
+		{v: "a:\n  b:\n    c: d\n  # end of b\ne: f\n", want: "{\na {\nb {\nc d\n}\n// end of b\n}\ne f\n}\n"},
+		{v: "text: |+\n  a\n\n\nnext: 1\n", want: "{\ntext `\na\n\n\n\n`\nnext 1\n}\n"},
+		{v: "text: |2\n    indented\n  line\n", want: "{\ntext `\n  indented\nline\n\n`\n}\n"},
+		{v: testYAML, want: testNano},
+	}
+
+	for _, item := range testCases {
+		out, err := FromYAML([]byte(item.v))
+		if err != nil {
+			t.Errorf("[FromYAML] in: %s; error: %s", item.v, err)
+		} else if string(out) != item.want {
+			t.Errorf("[FromYAML] in: %s; out: %q; want: %q", item.v, out, item.want)
+		}
+	}
+
+	invalid := []string{"a: 1\n  b: 2\n", "a: {x: 1}\n", "a: >\n  text\n", "\ta: 1\n", "a: 'x\n", "a\nb: 1\n", "a: 1\n- b\n"}
+	for _, item := range invalid {
+		if _, err := FromYAML([]byte(item)); err == nil {
+			t.Errorf("[FromYAML] in: %s; want: error", item)
+		}
+	}
+}
+
+func TestToYAML(t *testing.T) {
+	testCases := []struct {
+		v    string
+		want string
+	}{
+		{v: "", want: ""},
+		{v: "hello world\n", want: "hello world\n"},
+		{v: "{\n}\n", want: "{}\n"},
+		{v: "[\n# 1\n- a\n{\n/* the key\nof the entity */\nkey value\n}\n]\n", want: "- \"# 1\"\n- \"- a\"\n-\n  # the key\n  #of the entity\n  key: value\n"},
+		{v: "{\nlines `\n  a\nb\n`\n}\n", want: "lines: |2-\n    a\n  b\n"},
+		{v: "{\na 1\n}\n// the end\n", want: "a: 1\n# the end\n"},
+		{v: "{\nport 8080 // the port\nhosts [\na // the first\n]\n}\n", want: "port: 8080 # the port\nhosts:\n  - a # the first\n"},
+		{v: testNano, want: testYAML},
+	}
+
+	for _, item := range testCases {
+		out, err := ToYAML([]byte(item.v))
+		if err != nil {
+			t.Errorf("[ToYAML] in: %s; error: %s", item.v, err)
+		} else if string(out) != item.want {
+			t.Errorf("[ToYAML] in: %s; out: %q; want: %q", item.v, out, item.want)
+		}
+	}
+
+	if _, err := ToYAML([]byte("{\nkey value\n")); err == nil {
+		t.Errorf("[ToYAML] in: invalid data; want: error")
+	}
+}
@@ -0,0 +1,178 @@
+package nanoyaml
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/nanomarkup/nanomarkup.go/internal/nanotree"
+	"github.com/nanomarkup/nanomarkup.go/nanocomment"
+)
+
+// writeDocument appends the YAML encoding of the nano value to dst.
+func writeDocument(dst []byte, n *nanotree.Node) []byte {
+	if n == nil {
+		return dst
+	}
+	dst = appendComments(dst, n.Comments, 0)
+	switch {
+	case n.Kind == '{' && len(n.Items) > 0:
+		return appendEntity(dst, n, 0)
+	case n.Kind == '[' && len(n.Items) > 0:
+		return appendArray(dst, n, 0)
+	default:
+		dst = appendValue(dst, n, 0)
+		dst = append(dst, '\n')
+		return appendComments(dst, n.End, 0)
+	}
+}
+
+func appendEntity(dst []byte, n *nanotree.Node, indent int) []byte {
+	for i, key := range n.Keys {
+		it := n.Items[i]
+		dst = appendComments(dst, it.Comments, indent)
+		dst = appendIndent(dst, indent)
+		dst = appendScalar(dst, key)
+		dst = append(dst, ':')
+		if len(it.Items) > 0 {
+			dst = append(dst, '\n')
+			dst = appendCollection(dst, it, indent+2)
+			continue
+		}
+		dst = append(dst, ' ')
+		dst = appendValue(dst, it, indent)
+		dst = append(dst, '\n')
+		dst = appendComments(dst, it.End, indent+2)
+	}
+	return appendComments(dst, n.End, indent)
+}
+
+func appendArray(dst []byte, n *nanotree.Node, indent int) []byte {
+	for _, it := range n.Items {
+		dst = appendComments(dst, it.Comments, indent)
+		dst = appendIndent(dst, indent)
+		if len(it.Items) > 0 {
+			if len(it.Items[0].Comments) > 0 {
+				// the comments of the first item are written after the dash
+				dst = append(dst, "-\n"...)
+				dst = appendCollection(dst, it, indent+2)
+			} else {
+				// the first item is written at the line of the dash
+				dst = append(dst, "- "...)
+				dst = append(dst, appendCollection([]byte{}, it, indent+2)[indent+2:]...)
+			}
+			continue
+		}
+		dst = append(dst, "- "...)
+		dst = appendValue(dst, it, indent)
+		dst = append(dst, '\n')
+		dst = appendComments(dst, it.End, indent+2)
+	}
+	return appendComments(dst, n.End, indent)
+}
+
+func appendCollection(dst []byte, n *nanotree.Node, indent int) []byte {
+	if n.Kind == '{' {
+		return appendEntity(dst, n, indent)
+	}
+	return appendArray(dst, n, indent)
+}
+
+// appendValue appends a scalar with its trailing comment, an empty collection or a literal block of a multi-line value.
+func appendValue(dst []byte, n *nanotree.Node, indent int) []byte {
+	switch {
+	case n.Kind == '{':
+		return append(dst, "{}"...)
+	case n.Kind == '[':
+		return append(dst, "[]"...)
+	case !strings.Contains(n.Value, "\n"):
+		dst = appendScalar(dst, n.Value)
+		if n.Trailing != "" {
+			dst = append(dst, " #"...)
+			dst = append(dst, strings.TrimRight(n.Trailing, " \t")...)
+		}
+		return dst
+	}
+	text := strings.TrimRight(n.Value, "\n")
+	trailing := len(n.Value) - len(text)
+	dst = append(dst, '|')
+	if strings.HasPrefix(strings.TrimLeft(text, "\n"), " ") {
+		// the indentation of the block cannot be detected by the first line
+		dst = append(dst, '2')
+	}
+	switch trailing {
+	case 0:
+		dst = append(dst, '-')
+	case 1:
+	default:
+		dst = append(dst, '+')
+	}
+	lines := strings.Split(text, "\n")
+	for i := 1; i < trailing; i++ {
+		lines = append(lines, "")
+	}
+	for _, l := range lines {
+		dst = append(dst, '\n')
+		if l != "" {
+			dst = appendIndent(dst, indent+2)
+			dst = append(dst, l...)
+		}
+	}
+	return dst
+}
+
+// appendScalar appends a plain scalar or a double-quoted scalar if the value is ambiguous in YAML.
+func appendScalar(dst []byte, s string) []byte {
+	if needsQuote(s) {
+		return strconv.AppendQuote(dst, s)
+	}
+	return append(dst, s...)
+}
+
+func needsQuote(s string) bool {
+	if s == "" || s != strings.TrimSpace(s) || strings.ContainsAny(s[:1], ",[]{}#&*!|>'\"%@`") {
+		return true
+	}
+	switch s {
+	case "-", "?", ":", "---", "...":
+		return true
+	}
+	if strings.HasPrefix(s, "- ") || strings.HasPrefix(s, "? ") || strings.HasPrefix(s, ": ") ||
+		strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return true
+	}
+	for _, c := range s {
+		if c < 32 || c == 127 {
+			return true
+		}
+	}
+	return false
+}
+
+// appendComments appends the comments as YAML comments, every line of a multi-line comment is a separate comment.
+func appendComments(dst []byte, comments nanocomment.Comments, indent int) []byte {
+	for _, c := range comments {
+		text := strings.TrimSuffix(string(nanocomment.Marshal(nanocomment.Comments{c})), "\n")
+		if strings.HasPrefix(text, nanocomment.MultilineCommentBegOpCode) {
+			text = strings.TrimSuffix(text[2:], nanocomment.MultilineCommentEndOpCode)
+		} else if strings.HasPrefix(text, nanocomment.SingleCommentOpCode) {
+			text = text[2:]
+		} else {
+			// skip a blank line
+			continue
+		}
+		for _, l := range strings.Split(text, "\n") {
+			dst = appendIndent(dst, indent)
+			dst = append(dst, '#')
+			dst = append(dst, strings.TrimRight(l, " \t")...)
+			dst = append(dst, '\n')
+		}
+	}
+	return dst
+}
+
+func appendIndent(dst []byte, indent int) []byte {
+	for i := 0; i < indent; i++ {
+		dst = append(dst, ' ')
+	}
+	return dst
+}
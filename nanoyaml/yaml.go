@@ -0,0 +1,439 @@
+package nanoyaml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	nanomarkup "github.com/nanomarkup/nanomarkup.go"
+	"github.com/nanomarkup/nanomarkup.go/nanocomment"
+	"github.com/nanomarkup/nanomarkup.go/nanoerror"
+)
+
+// line is a line of YAML data.
+type line struct {
+	num    int
+	indent int
+	raw    string
+	text   string
+}
+
+// parser writes nano reading YAML lines.
+type parser struct {
+	lines    []line
+	pos      int
+	enc      *nanomarkup.Encoder
+	comments nanocomment.Comments
+	// the indentation of the first collected comment
+	commentIndent int
+}
+
+// header is the beginning of a value which follows a key or a dash.
+type header struct {
+	typ     headerType
+	value   string
+	comment string
+	chomp   byte
+	indent  int
+}
+
+type headerType int
+
+const (
+	nestedHeader headerType = iota
+	scalarHeader
+	literalHeader
+	entityHeader
+	arrayHeader
+)
+
+func (p *parser) init(data []string) error {
+	for i, raw := range data {
+		raw = strings.TrimSuffix(raw, "\r")
+		text := strings.TrimLeft(raw, " ")
+		l := line{num: i + 1, indent: len(raw) - len(text), raw: raw, text: text}
+		if strings.HasPrefix(text, "\t") && strings.TrimSpace(text) != "" {
+			return p.error(&l, "tabs are not allowed for indentation")
+		}
+		p.lines = append(p.lines, l)
+	}
+	return nil
+}
+
+func (p *parser) parseDocument() error {
+	l := p.next()
+	if l != nil && (l.text == "---" || strings.HasPrefix(l.text, "--- ")) {
+		p.pos++
+		l = p.next()
+	}
+	if l != nil {
+		if err := p.flushComments(); err != nil {
+			return err
+		}
+		var err error
+		if isSeqItem(l.text) || isKey(l.text) {
+			err = p.parseBlock(l)
+		} else {
+			p.pos++
+			err = p.parseHeader(l, l.text, -1)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if l = p.next(); l != nil && l.text != "..." {
+		return p.error(l, "unexpected data after the value")
+	}
+	return p.flushComments()
+}
+
+// parseBlock parses a mapping or a sequence which starts at the line.
+func (p *parser) parseBlock(l *line) error {
+	if isSeqItem(l.text) {
+		return p.parseSequence(l.indent)
+	}
+	return p.parseMapping(l.indent)
+}
+
+func (p *parser) parseMapping(indent int) error {
+	if err := p.enc.BeginEntity(); err != nil {
+		return err
+	}
+	for l := p.next(); l != nil && l.indent >= indent; l = p.next() {
+		if l.indent > indent {
+			return p.error(l, "unexpected indentation")
+		} else if isSeqItem(l.text) {
+			return p.error(l, "unexpected sequence item")
+		}
+		key, rest, err := p.splitKey(l)
+		if err != nil {
+			return err
+		}
+		p.pos++
+		h, err := p.header(l, rest)
+		if err != nil {
+			return err
+		}
+		p.addComment(h.comment)
+		if err = p.flushComments(); err != nil {
+			return err
+		}
+		if err = p.enc.Key(key); err != nil {
+			return p.error(l, err.Error())
+		}
+		if err = p.parseValue(l, h, indent); err != nil {
+			return err
+		}
+	}
+	if err := p.flushBlockComments(indent); err != nil {
+		return err
+	}
+	return p.enc.EndEntity()
+}
+
+func (p *parser) parseSequence(indent int) error {
+	if err := p.enc.BeginArray(); err != nil {
+		return err
+	}
+	for l := p.next(); l != nil && l.indent >= indent; l = p.next() {
+		if l.indent > indent {
+			return p.error(l, "unexpected indentation")
+		} else if !isSeqItem(l.text) {
+			break
+		}
+		rest := strings.TrimLeft(l.text[1:], " ")
+		if isSeqItem(rest) || isKey(rest) {
+			// the item is a collection which starts at the same line
+			l.indent += len(l.text) - len(rest)
+			l.text = rest
+			if err := p.flushComments(); err != nil {
+				return err
+			}
+			if err := p.parseBlock(l); err != nil {
+				return err
+			}
+			continue
+		}
+		p.pos++
+		h, err := p.header(l, rest)
+		if err != nil {
+			return err
+		}
+		p.addComment(h.comment)
+		if err = p.flushComments(); err != nil {
+			return err
+		}
+		if err = p.parseValue(l, h, indent); err != nil {
+			return err
+		}
+	}
+	if err := p.flushBlockComments(indent); err != nil {
+		return err
+	}
+	return p.enc.EndArray()
+}
+
+// parseHeader parses a value of the line at the top level.
+func (p *parser) parseHeader(l *line, text string, indent int) error {
+	h, err := p.header(l, text)
+	if err != nil {
+		return err
+	}
+	p.addComment(h.comment)
+	if err = p.flushComments(); err != nil {
+		return err
+	}
+	return p.parseValue(l, h, indent)
+}
+
+// parseValue parses the value which starts by the header, the value of a collection is located at the next lines.
+func (p *parser) parseValue(l *line, h header, indent int) error {
+	switch h.typ {
+	case nestedHeader:
+		n := p.next()
+		if n != nil && (n.indent > indent || n.indent == indent && isSeqItem(n.text) && isKey(l.text)) {
+			return p.parseBlock(n)
+		}
+		return p.enc.Encode("", nil)
+	case literalHeader:
+		return p.enc.Encode(p.literal(h, indent), nil)
+	case entityHeader:
+		if err := p.enc.BeginEntity(); err != nil {
+			return err
+		}
+		return p.enc.EndEntity()
+	case arrayHeader:
+		if err := p.enc.BeginArray(); err != nil {
+			return err
+		}
+		return p.enc.EndArray()
+	default:
+		return p.enc.Encode(h.value, nil)
+	}
+}
+
+// header parses the text which follows a key or a dash.
+func (p *parser) header(l *line, text string) (header, error) {
+	h := header{}
+	text = strings.TrimRight(text, " \t")
+	if strings.HasPrefix(text, "#") {
+		h.comment = text[1:]
+		return h, nil
+	} else if text == "" {
+		return h, nil
+	}
+	switch text[0] {
+	case '"', '\'':
+		val, rest, err := unquote(text)
+		if err != nil {
+			return h, p.error(l, err.Error())
+		}
+		if h.comment, err = trailingComment(rest); err != nil {
+			return h, p.error(l, err.Error())
+		}
+		h.typ = scalarHeader
+		h.value = val
+		return h, nil
+	case '|':
+		text, h.comment, _ = strings.Cut(text, " #")
+		h.typ = literalHeader
+		for _, c := range strings.TrimSpace(text[1:]) {
+			if c == '-' || c == '+' {
+				h.chomp = byte(c)
+			} else if c >= '1' && c <= '9' {
+				h.indent = int(c - '0')
+			} else {
+				return h, p.error(l, "invalid literal block header: "+text)
+			}
+		}
+		return h, nil
+	case '>', '&', '*', '!', '%', '@', '`':
+		return h, p.error(l, "unsupported value: "+text)
+	}
+	val, comment, _ := strings.Cut(text, " #")
+	val = strings.TrimRight(val, " \t")
+	h.comment = comment
+	h.typ = scalarHeader
+	switch val {
+	case "{}":
+		h.typ = entityHeader
+	case "[]":
+		h.typ = arrayHeader
+	default:
+		if val[0] == '{' || val[0] == '[' {
+			return h, p.error(l, "flow collections are not supported: "+val)
+		}
+		h.value = val
+	}
+	return h, nil
+}
+
+// literal reads the content of a literal block which follows the line at the indent.
+func (p *parser) literal(h header, indent int) string {
+	block := -1
+	if h.indent > 0 {
+		block = indent + h.indent
+		if indent < 0 {
+			block = h.indent
+		}
+	}
+	lines := []string{}
+	for ; p.pos < len(p.lines); p.pos++ {
+		l := &p.lines[p.pos]
+		if strings.TrimSpace(l.raw) == "" {
+			if block >= 0 && len(l.raw) > block {
+				lines = append(lines, l.raw[block:])
+			} else {
+				lines = append(lines, "")
+			}
+			continue
+		} else if l.indent <= indent {
+			break
+		}
+		if block < 0 {
+			block = l.indent
+		} else if l.indent < block {
+			break
+		}
+		lines = append(lines, l.raw[block:])
+	}
+	// the trailing empty lines belong to the next item unless they are kept
+	trailing := 0
+	for i := len(lines) - 1; i >= 0 && lines[i] == ""; i-- {
+		trailing++
+	}
+	text := strings.Join(lines[:len(lines)-trailing], "\n")
+	switch h.chomp {
+	case '-':
+		return text
+	case '+':
+		return text + strings.Repeat("\n", trailing+1)
+	default:
+		if text == "" {
+			return text
+		}
+		return text + "\n"
+	}
+}
+
+// next returns the next line which contains data collecting the comments before it.
+func (p *parser) next() *line {
+	for ; p.pos < len(p.lines); p.pos++ {
+		l := &p.lines[p.pos]
+		if strings.HasPrefix(l.text, "#") {
+			if len(p.comments) == 0 {
+				p.commentIndent = l.indent
+			}
+			p.addComment(strings.TrimRight(l.text[1:], " \t"))
+		} else if strings.TrimSpace(l.text) != "" {
+			return l
+		}
+	}
+	return nil
+}
+
+func (p *parser) addComment(text string) {
+	if text != "" {
+		p.comments.Add(text, false)
+	}
+}
+
+func (p *parser) flushComments() error {
+	if len(p.comments) == 0 {
+		return nil
+	}
+	err := p.enc.Comments(p.comments)
+	p.comments = nanocomment.Comments{}
+	return err
+}
+
+// flushBlockComments writes the collected comments at the end of a block if they are indented as the block.
+func (p *parser) flushBlockComments(indent int) error {
+	if p.commentIndent < indent {
+		return nil
+	}
+	return p.flushComments()
+}
+
+// splitKey returns the key of the line and the rest of the line.
+func (p *parser) splitKey(l *line) (string, string, error) {
+	text := l.text
+	if text[0] == '"' || text[0] == '\'' {
+		key, rest, err := unquote(text)
+		if err != nil {
+			return "", "", p.error(l, err.Error())
+		}
+		if !strings.HasPrefix(rest, ":") || len(rest) > 1 && rest[1] != ' ' {
+			return "", "", p.error(l, "':' is missing")
+		}
+		return key, strings.TrimLeft(rest[1:], " "), nil
+	}
+	if i := strings.Index(text, ": "); i > 0 {
+		return strings.TrimRight(text[:i], " "), strings.TrimLeft(text[i+2:], " "), nil
+	} else if strings.HasSuffix(text, ":") {
+		return strings.TrimRight(text[:len(text)-1], " "), "", nil
+	}
+	return "", "", p.error(l, "a key is missing")
+}
+
+func (p *parser) error(l *line, msg string) error {
+	return &nanoerror.SyntaxError{Context: "YAML", Line: l.num, Column: l.indent + 1, Err: fmt.Errorf("%s", msg)}
+}
+
+func isSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// isKey reports whether the text is a key with a value.
+func isKey(text string) bool {
+	if text == "" {
+		return false
+	} else if text[0] == '"' || text[0] == '\'' {
+		_, rest, err := unquote(text)
+		return err == nil && (rest == ":" || strings.HasPrefix(rest, ": "))
+	} else if strings.HasPrefix(text, "#") || isSeqItem(text) {
+		return false
+	}
+	val, _, _ := strings.Cut(text, " #")
+	val = strings.TrimRight(val, " \t")
+	return strings.Contains(val, ": ") || strings.HasSuffix(val, ":")
+}
+
+// unquote returns the value of a quoted scalar and the rest of the text.
+func unquote(text string) (string, string, error) {
+	if text[0] == '\'' {
+		val := strings.Builder{}
+		for i := 1; i < len(text); i++ {
+			if text[i] != '\'' {
+				val.WriteByte(text[i])
+			} else if i+1 < len(text) && text[i+1] == '\'' {
+				val.WriteByte('\'')
+				i++
+			} else {
+				return val.String(), strings.TrimLeft(text[i+1:], " "), nil
+			}
+		}
+		return "", "", fmt.Errorf("' is missing")
+	}
+	for i := 1; i < len(text); i++ {
+		if text[i] == '\\' {
+			i++
+		} else if text[i] == '"' {
+			val, err := strconv.Unquote(text[:i+1])
+			if err != nil {
+				return "", "", fmt.Errorf("invalid quoted value: %s", text[:i+1])
+			}
+			return val, strings.TrimLeft(text[i+1:], " "), nil
+		}
+	}
+	return "", "", fmt.Errorf("'\"' is missing")
+}
+
+func trailingComment(text string) (string, error) {
+	if text == "" {
+		return "", nil
+	} else if text[0] == '#' {
+		return text[1:], nil
+	}
+	return "", fmt.Errorf("unexpected data after the value: %s", text)
+}
@@ -5,9 +5,11 @@ import (
 	"encoding"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/nanomarkup/nanomarkup.go/internal/nanotag"
 	"github.com/nanomarkup/nanomarkup.go/nanocomment"
 	"github.com/nanomarkup/nanomarkup.go/nanodecoder"
 	"github.com/nanomarkup/nanomarkup.go/nanoerror"
@@ -19,6 +21,14 @@ type omitEmpty bool
 
 type unmarshalType int64
 
+type marshalOptions struct {
+	style CommentStyle
+}
+
+type unmarshalOptions struct {
+	validators []Validator
+}
+
 const (
 	undefined unmarshalType = iota
 	entity
@@ -26,71 +36,112 @@ const (
 )
 
 const (
-	tagNameDelim     string = " "
-	tagValueDelim    string = ","
-	nanoTagName      string = "nano"
-	nanoTagIgnore    string = "-"
-	nanoTagOmitEmpty string = "omitempty"
+	commentTagName string = "comment"
 )
 
-func marshal(data any, meta *nanometadata.Metadata) ([]byte, error) {
-	val := reflect.ValueOf(data)
-	if isValueNil(val) {
-		return []byte(""), nil
-	}
-	if val.Kind() == reflect.Pointer {
+var (
+	unmarshalerFromType = reflect.TypeOf((*UnmarshalerFrom)(nil)).Elem()
+	unmarshalerType     = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	rawMessageType      = reflect.TypeOf(RawMessage{})
+)
+
+// marshalData is like marshal but a struct is marshaled even if it is empty.
+func marshalData(dst []byte, val reflect.Value, meta *nanometadata.Metadata, o marshalOptions) ([]byte, error) {
+	if val.Kind() == reflect.Pointer && !val.IsNil() {
 		val = val.Elem()
 	}
+	if val.Kind() != reflect.Struct {
+		return marshalItem(dst, val, meta, o)
+	} else if out, ok, err := marshalByMethod(dst, val); ok || err != nil {
+		// check MarshalNanoTo, AppendNano, MarshalNano and MarshalText methods before to do the marshaling
+		return out, err
+	} else {
+		return marshalStruct(dst, val, meta, o)
+	}
+}
+
+// marshalItem is like marshal but appends the trailing comment of the metadata
+// if the value is written as a single line.
+func marshalItem(dst []byte, val reflect.Value, meta *nanometadata.Metadata, o marshalOptions) ([]byte, error) {
+	size := len(dst)
+	out, err := marshal(dst, val, meta, o)
+	if err != nil || meta == nil || meta.Trailing == "" {
+		return out, err
+	}
+	line := out[size:]
+	if len(line) == 0 && size > 0 && out[size-1] == 32 { // space
+		// an empty value after a key is followed by the comment at once
+		out = append(out, nanocomment.SingleCommentOpCode...)
+		return append(out, meta.Trailing...), nil
+	}
+	if len(line) == 0 || bytes.ContainsRune(line, 10) || line[len(line)-1] == 32 || line[len(line)-1] == 9 { // new line, space, tab
+		return out, nil
+	}
+	out = append(out, " "+nanocomment.SingleCommentOpCode...)
+	return append(out, meta.Trailing...), nil
+}
+
+func marshal(dst []byte, val reflect.Value, meta *nanometadata.Metadata, o marshalOptions) ([]byte, error) {
+	if !val.IsValid() || (isValueNil(val) && val.Kind() != reflect.Slice && val.Kind() != reflect.Map) {
+		return dst, nil
+	}
+	// check MarshalNanoTo, AppendNano, MarshalNano and MarshalText methods
+	if out, ok, err := marshalByMethod(dst, val); ok || err != nil {
+		return out, err
+	}
 	switch val.Kind() {
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return []byte(strconv.FormatInt(val.Int(), 10)), nil
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		return []byte(strconv.FormatUint(val.Uint(), 10)), nil
-	case reflect.Float32, reflect.Float64:
-		return []byte(strconv.FormatFloat(val.Float(), 'g', -1, 64)), nil
-	case reflect.Complex64, reflect.Complex128:
-		return []byte(strconv.FormatComplex(val.Complex(), 'g', -1, 128)), nil
-	case reflect.String:
-		return nanostr.Marshal(val.String()), nil
-	case reflect.Bool:
-		return []byte(strconv.FormatBool(val.Bool())), nil
+	case reflect.Pointer, reflect.Interface:
+		return marshal(dst, val.Elem(), meta, o)
 	case reflect.Slice, reflect.Array:
 		if val.Len() == 0 {
-			return []byte("[\n]\n"), nil
+			return append(dst, "[\n]\n"...), nil
 		} else {
-			return marshalSlice(val)
+			return marshalSlice(dst, val, meta, o)
 		}
 	case reflect.Map:
 		if val.Len() == 0 {
-			return []byte("{\n}\n"), nil
+			return append(dst, "{\n}\n"...), nil
 		} else {
-			return marshalMap(val)
+			return marshalMap(dst, val, meta, o)
 		}
 	case reflect.Struct:
 		if val.IsZero() {
-			return []byte("{\n}\n"), nil
+			return append(dst, "{\n}\n"...), nil
 		}
-		return marshalStruct(data, meta)
+		return marshalStruct(dst, val, meta, o)
 	default:
-		return []byte(""), nil
+		return appendScalar(dst, val), nil
 	}
 }
 
-func marshalStruct(data any, meta *nanometadata.Metadata) ([]byte, error) {
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Pointer {
-		val = val.Elem()
+// appendScalar appends a value of a built-in scalar type to dst.
+func appendScalar(dst []byte, val reflect.Value) []byte {
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.AppendInt(dst, val.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.AppendUint(dst, val.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.AppendFloat(dst, val.Float(), 'g', -1, 64)
+	case reflect.Complex64, reflect.Complex128:
+		// strconv does not provide AppendComplex
+		return append(dst, strconv.FormatComplex(val.Complex(), 'g', -1, 128)...)
+	case reflect.String:
+		return nanostr.Append(dst, val.String())
+	case reflect.Bool:
+		return strconv.AppendBool(dst, val.Bool())
+	default:
+		return dst
 	}
+}
+
+func marshalStruct(dst []byte, val reflect.Value, meta *nanometadata.Metadata, o marshalOptions) ([]byte, error) {
 	typ := val.Type()
-	// check MarshalNano and MarshalText methods
-	out, err := marshalStructByMethod(typ, val)
-	if err != nil {
-		return nil, err
-	} else if out != nil {
-		return out, nil
-	}
+	comments := structComments(val)
+	remain := reflect.Value{}
 	// marshal the struct
-	res := []byte("{\n")
+	res := append(dst, "{\n"...)
 	for _, f := range reflect.VisibleFields(typ) {
 		if !f.IsExported() {
 			continue
@@ -99,402 +150,453 @@ func marshalStruct(data any, meta *nanometadata.Metadata) ([]byte, error) {
 		if isValueNil(fv) {
 			continue
 		}
-		// handle a nano tag
-		data := fv.Interface()
-		name := ""
-		omitempty := false
-		tag, ok := f.Tag.Lookup(nanoTagName)
-		if ok {
-			if tag == nanoTagIgnore {
-				continue
-			} else if tag == nanoTagOmitEmpty {
-				omitempty = true
-			} else {
-				items := strings.Split(tag, tagValueDelim)
-				l := len(items)
-				if l == 1 {
-					name = tag
-				} else if l > 1 {
-					if items[0] == nanoTagOmitEmpty {
-						name = items[1]
-						omitempty = true
-					} else if items[1] == nanoTagOmitEmpty {
-						name = items[0]
-						omitempty = true
-					} else {
-						name = items[0]
-					}
-				}
-				if name == nanoTagIgnore {
-					continue
-				} else if name == nanoTagOmitEmpty {
-					name = ""
-				}
-			}
-		}
-		if omitempty && isEmpty(data) {
+		tag := nanotag.Parse(f)
+		if tag.Remain {
+			// the unknown keys are written after the fields
+			remain = fv
 			continue
 		}
-		if name == "" {
-			name = f.Name
+		if tag.Ignore || tag.OmitEmpty && isEmpty(fv.Interface()) {
+			continue
 		}
+		name := tag.Name
 		// handle a metadata
 		var fmeta *nanometadata.Metadata = nil
 		if meta != nil {
-			fmeta := meta.GetField(f.Name)
-			if fmeta != nil && len(fmeta.Comments) > 0 {
-				res = append(res, nanocomment.Marshal(fmeta.Comments)...)
-			}
+			fmeta = meta.GetField(f.Name)
+		}
+		if fmeta != nil && len(fmeta.Comments) > 0 {
+			res = append(res, nanocomment.Marshal(fmeta.Comments)...)
+		} else if text, ok := comments[f.Name]; ok {
+			res = append(res, nanocomment.Marshal(o.comments(text))...)
+		} else if text, ok := f.Tag.Lookup(commentTagName); ok {
+			res = append(res, nanocomment.Marshal(o.comments(text))...)
 		}
-		res = append(res, []byte(name+" ")...)
-		v, e := marshal(data, fmeta)
+		res = append(res, name...)
+		res = append(res, 32) // add a space
+		var e error
+		res, e = marshalItem(res, fv, fmeta, o)
 		if e != nil {
 			return nil, e
 		}
-		res = append(res, v...)
-		if string(res[len(res)-1]) != "\n" {
-			res = append(res, "\n"...)
+		if res[len(res)-1] != 10 { // new line
+			res = append(res, 10)
 		}
 	}
-	res = append(res, []byte("}\n")...)
+	if remain.IsValid() {
+		var e error
+		if res, e = marshalRemain(res, remain, meta, o); e != nil {
+			return nil, e
+		}
+	}
+	res = append(res, "}\n"...)
 	return res, nil
 }
 
-func marshalStructByMethod(typ reflect.Type, val reflect.Value) ([]byte, error) {
-	// check Nano Marshaler
-	marshaler := reflect.TypeOf((*Marshaler)(nil)).Elem()
-	if typ.Implements(marshaler) {
-		if m, ok := val.Interface().(Marshaler); ok {
-			o, err := m.MarshalNano()
-			if err == nil {
-				out := []byte("{\n")
-				out = append(out, o...)
-				out = append(out, []byte("\n}\n")...)
-				return out, nil
-			} else {
-				return nil, err
+// marshalRemain appends the items of the remain field sorted by keys.
+func marshalRemain(dst []byte, val reflect.Value, meta *nanometadata.Metadata, o marshalOptions) ([]byte, error) {
+	if val.Kind() != reflect.Map || val.Type().Key().Kind() != reflect.String {
+		return nil, &nanoerror.InvalidEntityError{Context: "Marshal", Entity: val.Type().String(), Err: fmt.Errorf("the remain field must be a map with string keys")}
+	}
+	keys := val.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	res := dst
+	for _, k := range keys {
+		var imeta *nanometadata.Metadata = nil
+		if meta != nil {
+			imeta = meta.GetField(k.String())
+			if imeta != nil && len(imeta.Comments) > 0 {
+				res = append(res, nanocomment.Marshal(imeta.Comments)...)
 			}
 		}
+		res = append(res, k.String()...)
+		res = append(res, 32) // add a space
+		var e error
+		res, e = marshalItem(res, val.MapIndex(k), imeta, o)
+		if e != nil {
+			return nil, e
+		}
+		if res[len(res)-1] != 10 { // new line
+			res = append(res, 10)
+		}
 	}
-	// check Text Marshaler
-	marshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
-	if typ.Implements(marshaler) {
-		if m, ok := val.Interface().(encoding.TextMarshaler); ok {
-			o, err := m.MarshalText()
-			if err == nil {
-				out := []byte("{\n")
-				out = append(out, o...)
-				out = append(out, []byte("\n}\n")...)
-				return out, nil
-			} else {
-				return nil, err
-			}
+	return res, nil
+}
+
+// structComments returns the comments of the struct fields using the NanoComments method.
+// The method with a pointer receiver is used if the value is addressable.
+func structComments(val reflect.Value) map[string]string {
+	if val.CanAddr() {
+		val = val.Addr()
+	}
+	if !val.CanInterface() {
+		return nil
+	}
+	if c, ok := val.Interface().(Commenter); ok {
+		return c.NanoComments()
+	}
+	return nil
+}
+
+// comments returns the comments of the text in the style of the options.
+// A text which contains the end of a block comment is written as line comments.
+func (o marshalOptions) comments(text string) nanocomment.Comments {
+	out := nanocomment.Comments{}
+	if o.style == BlockComments && !strings.Contains(text, nanocomment.MultilineCommentEndOpCode) {
+		out.Add(" "+text+" ", true)
+		return out
+	}
+	for _, line := range strings.Split(text, "\n") {
+		out.Add(" "+line, false)
+	}
+	return out
+}
+
+// marshalByMethod encodes the value using MarshalNanoTo, AppendNano, MarshalNano or MarshalText methods.
+// Methods with a pointer receiver are used if the value is addressable.
+// The output of the methods except MarshalText is written as is, so the method decides
+// whether the value is a scalar, an array or an entity.
+func marshalByMethod(dst []byte, val reflect.Value) ([]byte, bool, error) {
+	if val.Kind() == reflect.Interface {
+		return dst, false, nil
+	}
+	if val.Kind() != reflect.Pointer && val.CanAddr() {
+		val = val.Addr()
+	}
+	if !val.CanInterface() || val.Kind() == reflect.Pointer && val.IsNil() {
+		// the methods of a nil slice or a nil map, such as an empty RawMessage, are called
+		return dst, false, nil
+	}
+	switch m := val.Interface().(type) {
+	case MarshalerTo:
+		enc := Encoder{buf: dst}
+		if err := enc.encodeTo(m); err != nil {
+			return nil, true, err
+		}
+		return enc.buf, true, nil
+	case AppenderNano:
+		out, err := m.AppendNano(dst)
+		if err != nil {
+			return nil, true, err
+		}
+		return out, true, nil
+	case Marshaler:
+		out, err := m.MarshalNano()
+		if err != nil {
+			return nil, true, err
+		}
+		return append(dst, out...), true, nil
+	case encoding.TextMarshaler:
+		out, err := m.MarshalText()
+		if err != nil {
+			return nil, true, err
 		}
+		return nanostr.Append(dst, string(out)), true, nil
 	}
-	return nil, nil
+	return dst, false, nil
 }
 
-func marshalSlice(value reflect.Value) ([]byte, error) {
-	res := []byte("[\n")
+func marshalSlice(dst []byte, value reflect.Value, meta *nanometadata.Metadata, o marshalOptions) ([]byte, error) {
+	res := append(dst, "[\n"...)
+	var e error
 	for i := 0; i < value.Len(); i++ {
-		v, e := marshal(value.Index(i).Interface(), nil)
+		var imeta *nanometadata.Metadata = nil
+		if meta != nil {
+			imeta = meta.GetItem(i)
+			if imeta == nil {
+				imeta = meta.Elem()
+			}
+			if imeta != nil && len(imeta.Comments) > 0 {
+				res = append(res, nanocomment.Marshal(imeta.Comments)...)
+			}
+		}
+		res, e = marshalItem(res, value.Index(i), imeta, o)
 		if e != nil {
 			return nil, e
 		}
-		res = append(res, v...)
-		if string(res[len(res)-1]) != "\n" {
-			res = append(res, "\n"...)
+		if res[len(res)-1] != 10 { // new line
+			res = append(res, 10)
 		}
 	}
-	res = append(res, []byte("]\n")...)
+	res = append(res, "]\n"...)
 	return res, nil
 }
 
-func marshalMap(value reflect.Value) ([]byte, error) {
-	res := []byte("{\n")
+func marshalMap(dst []byte, value reflect.Value, meta *nanometadata.Metadata, o marshalOptions) ([]byte, error) {
+	res := append(dst, "{\n"...)
 	iter := value.MapRange()
 	for iter.Next() {
-		v, e := marshal(iter.Key().Interface(), nil)
+		key, e := marshal([]byte{}, iter.Key(), nil, o)
 		if e != nil {
 			return nil, e
 		}
-		res = append(res, v...)
+		// the metadata of an item is addressed by the encoded key
+		var imeta *nanometadata.Metadata = nil
+		if meta != nil {
+			imeta = meta.GetField(string(key))
+			if imeta == nil {
+				imeta = meta.Elem()
+			}
+			if imeta != nil && len(imeta.Comments) > 0 {
+				res = append(res, nanocomment.Marshal(imeta.Comments)...)
+			}
+		}
+		res = append(res, key...)
 		res = append(res, 32) // add a space
-		v, e = marshal(iter.Value().Interface(), nil)
+		res, e = marshalItem(res, iter.Value(), imeta, o)
 		if e != nil {
 			return nil, e
 		}
-		res = append(res, v...)
-		if string(res[len(res)-1]) != "\n" {
-			res = append(res, "\n"...)
+		if res[len(res)-1] != 10 { // new line
+			res = append(res, 10)
 		}
 	}
-	res = append(res, []byte("}\n")...)
+	res = append(res, "}\n"...)
 	return res, nil
 }
 
-func unmarshal(d *nanodecoder.Decoder, elem reflect.Value, curr unmarshalType, meta *nanometadata.Metadata) error {
-	ind := -1
-	item, ok := d.Next()
-	comments := nanocomment.Comments{}
-	for ; ok; item, ok = d.Next() {
-		item = bytes.TrimLeft(item, " \t")
-		if len(item) == 0 {
-			continue
+func unmarshal(d *nanodecoder.Decoder, elem reflect.Value, meta *nanometadata.Metadata) error {
+	item, comments, ok, err := nextItem(d)
+	if err != nil {
+		return err
+	}
+	if meta != nil && len(comments) > 0 {
+		meta.Comments.Adds(comments)
+	}
+	if !ok {
+		return nil
+	}
+	return unmarshalItem(d, item, elem, meta)
+}
+
+// unmarshalItem decodes a value which begins with the item.
+// The item is a line of data or the rest of a line after a key.
+func unmarshalItem(d *nanodecoder.Decoder, item []byte, elem reflect.Value, meta *nanometadata.Metadata) error {
+	scalar, comment, ok := nanostr.CutComment(item)
+	if ok && meta != nil {
+		meta.Trailing = comment
+	}
+	if len(scalar) == 0 {
+		// an empty scalar is an empty string of an empty interface and an empty raw message,
+		// other values keep their zero values
+		if elem.Kind() == reflect.Interface && elem.NumMethod() == 0 {
+			elem.Set(reflect.ValueOf(""))
+		} else if elem.Type() == rawMessageType {
+			elem.Set(reflect.ValueOf(RawMessage{}))
 		}
-		switch item[0] {
+		return nil
+	}
+	// check UnmarshalNano and UnmarshalText methods allocating pointers as necessary
+	for {
+		ok, err := unmarshalByMethod(d, item, elem)
+		if ok || err != nil {
+			return err
+		}
+		if elem.Kind() != reflect.Pointer {
+			break
+		}
+		if elem.IsNil() {
+			elem.Set(reflect.New(elem.Type().Elem()))
+		}
+		elem = elem.Elem()
+	}
+	val := bytes.TrimRight(item, " \t")
+	if elem.Kind() == reflect.Interface && elem.NumMethod() == 0 {
+		// an empty interface holds a string, a map[string]any or a []any
+		var v reflect.Value
+		switch {
+		case len(val) == 1 && val[0] == 91: // [
+			v = reflect.New(reflect.TypeOf([]any{})).Elem()
+		case len(val) == 1 && val[0] == 123: // {
+			v = reflect.New(reflect.TypeOf(map[string]any{})).Elem()
+		default:
+			v = reflect.New(reflect.TypeOf("")).Elem()
+		}
+		if err := unmarshalItem(d, item, v, meta); err != nil {
+			return err
+		}
+		elem.Set(v)
+		return nil
+	}
+	if len(val) > 0 {
+		switch val[0] {
 		case 91: // [
-			val := item[1:]
-			if len(val) > 0 && len(strings.TrimSpace(string(val))) > 0 {
+			if len(val) > 1 {
 				return &nanoerror.InvalidEntityError{Context: "Unmarshal", Entity: string(item), Err: fmt.Errorf("the data of an array must be started from a new line")}
 			}
-			if curr == undefined {
-				// set the current type and continue the parsing the rest of data
-				curr = array
-				if meta != nil && len(comments) > 0 {
-					meta.Comments.Adds(comments)
-					comments = nanocomment.Comments{}
-				}
-			} else {
-				// it is an internal array, parse it using other thread/loop
-				e := unmarshal(d, elem, array, meta)
-				if e != nil {
-					return e
-				}
-			}
-		case 93: // ]
-			if curr == array {
-				return nil
-			} else {
-				return &nanoerror.InvalidEntityError{Context: "Unmarshal", Entity: "", Err: fmt.Errorf("'[' is missing")}
-			}
+			return unmarshalArray(d, elem, meta)
 		case 123: // {
-			val := item[1:]
-			if len(val) > 0 && len(strings.TrimSpace(string(val))) > 0 {
+			if len(val) > 1 {
 				return &nanoerror.InvalidEntityError{Context: "Unmarshal", Entity: string(item), Err: fmt.Errorf("the data of an entity must be started from a new line")}
 			}
-			if curr == undefined {
-				// set the current type and continue the parsing the rest of data
-				curr = entity
-				if meta != nil && len(comments) > 0 {
-					meta.Comments.Adds(comments)
-					comments = nanocomment.Comments{}
-				}
-			} else {
-				// it is an internal entity, parse it using other thread/loop
-				var e error
-				if curr == array {
-					val := reflect.New(elem.Type().Elem()).Elem()
-					e = unmarshal(d, val, entity, meta)
-					elem.Set(reflect.Append(elem, val))
-				} else {
-					if meta == nil {
-						e = unmarshal(d, elem, entity, nil)
-					} else {
-						name := elem.Elem().String()
-						meta.AddField(name, &nanometadata.Metadata{})
-						e = unmarshal(d, elem, entity, meta.GetField(name))
-					}
-				}
-				if e != nil {
-					return e
-				}
-			}
+			return unmarshalEntity(d, elem, meta)
+		}
+	}
+	s, err := nanostr.Unmarshal(d, item)
+	if err != nil {
+		return err
+	}
+	return unmarshalValue(elem, string(s))
+}
+
+func unmarshalArray(d *nanodecoder.Decoder, elem reflect.Value, meta *nanometadata.Metadata) error {
+	ind := -1
+	for {
+		item, comments, ok, err := nextItem(d)
+		if err != nil {
+			return err
+		} else if !ok {
+			return &nanoerror.InvalidEntityError{Context: "Unmarshal", Entity: "", Err: fmt.Errorf("']' is missing")}
+		}
+		switch item[0] {
+		case 93: // ]
+			return nil
 		case 125: // }
-			if curr == entity {
-				return nil
-			} else {
-				return &nanoerror.InvalidEntityError{Context: "Unmarshal", Entity: "", Err: fmt.Errorf("'}' is missing")}
-			}
-		default:
-			comms, err := nanocomment.Unmarshal(d, item)
-			if err != nil {
-				return err
-			} else if len(comms) > 0 {
-				if meta != nil {
-					comments = comms
+			return &nanoerror.InvalidEntityError{Context: "Unmarshal", Entity: "", Err: fmt.Errorf("'{' is missing")}
+		}
+		switch elem.Kind() {
+		case reflect.Array:
+			ind++
+			if ind >= elem.Len() {
+				// skip items which do not fit the array
+				if _, err := getItemData(d, item); err != nil {
+					return err
 				}
 				continue
 			}
-			item, err := nanostr.Unmarshal(d, item)
-			if err != nil {
+			imeta := itemMetadata(meta, comments)
+			if err := unmarshalItem(d, item, elem.Index(ind), imeta); err != nil {
 				return err
 			}
+			if imeta != nil {
+				meta.AddItem(ind, imeta)
+			}
+		case reflect.Slice:
+			val := reflect.New(elem.Type().Elem()).Elem()
+			imeta := itemMetadata(meta, comments)
+			if err := unmarshalItem(d, item, val, imeta); err != nil {
+				return err
+			}
+			if imeta != nil {
+				meta.AddItem(elem.Len(), imeta)
+			}
+			elem.Set(reflect.Append(elem, val))
+		default:
+			return &nanoerror.InvalidEntityError{Context: "Unmarshal", Entity: elem.Type().String(), Err: fmt.Errorf("cannot decode an array into")}
+		}
+	}
+}
 
-			switch elem.Kind() {
-			case reflect.Array:
-				ind++
-				elem := elem.Index(ind)
-				if e := unmarshalValue(elem, string(item)); e != nil {
-					return e
-				}
-			case reflect.Slice:
-				val := reflect.New(elem.Type().Elem()).Elem()
-				if e := unmarshalValue(val, string(item)); e != nil {
-					return e
-				}
-				elem.Set(reflect.Append(elem, val))
-			case reflect.Map, reflect.Struct:
-				// check UnmarshalNano and UnmarshalText methods
-				ok, e := unmarshalStructByMethod(d, elem)
-				if e != nil {
-					return e
-				} else if ok {
-					continue
-				}
-				// unmarshal the struct/map
-				s := bytes.TrimLeft(item, " \t")
-				var ks []byte
-				var vs []byte
-				space := bytes.Index(s, []byte(" "))
-				if space > 0 {
-					ks = s[:space]
-					vs = bytes.TrimLeft(s[space+1:], " \t")
-				} else {
-					ks = s
-					vs = []byte{}
-				}
-				vs, err := nanostr.Unmarshal(d, vs)
-				if err != nil {
-					return err
-				}
-
-				if elem.Kind() == reflect.Map {
-					kv := reflect.New(elem.Type().Key()).Elem()
-					vv := reflect.New(elem.Type().Elem()).Elem()
-					if e := unmarshalValue(kv, string(ks)); e != nil {
-						return e
-					}
-					// check inline entity
-					if len(vs) > 0 && vs[0] == 123 { // {
-						if len(bytes.TrimSpace(vs[1:])) > 0 {
-							return &nanoerror.InvalidEntityError{Context: "Unmarshal", Entity: string(item), Err: fmt.Errorf("the data of an entity must be started from a new line")}
-						}
-						if vv.IsNil() {
-							tt := vv.Type()
-							if vv.Kind() == reflect.Pointer {
-								vv = reflect.MakeMap(tt.Elem())
-							} else if tt.Kind() == reflect.Map {
-								vv = reflect.MakeMap(tt)
-							} else {
-								vv = reflect.New(tt).Elem()
-							}
-						}
-						unmarshal(d, vv, entity, meta)
-					} else if e := unmarshalValue(vv, string(vs)); e != nil {
-						return e
-					}
-					elem.SetMapIndex(kv, vv)
-				} else {
-					if field, name, omitempty := getField(elem, string(ks)); field.IsValid() {
-						var vv reflect.Value
-						tt := field.Type()
-						if tt.Kind() == reflect.Pointer {
-							if tt.Elem().Kind() == reflect.Map {
-								vv = reflect.MakeMap(tt.Elem())
-							} else {
-								// vv = reflect.New(tt).Elem()
-								vv = reflect.New(tt.Elem()).Elem()
-							}
-						} else if tt.Kind() == reflect.Map {
-							vv = reflect.MakeMap(tt)
-						} else {
-							vv = reflect.New(tt).Elem()
-						}
-						if len(vs) > 0 {
-							// check for an inline entity/array
-							switch vs[0] {
-							case 91: // [
-								val := vs[1:]
-								if len(val) > 0 && len(strings.TrimSpace(string(val))) > 0 {
-									return &nanoerror.InvalidEntityError{Context: "Unmarshal", Entity: string(item), Err: fmt.Errorf("the data of an array must be started from a new line")}
-								} else {
-									// it is an internal array, parse it using other thread/loop
-									e := unmarshal(d, vv, array, meta)
-									if e != nil {
-										return e
-									}
-								}
-							case 123: // {
-								val := vs[1:]
-								if len(val) > 0 && len(strings.TrimSpace(string(val))) > 0 {
-									return &nanoerror.InvalidEntityError{Context: "Unmarshal", Entity: string(item), Err: fmt.Errorf("the data of an entity must be started from a new line")}
-								} else {
-									// it is an internal entity, parse it using other thread/loop
-									// check UnmarshalNano and UnmarshalText methods
-									// ok, e := unmarshalStructByMethod(d, field)
-									ok, e := unmarshalStructByMethod(d, vv)
-									if e != nil {
-										return e
-									} else if ok {
-										continue
-									}
-									// unmarshal the struct/map
-									if meta == nil {
-										e = unmarshal(d, vv, entity, nil)
-									} else {
-										name := elem.Elem().String()
-										meta.AddField(name, &nanometadata.Metadata{})
-										e = unmarshal(d, vv, entity, meta.GetField(name))
-									}
-									if e != nil {
-										return e
-									}
-								}
-							default:
-								if e := unmarshalValue(vv, string(vs)); e != nil {
-									return e
-								}
-							}
-						}
-						if bool(omitempty) && isEmpty(vv.Interface()) {
-							continue
-						}
-						if tt.Kind() == reflect.Pointer {
-							if vv.CanAddr() {
-								field.Set(vv.Addr())
-							} else {
-								pp := reflect.New(tt.Elem())
-								pp.Elem().Set(vv)
-								field.Set(pp)
-							}
-						} else {
-							field.Set(vv)
-						}
-						if meta != nil && len(comments) > 0 {
-							m := nanometadata.Metadata{}
-							m.Comments.Adds(comments)
-							meta.AddField(name, &m)
-							comments = nanocomment.Comments{}
-						}
-					} else {
-						_, err := getUnmarshalData(d)
-						if err != nil {
-							return err
-						}
-					}
-				}
-			default:
-				if meta != nil && len(comments) > 0 {
-					meta.Comments.Adds(comments)
-					comments = nanocomment.Comments{}
-				}
-				if e := unmarshalValue(elem, string(item)); e != nil {
+func unmarshalEntity(d *nanodecoder.Decoder, elem reflect.Value, meta *nanometadata.Metadata) error {
+	kind := elem.Kind()
+	if kind == reflect.Map && elem.IsNil() {
+		elem.Set(reflect.MakeMap(elem.Type()))
+	} else if kind != reflect.Map && kind != reflect.Struct {
+		return &nanoerror.InvalidEntityError{Context: "Unmarshal", Entity: elem.Type().String(), Err: fmt.Errorf("cannot decode an entity into")}
+	}
+	for {
+		item, comments, ok, err := nextItem(d)
+		if err != nil {
+			return err
+		} else if !ok {
+			return &nanoerror.InvalidEntityError{Context: "Unmarshal", Entity: "", Err: fmt.Errorf("'}' is missing")}
+		}
+		switch item[0] {
+		case 125: // }
+			return nil
+		case 93: // ]
+			return &nanoerror.InvalidEntityError{Context: "Unmarshal", Entity: "", Err: fmt.Errorf("'[' is missing")}
+		}
+		ks, vs := splitItem(item)
+		if kind == reflect.Map {
+			kv := reflect.New(elem.Type().Key()).Elem()
+			vv := reflect.New(elem.Type().Elem()).Elem()
+			if e := unmarshalValue(kv, string(ks)); e != nil {
+				return e
+			}
+			imeta := itemMetadata(meta, comments)
+			if e := unmarshalItem(d, vs, vv, imeta); e != nil {
+				return e
+			}
+			elem.SetMapIndex(kv, vv)
+			if imeta != nil {
+				meta.AddField(string(ks), imeta)
+			}
+			continue
+		}
+		field, name, omitempty := getField(elem, string(ks))
+		if !field.IsValid() {
+			if remain, ok := remainField(elem); ok {
+				if e := unmarshalRemain(d, remain, string(ks), vs, meta, comments); e != nil {
 					return e
 				}
+				continue
 			}
+			// skip an unknown field
+			if _, err := getItemData(d, vs); err != nil {
+				return err
+			}
+			continue
 		}
+		fmeta := itemMetadata(meta, comments)
+		vv := reflect.New(field.Type()).Elem()
+		if e := unmarshalItem(d, vs, vv, fmeta); e != nil {
+			return e
+		}
+		if bool(omitempty) && isEmpty(indirect(vv)) {
+			continue
+		}
+		field.Set(vv)
+		if meta != nil {
+			meta.AddField(name, fmeta)
+			meta.SetKey(name, string(ks))
+		}
+	}
+}
+
+// remainField returns the field which collects the unknown keys of the struct.
+func remainField(elem reflect.Value) (reflect.Value, bool) {
+	for _, f := range reflect.VisibleFields(elem.Type()) {
+		if f.IsExported() && nanotag.Parse(f).Remain {
+			return elem.Field(f.Index[0]), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// unmarshalRemain decodes the value of an unknown key into the remain field.
+func unmarshalRemain(d *nanodecoder.Decoder, field reflect.Value, key string, item []byte, meta *nanometadata.Metadata, comments nanocomment.Comments) error {
+	typ := field.Type()
+	if typ.Kind() != reflect.Map || typ.Key().Kind() != reflect.String {
+		return &nanoerror.InvalidEntityError{Context: "Unmarshal", Entity: typ.String(), Err: fmt.Errorf("the remain field must be a map with string keys")}
+	}
+	if field.IsNil() {
+		field.Set(reflect.MakeMap(typ))
+	}
+	imeta := itemMetadata(meta, comments)
+	vv := reflect.New(typ.Elem()).Elem()
+	if e := unmarshalItem(d, item, vv, imeta); e != nil {
+		return e
 	}
-	// check the end/close operator
-	switch curr {
-	case array:
-		return &nanoerror.InvalidEntityError{Context: "Unmarshal", Entity: "", Err: fmt.Errorf("']' is missing")}
-	case entity:
-		return &nanoerror.InvalidEntityError{Context: "Unmarshal", Entity: "", Err: fmt.Errorf("'}' is missing")}
+	field.SetMapIndex(reflect.ValueOf(key).Convert(typ.Key()), vv)
+	if imeta != nil {
+		meta.AddField(key, imeta)
 	}
 	return nil
 }
 
+// itemMetadata returns the metadata of an item with the comments if the metadata of the parent is requested.
+func itemMetadata(meta *nanometadata.Metadata, comments nanocomment.Comments) *nanometadata.Metadata {
+	if meta == nil {
+		return nil
+	}
+	imeta := &nanometadata.Metadata{}
+	imeta.Comments.Adds(comments)
+	return imeta
+}
+
 func unmarshalValue(v reflect.Value, s string) error {
 	switch v.Kind() {
 	case reflect.Int:
@@ -599,45 +701,42 @@ func unmarshalValue(v reflect.Value, s string) error {
 	return nil
 }
 
-func unmarshalStructByMethod(d *nanodecoder.Decoder, val reflect.Value) (bool, error) {
-	if val.Kind() != reflect.Ptr {
+// unmarshalByMethod decodes the value using UnmarshalNanoFrom, UnmarshalNano or UnmarshalText methods.
+// Methods with a pointer receiver are used if the value is addressable.
+// UnmarshalNano receives the data of the value as is, an entity or an array included.
+func unmarshalByMethod(d *nanodecoder.Decoder, item []byte, val reflect.Value) (bool, error) {
+	if val.Kind() != reflect.Pointer {
+		if !val.CanAddr() {
+			return false, nil
+		}
+		val = val.Addr()
+	} else if typ := val.Type(); !typ.Implements(unmarshalerFromType) && !typ.Implements(unmarshalerType) && !typ.Implements(textUnmarshalerType) {
 		return false, nil
-	}
-	// check NanoUnmarshaler
-	unmarshaler := reflect.TypeOf((*Unmarshaler)(nil)).Elem()
-	if val.Type().Implements(unmarshaler) {
-		m := val.Interface()
-		if m, ok := (m).(Unmarshaler); ok {
-			in, err := getUnmarshalData(d)
-			in = bytes.TrimLeft(in, " \t")
-			if err != nil {
-				return false, err
-			}
-			err = m.UnmarshalNano(in)
-			if err == nil {
-				return true, nil
-			} else {
-				return false, err
-			}
+	} else if val.IsNil() {
+		if !val.CanSet() {
+			return false, nil
 		}
+		val.Set(reflect.New(val.Type().Elem()))
 	}
-	// check TextUnmarshaler
-	unmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
-	if val.Type().Implements(unmarshaler) {
-		m := val.Interface()
-		if m, ok := (m).(encoding.TextUnmarshaler); ok {
-			in, err := getUnmarshalData(d)
-			in = bytes.TrimLeft(in, " \t")
-			if err != nil {
-				return false, err
-			}
-			err = m.UnmarshalText(in)
-			if err == nil {
-				return true, nil
-			} else {
-				return false, err
-			}
+	if !val.CanInterface() {
+		return false, nil
+	}
+	switch m := val.Interface().(type) {
+	case UnmarshalerFrom:
+		dec := Decoder{d: d, value: item, pending: true, scoped: true}
+		return true, dec.decodeFrom(m)
+	case Unmarshaler:
+		in, err := getItemData(d, item)
+		if err != nil {
+			return false, err
 		}
+		return true, m.UnmarshalNano(in)
+	case encoding.TextUnmarshaler:
+		in, err := nanostr.Unmarshal(d, item)
+		if err != nil {
+			return false, err
+		}
+		return true, m.UnmarshalText(in)
 	}
 	return false, nil
 }
@@ -664,130 +763,154 @@ func isValueNil(v reflect.Value) bool {
 	}
 }
 
+func indirect(v reflect.Value) any {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	return v.Interface()
+}
+
 func getField(src reflect.Value, name string) (reflect.Value, string, omitEmpty) {
-	rValue := reflect.Value{}
-	var rEmpty omitEmpty = true
 	if src.Kind() != reflect.Struct {
-		return rValue, name, rEmpty
+		return reflect.Value{}, name, true
 	}
 	// nano tag has more priority than a field of struct
 	for _, f := range reflect.VisibleFields(src.Type()) {
 		if !f.IsExported() {
 			continue
 		}
-		fv := src.Field(f.Index[0])
-		if isValueNil(fv) {
-			continue
-		}
-		tag, ok := f.Tag.Lookup(nanoTagName)
-		if !ok || tag == nanoTagIgnore || tag == nanoTagOmitEmpty {
-			continue
-		}
-		items := strings.Split(tag, tagValueDelim)
-		fn := ""
-		l := len(items)
-		if l == 1 {
-			fn = tag
-		} else if l > 1 {
-			if items[0] == nanoTagOmitEmpty {
-				fn = items[1]
-				rEmpty = true
-			} else if items[1] == nanoTagOmitEmpty {
-				fn = items[0]
-				rEmpty = true
-			} else {
-				fn = items[0]
-			}
-		}
-		if fn != nanoTagIgnore && fn != nanoTagOmitEmpty && fn == name {
-			return fv, f.Name, rEmpty
+		if tag := nanotag.Parse(f); tag.Renamed && !tag.Ignore && !tag.Remain && tag.Name == name {
+			return src.Field(f.Index[0]), f.Name, omitEmpty(tag.OmitEmpty)
 		}
 	}
 	// check field
 	sf, ok := src.Type().FieldByName(name)
 	if !ok {
-		return rValue, sf.Name, rEmpty
-	} else {
-		rValue = src.FieldByName(sf.Name)
-		tag, ok := sf.Tag.Lookup(nanoTagName)
-		if !ok {
-			return rValue, sf.Name, false
-		} else if tag == nanoTagIgnore {
-			return reflect.Value{}, sf.Name, false
-		} else if tag == nanoTagOmitEmpty {
-			return rValue, sf.Name, true
-		}
-		items := strings.Split(tag, tagValueDelim)
-		l := len(items)
-		if l == 1 {
-			if tag == nanoTagIgnore {
-				return reflect.Value{}, sf.Name, false
-			} else if tag == nanoTagOmitEmpty {
-				return rValue, sf.Name, true
-			}
-		} else if l > 1 {
-			if items[0] == nanoTagIgnore || items[1] == nanoTagIgnore {
-				return reflect.Value{}, sf.Name, false
-			} else if items[0] == nanoTagOmitEmpty || items[1] == nanoTagOmitEmpty {
-				return rValue, sf.Name, true
-			}
-		}
-		return rValue, sf.Name, false
+		return reflect.Value{}, sf.Name, true
+	}
+	tag := nanotag.Parse(sf)
+	if tag.Ignore || tag.Remain {
+		return reflect.Value{}, sf.Name, false
 	}
+	return src.FieldByName(sf.Name), sf.Name, omitEmpty(tag.OmitEmpty)
 }
 
-func getUnmarshalData(d *nanodecoder.Decoder) ([]byte, error) {
-	res := []byte{}
-	curr, ok := d.Curr()
-	if !ok {
-		return res, nil
-	}
-	curr = bytes.TrimRight(curr, " ")
-	if !bytes.HasSuffix(curr, []byte{123}) { // {
-		return res, nil
-	}
-	var i int = 1
-	var l int = 0
-	var org []byte
+// nextItem returns the next item skipping comments and empty lines.
+func nextItem(d *nanodecoder.Decoder) ([]byte, nanocomment.Comments, bool, error) {
+	comments := nanocomment.Comments{}
 	item, ok := d.Next()
 	for ; ok; item, ok = d.Next() {
-		org = item
-		item = bytes.TrimLeft(org, " \t")
+		item = bytes.TrimLeft(item, " \t")
 		if len(item) == 0 {
-			res = append(res, org...)
 			continue
 		}
-		// check comments
 		comms, err := nanocomment.Unmarshal(d, item)
 		if err != nil {
-			return []byte{}, err
+			return nil, comments, false, err
 		} else if len(comms) > 0 {
-			res = append(res, []byte(comms.String())...)
+			comments.Adds(comms)
 			continue
 		}
-		// check multi-line value
-		if item[0] == 96 { // `
-			str, err := nanostr.Unmarshal(d, item)
+		return item, comments, true, nil
+	}
+	return nil, comments, false, nil
+}
+
+// splitItem splits the item of an entity to a key and a value.
+func splitItem(item []byte) ([]byte, []byte) {
+	space := bytes.IndexByte(item, 32) // space
+	if space > 0 {
+		return item[:space], bytes.TrimLeft(item[space+1:], " \t")
+	} else {
+		return item, []byte{}
+	}
+}
+
+// getItemData returns the data of a value which begins with the item.
+// The data of an entity or an array contains all nested lines including
+// the closing bracket, the data of a multi-line value includes backticks.
+func getItemData(d *nanodecoder.Decoder, item []byte) ([]byte, error) {
+	val := bytes.TrimRight(item, " \t")
+	if len(val) == 0 {
+		return item, nil
+	}
+	switch val[0] {
+	case 96: // `
+		str, err := nanostr.Unmarshal(d, item)
+		if err != nil {
+			return nil, err
+		}
+		return nanostr.MarshalMultiline(string(str)), nil
+	case 91, 123: // [, {
+	default:
+		// the trailing comment is not a part of the value
+		val, _, _ = nanostr.CutComment(item)
+		return val, nil
+	}
+	res := append([]byte{}, val...)
+	// keep types of opened brackets to recognize the nested data
+	stack := []unmarshalType{entity}
+	if val[0] == 91 { // [
+		stack[0] = array
+	}
+	line, ok := d.Next()
+	for ; ok; line, ok = d.Next() {
+		item = bytes.TrimLeft(line, " \t")
+		if len(item) > 0 {
+			// check comments
+			comms, err := nanocomment.Unmarshal(d, item)
 			if err != nil {
-				return []byte{}, err
+				return nil, err
+			} else if len(comms) > 0 {
+				res = append(res, 10) // new line
+				res = append(res, bytes.TrimSuffix(nanocomment.Marshal(comms), []byte("\n"))...)
+				continue
 			}
-			res = append(res, str...)
-			continue
 		}
-		// check the last char is '{' or '}'
-		item = bytes.TrimRight(item, " ")
-		l = len(item)
-		if item[l-1] == 123 { // {
-			i++
-		} else if l == 1 && item[0] == 125 { // }
-			i--
-			if i == 0 {
+		val = bytes.TrimRight(item, " \t")
+		if len(val) == 1 && (val[0] == 93 || val[0] == 125) { // ], }
+			res = append(res, 10) // new line
+			res = append(res, line...)
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
 				return res, nil
 			}
+			continue
+		}
+		// get a value of the item
+		ks := []byte{}
+		if stack[len(stack)-1] == entity {
+			ks, val = splitItem(val)
+			if len(val) > 0 {
+				ks = append(ks, 32) // space
+			}
+		}
+		switch {
+		case len(val) == 1 && val[0] == 91: // [
+			stack = append(stack, array)
+		case len(val) == 1 && val[0] == 123: // {
+			stack = append(stack, entity)
+		case len(val) > 0 && val[0] == 96: // `
+			str, err := nanostr.Unmarshal(d, val)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, 10) // new line
+			res = append(res, ks...)
+			res = append(res, bytes.TrimSuffix(nanostr.MarshalMultiline(string(str)), []byte("\n"))...)
+			continue
 		}
-		res = append(res, org...)
+		res = append(res, 10) // new line
+		res = append(res, line...)
+	}
+	if stack[len(stack)-1] == array {
+		return nil, &nanoerror.InvalidEntityError{Context: "Unmarshal", Entity: "", Err: fmt.Errorf("']' is missing")}
+	} else {
+		return nil, &nanoerror.InvalidEntityError{Context: "Unmarshal", Entity: "", Err: fmt.Errorf("'}' is missing")}
 	}
-	return res, nil
 }
 
 func appendIndent(dst, src []byte, prefix, indent string) ([]byte, error) {
@@ -960,3 +1083,69 @@ func appendIndentMultiline(d *nanodecoder.Decoder, prefix, currIndent string, fi
 		return nil, &nanoerror.InvalidEntityError{Context: "Indent", Entity: "", Err: fmt.Errorf("'`' is missing")}
 	}
 }
+
+// appendMinify appends the data without indentation, comments and empty lines.
+// The content of multi-line values is appended as is.
+func appendMinify(dst, src []byte) []byte {
+	stack := []unmarshalType{}
+	multi := false
+	comment := false
+	lines := bytes.Split(src, []byte("\n"))
+	for _, line := range lines {
+		if multi {
+			// the content is appended as is up to the closing backtick
+			multi = len(line) != 1 || line[0] != 96
+			dst = append(dst, line...)
+			dst = append(dst, 10) // new line
+			continue
+		} else if comment {
+			comment = !bytes.HasSuffix(bytes.TrimRight(line, " "), []byte("*/"))
+			continue
+		}
+		item := bytes.TrimLeft(line, " \t")
+		if len(item) == 0 {
+			continue
+		} else if len(item) > 1 && item[0] == 47 && (item[1] == 47 || item[1] == 42) { // //, /*
+			comment = item[1] == 42 && !bytes.HasSuffix(bytes.TrimRight(item[2:], " "), []byte("*/"))
+			continue
+		}
+		val := bytes.TrimRight(item, " \t")
+		if len(val) == 1 && (val[0] == 93 || val[0] == 125) { // ], }
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			dst = append(dst, val...)
+			dst = append(dst, 10) // new line
+			continue
+		}
+		// get a value of the item
+		if len(stack) > 0 && stack[len(stack)-1] == entity {
+			var key []byte
+			key, item = splitItem(item)
+			// drop the trailing comment before checking for an empty value
+			item, _, _ = nanostr.CutComment(item)
+			dst = append(dst, key...)
+			if len(item) > 0 {
+				dst = append(dst, 32) // space
+			}
+			val = bytes.TrimRight(item, " \t")
+		}
+		if len(val) == 1 {
+			switch val[0] {
+			case 91: // [
+				stack = append(stack, array)
+				item = val
+			case 123: // {
+				stack = append(stack, entity)
+				item = val
+			case 96: // `
+				multi = true
+				item = val
+			}
+		}
+		item, _, _ = nanostr.CutComment(item)
+		dst = append(dst, item...)
+		dst = append(dst, 10) // new line
+	}
+	return dst
+}
@@ -3,9 +3,12 @@ package nanomarkup
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"testing"
 	"time"
+
+	"github.com/nanomarkup/nanomarkup.go/nanocomment"
 )
 
 type customTime struct {
@@ -26,6 +29,182 @@ func (t *customTime) UnmarshalNano(value []byte) error {
 	return err
 }
 
+type customLevel int
+
+const (
+	levelInfo customLevel = iota
+	levelWarning
+)
+
+func (l customLevel) MarshalText() ([]byte, error) {
+	switch l {
+	case levelInfo:
+		return []byte("info"), nil
+	case levelWarning:
+		return []byte("warning"), nil
+	default:
+		return nil, fmt.Errorf("unknown level: %d", l)
+	}
+}
+
+func (l *customLevel) UnmarshalText(value []byte) error {
+	switch string(value) {
+	case "info":
+		*l = levelInfo
+	case "warning":
+		*l = levelWarning
+	default:
+		return fmt.Errorf("unknown level: %s", value)
+	}
+	return nil
+}
+
+// customTags is stored as a sorted array of keys.
+type customTags map[string]bool
+
+func (c customTags) MarshalNano() ([]byte, error) {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return Marshal(keys, nil)
+}
+
+func (c *customTags) UnmarshalNano(value []byte) error {
+	keys := []string{}
+	if err := Unmarshal(value, &keys, nil); err != nil {
+		return err
+	}
+	*c = customTags{}
+	for _, k := range keys {
+		(*c)[k] = true
+	}
+	return nil
+}
+
+// customPoint is stored as a scalar value.
+type customPoint struct {
+	X int
+	Y int
+}
+
+func (p customPoint) MarshalNano() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d:%d", p.X, p.Y)), nil
+}
+
+func (p *customPoint) UnmarshalNano(value []byte) error {
+	_, err := fmt.Sscanf(string(value), "%d:%d", &p.X, &p.Y)
+	return err
+}
+
+// customCounter has methods with a pointer receiver only.
+type customCounter int
+
+func (c *customCounter) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("#%d", *c)), nil
+}
+
+func (c *customCounter) UnmarshalText(value []byte) error {
+	_, err := fmt.Sscanf(string(value), "#%d", (*int)(c))
+	return err
+}
+
+// customID prefers AppendNano to MarshalNano.
+type customID uint64
+
+func (id customID) AppendNano(dst []byte) ([]byte, error) {
+	dst = append(dst, "id-"...)
+	return strconv.AppendUint(dst, uint64(id), 16), nil
+}
+
+func (id customID) MarshalNano() ([]byte, error) {
+	return nil, fmt.Errorf("MarshalNano must not be called")
+}
+
+// customHex is like customID but it allocates a slice for every value.
+type customHex uint64
+
+func (h customHex) MarshalNano() ([]byte, error) {
+	return strconv.AppendUint([]byte("id-"), uint64(h), 16), nil
+}
+
+// customRing writes and reads its items incrementally.
+type customRing struct {
+	Name  string
+	items []int
+}
+
+func (r *customRing) MarshalNanoTo(enc *Encoder) error {
+	if err := enc.BeginEntity(); err != nil {
+		return err
+	}
+	if err := enc.Key("Name"); err != nil {
+		return err
+	}
+	if err := enc.Encode(r.Name, nil); err != nil {
+		return err
+	}
+	comments := nanocomment.Comments{}
+	comments.Add(" the oldest item goes first", false)
+	if err := enc.Comments(comments); err != nil {
+		return err
+	}
+	if err := enc.Key("Items"); err != nil {
+		return err
+	}
+	if err := enc.BeginArray(); err != nil {
+		return err
+	}
+	for _, it := range r.items {
+		if err := enc.Encode(it, nil); err != nil {
+			return err
+		}
+	}
+	if err := enc.EndArray(); err != nil {
+		return err
+	}
+	return enc.EndEntity()
+}
+
+func (r *customRing) UnmarshalNanoFrom(dec *Decoder) error {
+	if t, err := dec.Token(); err != nil {
+		return err
+	} else if t != Delim('{') {
+		return fmt.Errorf("unexpected token: %v", t)
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch t {
+		case Key("Name"):
+			if err = dec.Decode(&r.Name, nil); err != nil {
+				return err
+			}
+		case Key("Items"):
+			if _, err = dec.Token(); err != nil {
+				return err
+			}
+			for dec.More() {
+				it := 0
+				if err = dec.Decode(&it, nil); err != nil {
+					return err
+				}
+				r.items = append(r.items, it)
+			}
+			if _, err = dec.Token(); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unexpected token: %v", t)
+		}
+	}
+	_, err := dec.Token()
+	return err
+}
+
 func anyToStr(v any) string {
 	val := reflect.ValueOf(v)
 	switch val.Kind() {
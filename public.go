@@ -2,93 +2,200 @@ package nanomarkup
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"reflect"
-	"strconv"
 
 	"github.com/nanomarkup/nanomarkup.go/nanocomment"
 	"github.com/nanomarkup/nanomarkup.go/nanodecoder"
 	"github.com/nanomarkup/nanomarkup.go/nanoerror"
 	"github.com/nanomarkup/nanomarkup.go/nanometadata"
-	"github.com/nanomarkup/nanomarkup.go/nanostr"
 )
 
+// Marshaler is the interface implemented by types that can marshal themselves into nano data.
+// The output is written as is, so it can be a scalar, an array or an entity.
 type Marshaler interface {
 	MarshalNano() ([]byte, error)
 }
 
+// AppenderNano is the interface implemented by types that can append nano data of themselves to dst.
+// Marshal prefers AppendNano to MarshalNano and appends the value to the output without allocating
+// a separate slice for it, though a value which does not fit in an interface is still copied to call the method.
+type AppenderNano interface {
+	AppendNano(dst []byte) ([]byte, error)
+}
+
+// Unmarshaler is the interface implemented by types that can unmarshal nano data of themselves.
+// The input is the data of a value as is, so it can be a scalar, an array or an entity.
 type Unmarshaler interface {
 	UnmarshalNano([]byte) error
 }
 
+// MarshalerTo is the interface implemented by types that can write nano data of themselves
+// using the encoder. It allows writing keys, values and nested entities incrementally.
+type MarshalerTo interface {
+	MarshalNanoTo(enc *Encoder) error
+}
+
+// UnmarshalerFrom is the interface implemented by types that can read nano data of themselves
+// using the decoder. It allows reading keys, values and nested entities incrementally.
+type UnmarshalerFrom interface {
+	UnmarshalNanoFrom(dec *Decoder) error
+}
+
+// Commenter is the interface implemented by types that can describe their fields.
+// NanoComments returns the comments keyed by the names of struct fields, Marshal writes
+// them before the fields instead of the comments of the comment tags.
+type Commenter interface {
+	NanoComments() map[string]string
+}
+
+// RawMessage is a raw encoded nano value. It can be used to delay decoding of a value
+// or to keep the unknown keys of a struct in the remain field as is.
+// An empty value is decoded to an empty RawMessage, and an empty RawMessage is encoded as an empty value.
+type RawMessage []byte
+
+// MarshalNano returns m as the encoding of m.
+func (m RawMessage) MarshalNano() ([]byte, error) {
+	return m, nil
+}
+
+// UnmarshalNano sets *m to a copy of data.
+func (m *RawMessage) UnmarshalNano(data []byte) error {
+	if m == nil {
+		return &nanoerror.InvalidArgumentError{Context: "Unmarshal", Err: fmt.Errorf("RawMessage is Nil")}
+	}
+	*m = append((*m)[0:0], data...)
+	return nil
+}
+
+// An Encoder writes nano data to an output stream.
+type Encoder struct {
+	w      io.Writer
+	buf    []byte
+	prefix string
+	indent string
+	stack  []unmarshalType
+	key    bool
+	first  bool
+	opts   marshalOptions
+}
+
+// A Decoder reads and decodes nano data from an input stream.
+type Decoder struct {
+	d        *nanodecoder.Decoder
+	stack    []unmarshalType
+	comments nanocomment.Comments
+	// the trailing comment of the last value token
+	trailing string
+	// the rest of a line after a key or the first item of a nested value
+	value   []byte
+	pending bool
+	// the item which is read in advance by More
+	peek         []byte
+	peekComments nanocomment.Comments
+	peekOk       bool
+	peeked       bool
+	scoped       bool
+}
+
+// Validator is the interface implemented by types that can check nano data before decoding,
+// like a schema of the nanoschema package.
+type Validator interface {
+	Validate(data []byte) []error
+}
+
+// MarshalOption configures the encoding of Marshal and an Encoder.
+type MarshalOption func(*marshalOptions)
+
+// CommentStyle specifies how Marshal writes the comments of struct fields.
+type CommentStyle int
+
+const (
+	// LineComments writes every line of a comment after "//".
+	LineComments CommentStyle = iota
+	// BlockComments writes a comment between "/*" and "*/".
+	BlockComments
+)
+
+// UnmarshalOption configures the decoding of Unmarshal.
+type UnmarshalOption func(*unmarshalOptions)
+
+// FormatOption configures the layout of Format.
+type FormatOption func(*formatOptions)
+
+// MergeOption configures Merge.
+type MergeOption func(*mergeOptions)
+
+// ArrayStrategy specifies how Merge combines arrays.
+type ArrayStrategy int
+
+const (
+	// ReplaceArrays replaces an array of the base document by an array of the overlay.
+	ReplaceArrays ArrayStrategy = iota
+	// AppendArrays appends items of an array of the overlay to an array of the base document.
+	AppendArrays
+	// MergeArraysByKey merges entities of arrays which have the same value of the merge key
+	// and appends the other items.
+	MergeArraysByKey
+)
+
+// DeleteMarker is the value of an overlay which deletes the item of an entity.
+const DeleteMarker string = "~delete"
+
+// A Token holds a value of one of these types:
+//
+//	Delim, for the four nano delimiters [ ] { }
+//	Key, for keys of an entity
+//	string, for scalar and multi-line values
+type Token any
+
+// A Delim is a nano array or entity delimiter, one of [ ] { }.
+type Delim rune
+
+// A Key is a key of an entity item.
+type Key string
+
 // Marshal returns the encoding data for the input value.
 //
 // It traverses the value recursively.
-func Marshal(data any, meta *nanometadata.Metadata) ([]byte, error) {
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Pointer {
-		val = val.Elem()
+// If a value implements MarshalerTo, AppenderNano, Marshaler or encoding.TextMarshaler,
+// Marshal calls its MarshalNanoTo, AppendNano, MarshalNano or MarshalText method.
+//
+// The comments of the metadata are written before the values and the trailing comments
+// are written after the values which are written on a single line.
+// If a struct field has no comments in the metadata, Marshal writes the comment which is
+// returned by the NanoComments method of the struct or the value of the comment tag of the field:
+//
+//	Port int `comment:"Listening port"`
+//
+// The items of the map of a struct field which has the remain option of the nano tag
+// are written after the other fields:
+//
+//	Extra map[string]any `nano:",remain"`
+func Marshal(data any, meta *nanometadata.Metadata, opts ...MarshalOption) ([]byte, error) {
+	o := marshalOptions{}
+	for _, opt := range opts {
+		opt(&o)
 	}
 	out := []byte("")
-	var err error = nil
 	if meta != nil && len(meta.Comments) > 0 {
 		out = append(out, nanocomment.Marshal(meta.Comments)...)
 	}
-	switch val.Kind() {
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		out = append(out, []byte(strconv.FormatInt(val.Int(), 10))...)
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		out = append(out, []byte(strconv.FormatUint(val.Uint(), 10))...)
-	case reflect.Float32, reflect.Float64:
-		out = append(out, []byte(strconv.FormatFloat(val.Float(), 'g', -1, 64))...)
-	case reflect.Complex64, reflect.Complex128:
-		out = append(out, []byte(strconv.FormatComplex(val.Complex(), 'g', -1, 128))...)
-	case reflect.String:
-		out = append(out, nanostr.Marshal(val.String())...)
-	case reflect.Bool:
-		out = append(out, []byte(strconv.FormatBool(val.Bool()))...)
-	case reflect.Slice, reflect.Array:
-		if val.Len() == 0 {
-			out = append(out, []byte("[\n]\n")...)
-		} else {
-			if o, err := marshalSlice(val); err == nil {
-				out = append(out, o...)
-			} else {
-				out = []byte("")
-			}
-		}
-	case reflect.Map:
-		if val.Len() == 0 {
-			out = append(out, []byte("{\n}\n")...)
-		} else {
-			if o, err := marshalMap(val); err == nil {
-				out = append(out, o...)
-			} else {
-				out = []byte("")
-			}
-		}
-	case reflect.Struct:
-		typ := val.Type()
-		// check MarshalNano and MarshalText methods before to do the marshaling
-		if o, err := marshalStructByMethod(typ, val); err == nil {
-			if o != nil {
-				out = append(out, o...)
-			} else {
-				// marshal the struct
-				if o, err := marshalStruct(data, meta); err == nil {
-					out = append(out, o...)
-				} else {
-					out = []byte("")
-				}
-			}
-		}
+	return marshalData(out, reflect.ValueOf(data), meta, o)
+}
+
+// WithCommentStyle sets the style of the comments of struct fields, the default style is LineComments.
+func WithCommentStyle(style CommentStyle) MarshalOption {
+	return func(o *marshalOptions) {
+		o.style = style
 	}
-	return out, err
 }
 
 // MarshalIndent is like Marshal but applies Indent to format the output.
-func MarshalIndent(data any, prefix, indent string) ([]byte, error) {
-	enc, err := Marshal(data, nil)
+func MarshalIndent(data any, prefix, indent string, opts ...MarshalOption) ([]byte, error) {
+	enc, err := Marshal(data, nil, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -104,8 +211,18 @@ func MarshalIndent(data any, prefix, indent string) ([]byte, error) {
 // If v is nil or not a pointer, Unmarshal returns an InvalidArgumentError.
 //
 // It uses the inverse of the encodings that Marshal uses, allocating
-// maps, slices, and pointers as necessary.
-func Unmarshal(data []byte, v any, meta *nanometadata.Metadata) error {
+// maps, slices, and pointers as necessary. If a value implements UnmarshalerFrom, Unmarshaler
+// or encoding.TextUnmarshaler, Unmarshal calls its UnmarshalNanoFrom, UnmarshalNano or UnmarshalText method.
+//
+// A comment which begins with "//" after a space or a tab on the line of a single-line value
+// is a trailing comment, it is not a part of the value and it is stored in the metadata of the value.
+// The value can contain "//" after a whitespace if it is escaped as "\//".
+//
+// The keys of an entity which do not match struct fields are skipped unless the struct has
+// a field with the remain option of the nano tag, which is a map with string keys like
+// map[string]any or map[string]RawMessage. An empty interface holds a string for a scalar,
+// a map[string]any for an entity and a []any for an array.
+func Unmarshal(data []byte, v any, meta *nanometadata.Metadata, opts ...UnmarshalOption) error {
 	rv := reflect.ValueOf(v)
 	if rv.Kind() != reflect.Pointer {
 		return &nanoerror.InvalidArgumentError{Context: "Unmarshal", Err: fmt.Errorf("the second argument is not a Pointer")}
@@ -117,9 +234,44 @@ func Unmarshal(data []byte, v any, meta *nanometadata.Metadata) error {
 	if !elem.CanSet() {
 		return &nanoerror.InvalidArgumentError{Context: "Unmarshal", Err: fmt.Errorf("the second argument is not settable")}
 	}
+	o := unmarshalOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	for _, val := range o.validators {
+		if errs := val.Validate(data); len(errs) > 0 {
+			return errors.Join(errs...)
+		}
+	}
 	d := nanodecoder.Decoder{}
 	d.Init(bytes.Split(data, []byte("\n")))
-	return unmarshal(&d, elem, undefined, meta)
+	return unmarshal(&d, elem, meta)
+}
+
+// WithValidator instructs Unmarshal to check data using the validator before decoding.
+// Unmarshal returns all errors of the validator joined together.
+func WithValidator(v Validator) UnmarshalOption {
+	return func(o *unmarshalOptions) {
+		o.validators = append(o.validators, v)
+	}
+}
+
+// NewEncoder returns a new encoder that writes to w.
+// The options are applied to every encoded value like the options of Marshal.
+func NewEncoder(w io.Writer, opts ...MarshalOption) *Encoder {
+	e := &Encoder{w: w, first: true}
+	for _, opt := range opts {
+		opt(&e.opts)
+	}
+	return e
+}
+
+// NewDecoder returns a new decoder that reads from r.
+// The decoder reads lines from r on demand.
+func NewDecoder(r io.Reader) *Decoder {
+	d := nanodecoder.Decoder{}
+	d.InitReader(r)
+	return &Decoder{d: &d}
 }
 
 // Indent function appends to `dst` the nano-encoded source (`src`) in an indented format.
@@ -135,6 +287,18 @@ func Indent(dst *bytes.Buffer, src []byte, prefix, indent string) error {
 	return err
 }
 
+// Valid reports whether data is a valid nano encoding.
+func Valid(data []byte) bool {
+	return len(validate(data)) == 0
+}
+
+// Validate checks the syntax of data without decoding it and returns all found errors.
+// Every error is a *nanoerror.SyntaxError that holds the position of the problem.
+// It returns nil if data is a valid nano encoding.
+func Validate(data []byte) []error {
+	return validate(data)
+}
+
 // Compact appends the nano-encoded src to dst, eliminating insignificant space characters.
 func Compact(dst *bytes.Buffer, src []byte) error {
 	dst.Grow(len(src))
@@ -143,3 +307,109 @@ func Compact(dst *bytes.Buffer, src []byte) error {
 	dst.Write(b)
 	return err
 }
+
+// Minify appends the nano-encoded src to dst, eliminating insignificant space characters,
+// comments and empty lines. The content of multi-line values is kept as is.
+//
+// Minify returns the errors of Validate joined together if src is not a valid nano encoding.
+func Minify(dst *bytes.Buffer, src []byte) error {
+	if errs := validate(src); len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	dst.Grow(len(src))
+	b := dst.AvailableBuffer()
+	b = appendMinify(b, src)
+	dst.Write(b)
+	return nil
+}
+
+// Format returns the nano-encoded src in the canonical layout.
+// Nested items are indented by one tab per level, all comments are kept,
+// runs of blank lines are collapsed into one and blank lines at the beginning
+// and the end of entities and arrays are removed. The content of multi-line values
+// and multi-line comments is kept as is. Formatting the output again does not change it.
+//
+// Format returns the errors of Validate joined together if src is not a valid nano encoding.
+func Format(src []byte, opts ...FormatOption) ([]byte, error) {
+	if errs := validate(src); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	o := formatOptions{tabs: true, width: 4}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return appendFormat(make([]byte, 0, len(src)+len(src)/4), src, o), nil
+}
+
+// WithTabs instructs Format to indent nested items by tabs or spaces, tabs are used by default.
+func WithTabs(tabs bool) FormatOption {
+	return func(o *formatOptions) {
+		o.tabs = tabs
+	}
+}
+
+// WithIndentWidth sets the number of spaces of one indentation level when Format indents by spaces.
+// The default width is 4.
+func WithIndentWidth(width int) FormatOption {
+	return func(o *formatOptions) {
+		o.width = max(width, 0)
+	}
+}
+
+// WithKeyAlignment instructs Format to align the values of consecutive single-line items of an entity.
+func WithKeyAlignment(align bool) FormatOption {
+	return func(o *formatOptions) {
+		o.align = align
+	}
+}
+
+// Merge merges the overlay into the base document and returns the result.
+//
+// Entities are merged recursively: the items of the overlay replace the items of the base document
+// with the same keys, new items are added to the end and the items which have the DeleteMarker value
+// are deleted. Arrays are replaced by default, see ArrayStrategy for other options.
+// Other values of the overlay replace the values of the base document.
+// The comments of the base document are kept, the comments of the overlay are used for new items.
+//
+// Merge returns the errors of Validate joined together if a document is not a valid nano encoding.
+func Merge(base, overlay []byte, opts ...MergeOption) ([]byte, error) {
+	o := mergeOptions{arrays: ReplaceArrays, key: "name"}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	b, bfooter, err := readMergeDocument(base)
+	if err != nil {
+		return nil, err
+	}
+	v, vfooter, err := readMergeDocument(overlay)
+	if err != nil {
+		return nil, err
+	}
+	if len(bfooter) == 0 {
+		bfooter = vfooter
+	}
+	switch {
+	case v == nil:
+	case isDelete(v):
+		b = nil
+	case b == nil:
+		b = clean(v)
+	default:
+		b = mergeValues(b, v, &o)
+	}
+	return writeMergeDocument(b, bfooter)
+}
+
+// WithArrayStrategy sets the strategy of merging arrays, the default strategy is ReplaceArrays.
+func WithArrayStrategy(strategy ArrayStrategy) MergeOption {
+	return func(o *mergeOptions) {
+		o.arrays = strategy
+	}
+}
+
+// WithMergeKey sets the key which identifies entities of arrays for MergeArraysByKey, the default key is "name".
+func WithMergeKey(key string) MergeOption {
+	return func(o *mergeOptions) {
+		o.key = key
+	}
+}
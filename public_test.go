@@ -158,6 +158,19 @@ value`
 	}
 }
 
+func TestIndentTrailingComment(t *testing.T) {
+	in := "{\nport 8080 // default HTTP port\nhosts [\na \\// b // the first host\n]\n}\n"
+	want := "{\n##  port 8080 // default HTTP port\n##  hosts [\n##    a \\// b // the first host\n##  ]\n##}\n"
+	dst := bytes.Buffer{}
+	if err := Indent(&dst, []byte(in), "##", "  "); err != nil {
+		t.Error(err)
+		return
+	}
+	if out := dst.String(); out != want {
+		t.Errorf("[Indent] in: %s; out: %s; want: %s", in, out, want)
+	}
+}
+
 func TestIndentComment(t *testing.T) {
 	// test a string
 	sin := `testing
@@ -291,3 +304,185 @@ RequestURI
 		t.Errorf("[Compact] in: %s; out: %s; want: %s", ind.String(), out, string(enc))
 	}
 }
+
+func TestValid(t *testing.T) {
+	testCases := []struct {
+		v    string
+		want bool
+	}{
+		{v: "", want: true},
+		{v: "hello world", want: true},
+		{v: "// comment\n{\nKey value\n/* multi\nline */\nList [\n1\n{\nA b\n}\n]\nText `\nline }\n`\n}\n", want: true},
+		{v: "{\nKey value\n", want: false},
+		{v: "[\n1\n}\n", want: false},
+		{v: "{\nKey {value\n}\n", want: false},
+		{v: "Text `\nline\n", want: false},
+		{v: "{\n}\n{\n}\n", want: false},
+		{v: "/* comment\n1\n", want: false},
+	}
+
+	for _, item := range testCases {
+		if out := Valid([]byte(item.v)); out != item.want {
+			t.Errorf("[Valid] in: %s; out: %t; want: %t", item.v, out, item.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	in := `{
+	Key value
+	List [
+		1
+		}
+	]
+	Entity {inline
+	Text ` + "`" + `
+line
+` + "`" + `
+	]
+`
+	want := []string{
+		"[Validate] line 5, column 3: unexpected '}'",
+		"[Validate] line 7, column 9: the data of an entity must be started from a new line",
+		"[Validate] line 11, column 2: unexpected ']'",
+		"[Validate] line 1, column 1: '}' is missing",
+	}
+	errs := Validate([]byte(in))
+	if len(errs) != len(want) {
+		t.Errorf("[Validate] in: %s; out: %v; want: %v", in, errs, want)
+		return
+	}
+	for i, err := range errs {
+		if err.Error() != want[i] {
+			t.Errorf("[Validate] in: %s; out: %s; want: %s", in, err.Error(), want[i])
+		}
+	}
+
+	// an entity in an entity without a key
+	in = "{\n{\na 1\n}\n}\n"
+	want = []string{"[Validate] line 2, column 1: the key of an entity item is missing"}
+	errs = Validate([]byte(in))
+	if len(errs) != len(want) || errs[0].Error() != want[0] {
+		t.Errorf("[Validate] in: %s; out: %v; want: %v", in, errs, want)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	in := "\n\n// top\n//\n{\n\n  // server\n    server {\n\n\nhost   localhost\nport 8080\n\n\n  timeout_ms 500  \n/* multi\n   line */\nText `\n  line\n\n`\n\n}\nlist [\n  a\n  {\n  x 1\n  }\n]\n\n}\n\n// end\n\n"
+	testCases := []struct {
+		opts []FormatOption
+		want string
+	}{
+		{
+			want: "// top\n//\n{\n\t// server\n\tserver {\n\t\thost localhost\n\t\tport 8080\n\n\t\ttimeout_ms 500  \n\t\t/* multi\n   line */\n\t\tText `\n  line\n\n`\n\t}\n\tlist [\n\t\ta\n\t\t{\n\t\t\tx 1\n\t\t}\n\t]\n}\n\n// end\n",
+		},
+		{
+			opts: []FormatOption{WithTabs(false), WithIndentWidth(2), WithKeyAlignment(true)},
+			want: "// top\n//\n{\n  // server\n  server {\n    host localhost\n    port 8080\n\n    timeout_ms 500  \n    /* multi\n   line */\n    Text `\n  line\n\n`\n  }\n  list [\n    a\n    {\n      x 1\n    }\n  ]\n}\n\n// end\n",
+		},
+		{
+			opts: []FormatOption{WithKeyAlignment(true)},
+			want: "// top\n//\n{\n\t// server\n\tserver {\n\t\thost localhost\n\t\tport 8080\n\n\t\ttimeout_ms 500  \n\t\t/* multi\n   line */\n\t\tText `\n  line\n\n`\n\t}\n\tlist [\n\t\ta\n\t\t{\n\t\t\tx 1\n\t\t}\n\t]\n}\n\n// end\n",
+		},
+	}
+	for _, item := range testCases {
+		out, err := Format([]byte(in), item.opts...)
+		if err != nil || string(out) != item.want {
+			t.Errorf("[Format] out: %q; want: %q; error: %v", out, item.want, err)
+			continue
+		}
+		// the canonical layout is not changed by formatting
+		if again, err := Format(out, item.opts...); err != nil || string(again) != string(out) {
+			t.Errorf("[Format] in: %q; out: %q; error: %v", out, again, err)
+		}
+	}
+
+	in = "{\nname api\nport 8080\ndebug\nmax_connections 10\nlimits {\nrate 5\n}\n}\n"
+	want := "{\n\tname            api\n\tport            8080\n\tdebug\n\tmax_connections 10\n\tlimits {\n\t\trate 5\n\t}\n}\n"
+	if out, err := Format([]byte(in), WithKeyAlignment(true)); err != nil || string(out) != want {
+		t.Errorf("[Format] out: %q; want: %q; error: %v", out, want, err)
+	}
+	if out, err := Format([]byte("")); err != nil || len(out) != 0 {
+		t.Errorf("[Format] out: %q; error: %v", out, err)
+	}
+	if _, err := Format([]byte("{\nKey value\n")); err == nil {
+		t.Errorf("[Format] want: error")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := "// service\n{\n// the name\nname api\n// the server\nserver {\nhost localhost\n// the port\nport 8080\n}\nhosts [\na\nb\n]\nservers [\n{\nname a\nport 1\n}\n{\nname b\nport 2\n}\n]\ndebug true\n}\n// end\n"
+	overlay := "// local\n{\n// a new comment\nname web\nserver {\nport 9090\ntls {\ncert a.pem\nkey ~delete\n}\n}\nhosts [\nc\n]\nservers [\n{\nname b\nport 3\n}\n{\nname c\nport 4\n}\n]\ndebug ~delete\n}\n"
+	testCases := []struct {
+		opts []MergeOption
+		want string
+	}{
+		{
+			want: "// service\n{\n// the name\nname web\n// the server\nserver {\nhost localhost\n// the port\nport 9090\ntls {\ncert a.pem\n}\n}\nhosts [\nc\n]\nservers [\n{\nname b\nport 3\n}\n{\nname c\nport 4\n}\n]\n}\n// end\n",
+		},
+		{
+			opts: []MergeOption{WithArrayStrategy(AppendArrays)},
+			want: "// service\n{\n// the name\nname web\n// the server\nserver {\nhost localhost\n// the port\nport 9090\ntls {\ncert a.pem\n}\n}\nhosts [\na\nb\nc\n]\nservers [\n{\nname a\nport 1\n}\n{\nname b\nport 2\n}\n{\nname b\nport 3\n}\n{\nname c\nport 4\n}\n]\n}\n// end\n",
+		},
+		{
+			opts: []MergeOption{WithArrayStrategy(MergeArraysByKey)},
+			want: "// service\n{\n// the name\nname web\n// the server\nserver {\nhost localhost\n// the port\nport 9090\ntls {\ncert a.pem\n}\n}\nhosts [\na\nb\nc\n]\nservers [\n{\nname a\nport 1\n}\n{\nname b\nport 3\n}\n{\nname c\nport 4\n}\n]\n}\n// end\n",
+		},
+	}
+	for _, item := range testCases {
+		out, err := Merge([]byte(base), []byte(overlay), item.opts...)
+		if err != nil || string(out) != item.want {
+			t.Errorf("[Merge] out: %q; want: %q; error: %v", out, item.want, err)
+		}
+	}
+
+	want := "[\n{\nid 1\nport 2\n}\n]\n"
+	if out, err := Merge([]byte("[\n{\nid 1\nport 1\n}\n]\n"), []byte("[\n{\nid 1\nport 2\n}\n]\n"), WithArrayStrategy(MergeArraysByKey), WithMergeKey("id")); err != nil || string(out) != want {
+		t.Errorf("[Merge] out: %q; want: %q; error: %v", out, want, err)
+	}
+	want = "{\na 1\n}\n"
+	if out, err := Merge([]byte(""), []byte("{\na 1\nb ~delete\n}\n")); err != nil || string(out) != want {
+		t.Errorf("[Merge] out: %q; want: %q; error: %v", out, want, err)
+	}
+	if out, err := Merge([]byte(want), []byte("")); err != nil || string(out) != want {
+		t.Errorf("[Merge] out: %q; want: %q; error: %v", out, want, err)
+	}
+	if out, err := Merge([]byte(want), []byte("[\n1\n]\n")); err != nil || string(out) != "[\n1\n]\n" {
+		t.Errorf("[Merge] out: %q; error: %v", out, err)
+	}
+	want = "{\nport 9090 // the port\nname a // the name\nnew 1 // a new value\n}\n"
+	if out, err := Merge([]byte("{\nport 8080 // the port\nname a // the name\n}\n"), []byte("{\nport 9090\nnew 1 // a new value\n}\n")); err != nil || string(out) != want {
+		t.Errorf("[Merge] out: %q; want: %q; error: %v", out, want, err)
+	}
+	if _, err := Merge([]byte("{\n"), []byte(want)); err == nil {
+		t.Errorf("[Merge] want: error")
+	}
+}
+
+func TestMinify(t *testing.T) {
+	in := "\n// top\n{\n\n  // server\n    server {  \n\thost   localhost // the host\n  port 8080\n  url a \\// b // escaped\n\n  timeout_ms 500  \n/* multi\n   line */\n  debug\nText `  \n  // line\n\n/* not a comment */\n`\n}\nlist [\n  a\n  /* one line */\n  {\n  x 1\n  }\n]\n}\n\n// end\n"
+	want := "{\nserver {\nhost localhost\nport 8080\nurl a \\// b\ntimeout_ms 500  \ndebug\nText `\n  // line\n\n/* not a comment */\n`\n}\nlist [\na\n{\nx 1\n}\n]\n}\n"
+	dst := bytes.Buffer{}
+	if err := Minify(&dst, []byte(in)); err != nil || dst.String() != want {
+		t.Errorf("[Minify] out: %q; want: %q; error: %v", dst.String(), want, err)
+	}
+	// the data is not changed
+	out := struct {
+		Server map[string]string `nano:"server"`
+	}{}
+	if err := Unmarshal(dst.Bytes(), &out, nil); err != nil || out.Server["url"] != "a // b" || out.Server["Text"] != "  // line\n\n/* not a comment */" {
+		t.Errorf("[Unmarshal] out: %+v; error: %v", out, err)
+	}
+	dst.Reset()
+	if err := Minify(&dst, []byte("// only a comment\n")); err != nil || dst.Len() != 0 {
+		t.Errorf("[Minify] out: %q; error: %v", dst.String(), err)
+	}
+	// an empty value with a trailing comment
+	dst.Reset()
+	if err := Minify(&dst, []byte("{\nname // the name\nport 8080\n}\n")); err != nil || dst.String() != "{\nname\nport 8080\n}\n" {
+		t.Errorf("[Minify] out: %q; error: %v", dst.String(), err)
+	}
+	if err := Minify(&dst, []byte("{\nKey value\n")); err == nil {
+		t.Errorf("[Minify] want: error")
+	}
+}
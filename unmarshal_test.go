@@ -2,6 +2,8 @@ package nanomarkup
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -267,6 +269,9 @@ func TestStringUnmarshal(t *testing.T) {
 		{v: "go ", want: "go "},
 		{v: " go ", want: "go "},
 		{v: "hello world", want: "hello world"},
+		{v: "`\n\\`\n`\n", want: "`"},
+		{v: "`\n`x\n`\n", want: "`x"},
+		{v: "`\na\n\\`\n\\\\`\n`\n", want: "a\n`\n\\`"},
 	}
 
 	r := new(string)
@@ -759,6 +764,7 @@ func TestNanoTagUnmarshal(t *testing.T) {
 		Field9  string `nano:"omitempty,omitempty"`
 		Field10 int    `nano:"test10"`
 		Field11 string `nano:"test11"`
+		Field12 int    `nano:"test12"`
 	}
 	in := `{
 Field1 1
@@ -772,6 +778,7 @@ test8
 Field9 
 test10 10
 test11 11
+test12 0
 }
 `
 	out := t1{
@@ -786,8 +793,9 @@ test11 11
 		"99",
 		1010,
 		"1111",
+		1212,
 	}
-	want := t1{1, "2", 33, 44, 55, "66", 77, "88", "99", 10, "11"}
+	want := t1{1, "2", 33, 44, 55, "66", 77, "88", "99", 10, "11", 0}
 
 	err := Unmarshal([]byte(in), &out, nil)
 	mes := ""
@@ -801,7 +809,8 @@ test11 11
 		out.Field8 != want.Field8 ||
 		out.Field9 != want.Field9 ||
 		out.Field10 != want.Field10 ||
-		out.Field11 != want.Field11 {
+		out.Field11 != want.Field11 ||
+		out.Field12 != want.Field12 {
 		mes = fmt.Sprintf("[Unmarshal] in: %s; out: %v; want: %v", in, out, want)
 	}
 	if err != nil {
@@ -1165,3 +1174,286 @@ func TestCustomUnmarshalText(t *testing.T) {
 		t.Error(mes)
 	}
 }
+
+func TestCustomKindUnmarshal(t *testing.T) {
+	type test struct {
+		Level   customLevel
+		Levels  []customLevel
+		Tags    customTags
+		Point   customPoint
+		PPoint  *customPoint
+		Counter customCounter
+	}
+	in := `{
+Level warning
+Levels [
+info
+warning
+]
+Tags [
+a
+b
+]
+Point 1:2
+PPoint 3:4
+Counter #5
+}
+`
+	want := test{levelWarning, []customLevel{levelInfo, levelWarning}, customTags{"a": true, "b": true}, customPoint{1, 2}, &customPoint{3, 4}, 5}
+	out := test{}
+	if err := Unmarshal([]byte(in), &out, nil); err != nil {
+		t.Error(err)
+		return
+	}
+	testStructs(t, &want, &out)
+
+	// check the round trip
+	enc, err := Marshal(&out, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(enc) != in {
+		t.Errorf("[Marshal] in: %v; out: %s; want: %s", out, enc, in)
+	}
+}
+
+func TestMetaNestedUnmarshal(t *testing.T) {
+	type server struct {
+		Host  string
+		Ports []int
+	}
+	type config struct {
+		Server  server
+		Servers [2]server
+		Labels  map[string]string
+	}
+	in := `{
+// Main server
+Server {
+// Host name
+Host localhost
+// Ports
+Ports [
+80
+// TLS
+443
+]
+}
+Servers [
+{
+Host a
+}
+// Second server
+{
+// Backup
+Host b
+}
+]
+Labels {
+// Environment
+env prod
+}
+}
+`
+	out := config{}
+	mout := nanometadata.Metadata{}
+	err := Unmarshal([]byte(in), &out, &mout)
+	if err != nil {
+		t.Fatal("[Unmarshal]: " + err.Error())
+	}
+	comment := func(m *nanometadata.Metadata) string {
+		if m == nil {
+			return "<nil>"
+		}
+		return m.Comments.String()
+	}
+	srv := mout.GetField("Server")
+	if s := comment(srv); s != " Main server" {
+		t.Errorf("[Unmarshal] Server meta: %q", s)
+	}
+	if s := comment(srv.GetField("Host")); s != " Host name" {
+		t.Errorf("[Unmarshal] Server.Host meta: %q", s)
+	}
+	if s := comment(srv.GetField("Ports").GetItem(1)); s != " TLS" {
+		t.Errorf("[Unmarshal] Server.Ports[1] meta: %q", s)
+	}
+	second := mout.GetField("Servers").GetItem(1)
+	if s := comment(second); s != " Second server" {
+		t.Errorf("[Unmarshal] Servers[1] meta: %q", s)
+	}
+	if s := comment(second.GetField("Host")); s != " Backup" {
+		t.Errorf("[Unmarshal] Servers[1].Host meta: %q", s)
+	}
+	if s := comment(mout.GetField("Labels").GetField("env")); s != " Environment" {
+		t.Errorf("[Unmarshal] Labels.env meta: %q", s)
+	}
+	// the metadata restores the comments
+	res, err := Marshal(out, &mout)
+	if err != nil {
+		t.Fatal("[Marshal]: " + err.Error())
+	}
+	if string(res) != in {
+		t.Errorf("[Marshal] out: %s\nwant: %s", res, in)
+	}
+}
+
+func TestTrailingUnmarshal(t *testing.T) {
+	type config struct {
+		Port  int
+		URL   string
+		Path  string
+		Hosts []string
+		Name  string
+	}
+	in := "{\nPort 8080   // default HTTP port\nURL a \\// b //escaped\nPath http://localhost\nHosts [\na\nb // the second host\n]\nName // the name\n}\n"
+	want := config{8080, "a // b", "http://localhost", []string{"a", "b"}, ""}
+	out := config{}
+	mout := nanometadata.Metadata{}
+	if err := Unmarshal([]byte(in), &out, &mout); err != nil {
+		t.Fatal("[Unmarshal]: " + err.Error())
+	}
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("[Unmarshal] out: %+v\nwant: %+v", out, want)
+	}
+	trailing := []struct {
+		meta *nanometadata.Metadata
+		want string
+	}{
+		{mout.Lookup("Port"), " default HTTP port"},
+		{mout.Lookup("URL"), "escaped"},
+		{mout.Lookup("Path"), ""},
+		{mout.Lookup("Hosts[0]"), ""},
+		{mout.Lookup("Hosts[1]"), " the second host"},
+		{mout.Lookup("Name"), " the name"},
+	}
+	for i, tt := range trailing {
+		if tt.meta == nil || tt.meta.Trailing != tt.want {
+			t.Errorf("[Unmarshal] case %d meta: %+v; want trailing: %q", i, tt.meta, tt.want)
+		}
+	}
+	// the metadata restores the trailing comments
+	res, err := Marshal(out, &mout)
+	if err != nil {
+		t.Fatal("[Marshal]: " + err.Error())
+	}
+	if want := strings.Replace(in, "8080   //", "8080 //", 1); string(res) != want {
+		t.Errorf("[Marshal] out: %q\nwant: %q", res, want)
+	}
+}
+
+func TestTaggedMetadataUnmarshal(t *testing.T) {
+	type tls struct {
+		Cert string `nano:"cert"`
+	}
+	type server struct {
+		TLS  tls `nano:"tls"`
+		Port int
+	}
+	type config struct {
+		Server server `nano:"server"`
+	}
+	in := "{\nserver {\n// the certificates\ntls {\ncert a.pem // the certificate\n}\nPort 8080\n}\n}\n"
+	out := config{}
+	mout := nanometadata.Metadata{}
+	if err := Unmarshal([]byte(in), &out, &mout); err != nil {
+		t.Fatal("[Unmarshal]: " + err.Error())
+	}
+	lookups := []struct {
+		path     string
+		comments string
+		trailing string
+	}{
+		{"server.tls", " the certificates", ""},
+		{"Server.TLS", " the certificates", ""},
+		{"server.tls.cert", "", " the certificate"},
+		{"server.Port", "", ""},
+	}
+	for _, tt := range lookups {
+		m := mout.Lookup(tt.path)
+		if m == nil || m.Comments.String() != tt.comments || m.Trailing != tt.trailing {
+			t.Errorf("[Lookup] %q out: %+v", tt.path, m)
+		}
+	}
+}
+
+func TestInterfaceUnmarshal(t *testing.T) {
+	in := "{\nname api\nports [\n80\n443\n]\nserver {\nhost localhost\n}\ntext `\nline 1\nline 2\n`\nempty \n}\n"
+	want := map[string]any{
+		"name":   "api",
+		"ports":  []any{"80", "443"},
+		"server": map[string]any{"host": "localhost"},
+		"text":   "line 1\nline 2",
+		"empty":  "",
+	}
+	var out any
+	if err := Unmarshal([]byte(in), &out, nil); err != nil || !reflect.DeepEqual(out, want) {
+		t.Errorf("[Unmarshal] out: %#v; want: %#v; error: %v", out, want, err)
+	}
+}
+
+func TestRemainUnmarshal(t *testing.T) {
+	in := "{\nName api\n// added by a newer version\nlimits {\nrate 5\n}\nPort 8080\nempty \nzones [\na\nb\n]\nregion eu // the region\n}\n"
+	// the unknown keys are written after the fields
+	want := "{\nName api\nPort 8080\nempty \n// added by a newer version\nlimits {\nrate 5\n}\nregion eu // the region\nzones [\na\nb\n]\n}\n"
+	type anyConfig struct {
+		Name  string
+		Port  int
+		Extra map[string]any `nano:",remain"`
+	}
+	out := anyConfig{}
+	meta := nanometadata.Metadata{}
+	if err := Unmarshal([]byte(in), &out, &meta); err != nil {
+		t.Fatal("[Unmarshal]: " + err.Error())
+	}
+	extra := map[string]any{"limits": map[string]any{"rate": "5"}, "empty": "", "zones": []any{"a", "b"}, "region": "eu"}
+	if out.Name != "api" || out.Port != 8080 || !reflect.DeepEqual(out.Extra, extra) {
+		t.Errorf("[Unmarshal] out: %+v", out)
+	}
+	if res, err := Marshal(out, &meta); err != nil || string(res) != want {
+		t.Errorf("[Marshal] out: %q; want: %q; error: %v", res, want, err)
+	}
+
+	type rawConfig struct {
+		Name  string
+		Port  int
+		Extra map[string]RawMessage `nano:",remain"`
+	}
+	raw := rawConfig{}
+	if err := Unmarshal([]byte(in), &raw, nil); err != nil {
+		t.Fatal("[Unmarshal]: " + err.Error())
+	}
+	if s := string(raw.Extra["limits"]); s != "{\nrate 5\n}" {
+		t.Errorf("[Unmarshal] limits: %q", s)
+	}
+	if s := string(raw.Extra["region"]); s != "eu" {
+		t.Errorf("[Unmarshal] region: %q", s)
+	}
+	// an empty value is an empty raw message
+	if m, ok := raw.Extra["empty"]; !ok || m == nil || len(m) != 0 {
+		t.Errorf("[Unmarshal] empty: %#v", m)
+	}
+	want = "{\nName api\nPort 8080\nempty \nlimits {\nrate 5\n}\nregion eu\nzones [\na\nb\n]\n}\n"
+	if res, err := Marshal(raw, nil); err != nil || string(res) != want {
+		t.Errorf("[Marshal] out: %q; want: %q; error: %v", res, want, err)
+	}
+
+	// the remain field is not matched by its name
+	if err := Unmarshal([]byte("{\nExtra 1\n}\n"), &out, nil); err != nil || !reflect.DeepEqual(out.Extra["Extra"], "1") {
+		t.Errorf("[Unmarshal] out: %+v; error: %v", out, err)
+	}
+	// the name of the remain field is not matched too
+	named := struct {
+		Name  string
+		Extra map[string]RawMessage `nano:"extra,remain"`
+	}{}
+	if err := Unmarshal([]byte("{\nName api\nextra {\na 1\n}\n}\n"), &named, nil); err != nil || string(named.Extra["extra"]) != "{\na 1\n}" {
+		t.Errorf("[Unmarshal] out: %+v; error: %v", named, err)
+	}
+	invalid := struct {
+		Extra []string `nano:",remain"`
+	}{}
+	if err := Unmarshal([]byte(in), &invalid, nil); err == nil {
+		t.Errorf("[Unmarshal] want: error")
+	}
+}
@@ -0,0 +1,147 @@
+package nanomarkup
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/nanomarkup/nanomarkup.go/nanoerror"
+)
+
+// validator checks the syntax of nano data line by line.
+type validator struct {
+	lines  [][]byte
+	index  int
+	errors []error
+}
+
+// validateFrame is an opened entity or array.
+type validateFrame struct {
+	typ    unmarshalType
+	line   int
+	column int
+}
+
+func validate(data []byte) []error {
+	v := validator{lines: bytes.Split(data, []byte("\n"))}
+	stack := []validateFrame{}
+	done := false
+	for v.index = 0; v.index < len(v.lines); v.index++ {
+		line := v.lines[v.index]
+		item := bytes.TrimLeft(line, " \t")
+		if len(item) == 0 || v.skipComment(line, item) {
+			continue
+		}
+		val := bytes.TrimRight(item, " \t")
+		col := len(line) - len(item) + 1
+		// check the end of an entity or an array
+		if len(val) == 1 && (val[0] == 93 || val[0] == 125) { // ], }
+			typ := entity
+			if val[0] == 93 {
+				typ = array
+			}
+			if len(stack) == 0 || stack[len(stack)-1].typ != typ {
+				v.addError(col, fmt.Errorf("unexpected '%c'", val[0]))
+				continue
+			}
+			stack = stack[:len(stack)-1]
+			done = len(stack) == 0
+			continue
+		}
+		if len(stack) == 0 && done {
+			v.addError(col, fmt.Errorf("unexpected data after the value"))
+		}
+		// get a value of the item
+		if len(stack) > 0 && stack[len(stack)-1].typ == entity {
+			if len(val) == 1 && (val[0] == 91 || val[0] == 123 || val[0] == 96) { // [, {, `
+				// check the value without a key to find the end of it
+				v.addError(col, fmt.Errorf("the key of an entity item is missing"))
+			} else {
+				_, item = splitItem(item)
+				col = len(line) - len(item) + 1
+				val = bytes.TrimRight(item, " \t")
+			}
+		}
+		if typ := v.checkValue(val, col); typ != undefined {
+			stack = append(stack, validateFrame{typ, v.index + 1, col})
+		} else if len(stack) == 0 {
+			done = true
+		}
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		f := stack[i]
+		if f.typ == array {
+			v.errors = append(v.errors, &nanoerror.SyntaxError{Context: "Validate", Line: f.line, Column: f.column, Err: fmt.Errorf("']' is missing")})
+		} else {
+			v.errors = append(v.errors, &nanoerror.SyntaxError{Context: "Validate", Line: f.line, Column: f.column, Err: fmt.Errorf("'}' is missing")})
+		}
+	}
+	return v.errors
+}
+
+// checkValue checks the first line of a value and skips the content of a multi-line value.
+// It returns a type of the value if it is an opened entity or array.
+func (v *validator) checkValue(val []byte, col int) unmarshalType {
+	if len(val) == 0 {
+		return undefined
+	}
+	switch val[0] {
+	case 91: // [
+		if len(val) > 1 {
+			v.addError(col, fmt.Errorf("the data of an array must be started from a new line"))
+			return undefined
+		}
+		return array
+	case 123: // {
+		if len(val) > 1 {
+			v.addError(col, fmt.Errorf("the data of an entity must be started from a new line"))
+			return undefined
+		}
+		return entity
+	case 96: // `
+		if len(val) > 1 {
+			v.addError(col, fmt.Errorf("the data of a multi-line value must be started from a new line"))
+			return undefined
+		}
+		start := v.index
+		for v.index++; v.index < len(v.lines); v.index++ {
+			line := v.lines[v.index]
+			if len(line) == 1 && line[0] == 96 { // `
+				return undefined
+			}
+		}
+		v.index = start
+		v.addError(col, fmt.Errorf("'`' is missing"))
+		v.index = len(v.lines)
+	}
+	return undefined
+}
+
+// skipComment skips a single or multi-line comment.
+// It returns false if the item is not a comment.
+func (v *validator) skipComment(line, item []byte) bool {
+	if len(item) < 2 || item[0] != 47 || (item[1] != 47 && item[1] != 42) { // /, *
+		return false
+	}
+	if item[1] == 47 {
+		return true
+	}
+	// check MultilineCommentEndOpCode
+	b := bytes.TrimRight(item[2:], " ")
+	if len(b) > 1 && bytes.HasSuffix(b, []byte("*/")) {
+		return true
+	}
+	start := v.index
+	for v.index++; v.index < len(v.lines); v.index++ {
+		if bytes.HasSuffix(bytes.TrimRight(v.lines[v.index], " "), []byte("*/")) {
+			return true
+		}
+	}
+	v.index = start
+	v.addError(len(line)-len(item)+1, fmt.Errorf("'*/' is missing"))
+	v.index = len(v.lines)
+	return true
+}
+
+func (v *validator) addError(col int, err error) {
+	v.errors = append(v.errors, &nanoerror.SyntaxError{Context: "Validate", Line: v.index + 1, Column: col, Err: err})
+}